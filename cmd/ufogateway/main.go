@@ -1,19 +1,31 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"net/netip"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/plugins/migratecmd"
+	"github.com/uforg/ufogateway/internal/api"
 	"github.com/uforg/ufogateway/internal/cache"
 	"github.com/uforg/ufogateway/internal/db"
 	"github.com/uforg/ufogateway/internal/gateway"
+	"github.com/uforg/ufogateway/internal/har"
+	"github.com/uforg/ufogateway/internal/hitrecorder"
 	"github.com/uforg/ufogateway/internal/logstorer"
+	"github.com/uforg/ufogateway/internal/metrics"
 	_ "github.com/uforg/ufogateway/internal/migrations"
+	"github.com/uforg/ufogateway/internal/openapi"
+	"github.com/uforg/ufogateway/internal/replay"
 	"github.com/uforg/ufogateway/internal/routeprovider"
+	"github.com/uforg/ufogateway/internal/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func main() {
@@ -34,17 +46,199 @@ func start() error {
 		Dir:          "./internal/migrations",
 	})
 
+	var responseCacheMaxBytes int64
+	app.RootCmd.PersistentFlags().Int64Var(
+		&responseCacheMaxBytes,
+		"response-cache-max-bytes",
+		64<<20,
+		"the maximum size in bytes of the gateway response cache",
+	)
+
+	requestLimits := gateway.RequestLimits{}
+	app.RootCmd.PersistentFlags().Int64Var(
+		&requestLimits.MaxBodyBytes,
+		"gateway-max-body-bytes",
+		apis.DefaultMaxBodySize,
+		"the maximum size in bytes of request bodies for routes without their own limit, 0 for no limit",
+	)
+	app.RootCmd.PersistentFlags().IntVar(
+		&requestLimits.MaxHeaderBytes,
+		"gateway-max-header-bytes",
+		0,
+		"the maximum size in bytes of request headers for routes without their own limit, 0 for no limit",
+	)
+	app.RootCmd.PersistentFlags().IntVar(
+		&requestLimits.MaxHeaderCount,
+		"gateway-max-headers",
+		0,
+		"the maximum number of request headers for routes without their own limit, 0 for no limit",
+	)
+
+	var trustedProxies []string
+	app.RootCmd.PersistentFlags().StringSliceVar(
+		&trustedProxies,
+		"trusted-proxies",
+		nil,
+		"the CIDR ranges or IPs of the proxies whose X-Forwarded-For header is trusted to find the client IP",
+	)
+
+	logStorerConfig := logstorer.DefaultConfig()
+	app.RootCmd.PersistentFlags().IntVar(
+		&logStorerConfig.QueueSize,
+		"log-queue-size",
+		logStorerConfig.QueueSize,
+		"the maximum number of request logs waiting to be written",
+	)
+	app.RootCmd.PersistentFlags().IntVar(
+		&logStorerConfig.QueueMaxBytes,
+		"log-queue-max-bytes",
+		logStorerConfig.QueueMaxBytes,
+		"the maximum size of the stored bodies of the request logs waiting to be written",
+	)
+	app.RootCmd.PersistentFlags().IntVar(
+		&logStorerConfig.BatchSize,
+		"log-batch-size",
+		logStorerConfig.BatchSize,
+		"the maximum number of request logs written in one transaction",
+	)
+	app.RootCmd.PersistentFlags().DurationVar(
+		&logStorerConfig.FlushInterval,
+		"log-flush-interval",
+		logStorerConfig.FlushInterval,
+		"the maximum time a request log waits for its batch to fill up",
+	)
+	app.RootCmd.PersistentFlags().IntVar(
+		&logStorerConfig.Workers,
+		"log-workers",
+		logStorerConfig.Workers,
+		"the number of goroutines writing request logs",
+	)
+	app.RootCmd.PersistentFlags().BoolVar(
+		&logStorerConfig.DropOnFull,
+		"log-drop-on-full",
+		logStorerConfig.DropOnFull,
+		"drop request logs when the queue is full instead of making requests wait for room",
+	)
+
+	var metricsPath string
+	app.RootCmd.PersistentFlags().StringVar(
+		&metricsPath,
+		"metrics-path",
+		"",
+		"the path of the Prometheus metrics endpoint, like /metrics, which takes precedence over the gateway routes, disabled when empty",
+	)
+	var metricsToken string
+	app.RootCmd.PersistentFlags().StringVar(
+		&metricsToken,
+		"metrics-token",
+		"",
+		"the bearer token required to scrape the metrics endpoint, empty for no token",
+	)
+
+	tracingConfig := tracing.Config{}
+	app.RootCmd.PersistentFlags().StringVar(
+		&tracingConfig.Endpoint,
+		"tracing-endpoint",
+		"",
+		"the URL of the OTLP collector that receives the gateway spans, e.g. http://localhost:4317, empty to disable tracing",
+	)
+	app.RootCmd.PersistentFlags().StringVar(
+		&tracingConfig.Protocol,
+		"tracing-protocol",
+		tracing.ProtocolGRPC,
+		"the protocol of the OTLP collector, grpc or http",
+	)
+	app.RootCmd.PersistentFlags().Float64Var(
+		&tracingConfig.SampleRatio,
+		"tracing-sample-ratio",
+		1,
+		"the ratio of the new traces that are sampled, from 0 to 1",
+	)
+
 	cacheInstance := cache.NewCacheInstance()
 	db := db.NewDB(app, cacheInstance)
 
 	routeProvider := routeprovider.NewRouteProvider(app, db)
-	logStorer := logstorer.NewLogStorer(app, db)
+	routeProvider.BindHooks()
+	logStorer := logstorer.NewLogStorer(app, db, &logStorerConfig)
+	app.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
+		logStorer.Close()
+		return e.Next()
+	})
+
+	gatewayMetrics := metrics.NewMetrics()
+	gatewayMetrics.ObserveLogStorer(logStorer)
+	gatewayMetrics.ObserveCache("db", cacheInstance)
+
+	hitRecorder := hitrecorder.NewHitRecorder(app, db)
+	app.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
+		if err := hitRecorder.Flush(); err != nil {
+			app.Logger().Error("failed to flush route stats", "error", err)
+		}
+		return e.Next()
+	})
+
+	// replays skip the response cache, so they use a gateway of their own
+	// that can also be built outside of the serve command
+	replayer := replay.NewReplayer(app, db, gateway.NewGateway(routeProvider, logStorer), logStorer)
+	app.RootCmd.AddCommand(replay.NewCommand(replayer))
 
-	gat := gateway.NewGateway(routeProvider, logStorer)
-	wrappedGat := apis.WrapStdHandler(gat)
+	harExporter := har.NewExporter(db)
+	app.RootCmd.AddCommand(har.NewCommand(harExporter))
+
+	openapiImporter := openapi.NewImporter(db)
+	app.RootCmd.AddCommand(openapi.NewCommand(openapiImporter))
+	openapiInferrer := openapi.NewInferrer(db)
+	app.RootCmd.AddCommand(openapi.NewInferCommand(openapiInferrer))
+
+	var tracerProvider *sdktrace.TracerProvider
+	app.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
+		if tracerProvider != nil {
+			if err := tracerProvider.Shutdown(context.Background()); err != nil {
+				app.Logger().Error("failed to flush the gateway spans", "error", err)
+			}
+		}
+		return e.Next()
+	})
 
 	app.OnServe().BindFunc(func(se *core.ServeEvent) error {
-		se.Router.Any("/", wrappedGat)
+		responseCache := cache.NewSizedCacheInstance(responseCacheMaxBytes)
+		gatewayMetrics.ObserveCache("responses", responseCache)
+
+		trustedProxyPrefixes := make([]netip.Prefix, 0, len(trustedProxies))
+		for _, entry := range trustedProxies {
+			prefix, err := gateway.ParseIPAccessEntry(entry)
+			if err != nil {
+				return fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			trustedProxyPrefixes = append(trustedProxyPrefixes, prefix)
+		}
+
+		gatewayOpts := []gateway.Option{
+			gateway.WithTrustedProxies(trustedProxyPrefixes),
+			gateway.WithResponseCache(responseCache),
+			gateway.WithRequestLimits(requestLimits),
+			gateway.WithHitRecorder(hitRecorder),
+			gateway.WithMetrics(gatewayMetrics),
+		}
+		if tracingConfig.Enabled() {
+			var err error
+			tracerProvider, err = tracing.NewTracerProvider(context.Background(), tracingConfig)
+			if err != nil {
+				return err
+			}
+			gatewayOpts = append(gatewayOpts, gateway.WithTracerProvider(tracerProvider))
+		}
+
+		gat := gateway.NewGateway(routeProvider, logStorer, gatewayOpts...)
+		wrappedGat := apis.WrapStdHandler(gat)
+
+		api.NewAPI(db, replayer, harExporter, openapiImporter, openapiInferrer).Register(se)
+		if metricsPath != "" {
+			se.Router.GET(metricsPath, apis.WrapStdHandler(gatewayMetrics.Handler(metricsToken)))
+		}
+		// the gateway enforces its own body limits, logging the rejected requests
+		se.Router.Any("/", wrappedGat).Unbind(apis.DefaultBodyLimitMiddlewareId)
 		return se.Next()
 	})
 
@@ -58,8 +252,27 @@ func start() error {
 			return
 		}
 
+		gatewayMetrics.AddExpiredRequestsDeleted(qty)
 		app.Logger().Info("expired requests deleted", "qty", qty)
 	})
 
+	app.Cron().MustAdd("flushRouteStats", "* * * * *", func() {
+		if err := hitRecorder.Flush(); err != nil {
+			app.Logger().Error(
+				"failed to flush route stats",
+				"error", err,
+			)
+		}
+	})
+
+	app.Cron().MustAdd("compactRouteStats", "*/10 * * * *", func() {
+		if err := db.CompactRouteStats(time.Now()); err != nil {
+			app.Logger().Error(
+				"failed to compact route stats",
+				"error", err,
+			)
+		}
+	})
+
 	return app.Start()
 }
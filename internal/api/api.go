@@ -0,0 +1,53 @@
+package api
+
+import (
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/har"
+	"github.com/uforg/ufogateway/internal/openapi"
+	"github.com/uforg/ufogateway/internal/replay"
+)
+
+// basePath is the prefix of every ufogateway admin endpoint.
+const basePath = "/api/ufogateway"
+
+type API struct {
+	db              *db.DB
+	replayer        *replay.Replayer
+	harExporter     *har.Exporter
+	openapiImporter *openapi.Importer
+	openapiInferrer *openapi.Inferrer
+}
+
+func NewAPI(
+	db *db.DB,
+	replayer *replay.Replayer,
+	harExporter *har.Exporter,
+	openapiImporter *openapi.Importer,
+	openapiInferrer *openapi.Inferrer,
+) *API {
+	return &API{
+		db:              db,
+		replayer:        replayer,
+		harExporter:     harExporter,
+		openapiImporter: openapiImporter,
+		openapiInferrer: openapiInferrer,
+	}
+}
+
+// Register binds the admin endpoints to the PocketBase router. All of them
+// require superuser authentication.
+func (a *API) Register(se *core.ServeEvent) {
+	group := se.Router.Group(basePath)
+	group.Bind(apis.RequireSuperuserAuth())
+
+	group.POST("/replay", a.replay)
+	group.GET("/replay/{batchId}/diff", a.diffReplayBatch)
+	group.GET("/diff", a.diff)
+	group.GET("/har", a.exportHAR)
+	group.GET("/requests/{requestId}/body/{part}", a.downloadRequestBody)
+	group.POST("/projects/{projectId}/openapi-import", a.importOpenAPI)
+	group.GET("/routes/{routeId}/openapi", a.inferOpenAPI)
+	group.GET("/stats", a.routeStats)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/diff"
+	"github.com/uforg/ufogateway/internal/replay"
+	"github.com/uforg/ufogateway/internal/util/strutil"
+)
+
+// diffOptionsFromQuery reads the comma separated ignore_headers and
+// ignore_paths query parameters.
+func diffOptionsFromQuery(e *core.RequestEvent) diff.Options {
+	query := e.Request.URL.Query()
+	return diff.Options{
+		IgnoreHeaders: strutil.SplitList(query.Get("ignore_headers")),
+		IgnorePaths:   strutil.SplitList(query.Get("ignore_paths")),
+	}
+}
+
+// diff compares the stored responses of the requests a and b.
+func (a *API) diff(e *core.RequestEvent) error {
+	query := e.Request.URL.Query()
+	if query.Get("a") == "" || query.Get("b") == "" {
+		return e.BadRequestError("The a and b request ids are required.", nil)
+	}
+
+	reqA, err := a.db.GetStoredRequestByID(query.Get("a"))
+	if err != nil {
+		return e.NotFoundError("Request a not found.", err)
+	}
+	reqB, err := a.db.GetStoredRequestByID(query.Get("b"))
+	if err != nil {
+		return e.NotFoundError("Request b not found.", err)
+	}
+
+	result := diff.Compare(
+		replay.StoredResponse(reqA),
+		replay.StoredResponse(reqB),
+		diffOptionsFromQuery(e),
+	)
+
+	return e.JSON(http.StatusOK, result)
+}
+
+// diffReplayBatch summarises the differences between every replay of a batch
+// and its original request.
+func (a *API) diffReplayBatch(e *core.RequestEvent) error {
+	result, err := a.replayer.DiffBatch(e.Request.PathValue("batchId"), diffOptionsFromQuery(e))
+	if err != nil {
+		return e.BadRequestError("Failed to diff the replay batch.", err)
+	}
+
+	return e.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/har"
+	"github.com/uforg/ufogateway/internal/util/timeutil"
+)
+
+// exportHAR returns the stored requests selected by the route, from, to,
+// filter and limit query parameters as a HAR file.
+func (a *API) exportHAR(e *core.RequestEvent) error {
+	query := e.Request.URL.Query()
+
+	opts := har.Options{
+		RouteID: query.Get("route"),
+		Filter:  query.Get("filter"),
+	}
+
+	var err error
+	if opts.From, err = timeutil.ParseTime(query.Get("from")); err != nil {
+		return e.BadRequestError("Invalid from time.", err)
+	}
+	if opts.To, err = timeutil.ParseTime(query.Get("to")); err != nil {
+		return e.BadRequestError("Invalid to time.", err)
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if opts.Limit, err = strconv.Atoi(limit); err != nil {
+			return e.BadRequestError("Invalid limit.", err)
+		}
+	}
+
+	doc, err := a.harExporter.Export(opts)
+	if err != nil {
+		return e.BadRequestError("Failed to export the requests.", err)
+	}
+
+	e.Response.Header().Set("Content-Disposition", `attachment; filename="requests.har"`)
+	return e.JSON(http.StatusOK, doc)
+}
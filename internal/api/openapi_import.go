@@ -0,0 +1,41 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/openapi"
+)
+
+// maxOpenAPIDocumentBytes is the maximum size of an imported document.
+const maxOpenAPIDocumentBytes = 10 << 20
+
+// importOpenAPI creates or updates the routes of a project from the OpenAPI
+// document sent as the request body. The mode, endpoint_prefix, server_url
+// and dry_run query parameters configure the import.
+func (a *API) importOpenAPI(e *core.RequestEvent) error {
+	data, err := io.ReadAll(http.MaxBytesReader(e.Response, e.Request.Body, maxOpenAPIDocumentBytes))
+	if err != nil {
+		return e.BadRequestError("Failed to read the OpenAPI document.", err)
+	}
+
+	query := e.Request.URL.Query()
+	opts := openapi.ImportOptions{
+		PlanOptions: openapi.PlanOptions{
+			Mode:           openapi.Mode(query.Get("mode")),
+			EndpointPrefix: query.Get("endpoint_prefix"),
+			ServerURL:      query.Get("server_url"),
+		},
+		DryRun: query.Get("dry_run") == "true",
+	}
+
+	result, err := a.openapiImporter.Import(e.Request.PathValue("projectId"), data, opts)
+	if err != nil {
+		// the errors point at the part of the document that can't be
+		// imported, so they are worth showing
+		return e.BadRequestError("Failed to import the OpenAPI document: "+err.Error(), err)
+	}
+
+	return e.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/openapi"
+)
+
+// inferOpenAPI returns an OpenAPI document inferred from the stored requests
+// of a route. The format query parameter is yaml (default) or json, and limit
+// caps the number of inspected requests.
+func (a *API) inferOpenAPI(e *core.RequestEvent) error {
+	query := e.Request.URL.Query()
+
+	format := openapi.Format(query.Get("format"))
+	if format == "" {
+		format = openapi.FormatYAML
+	}
+	if format != openapi.FormatYAML && format != openapi.FormatJSON {
+		return e.BadRequestError("Invalid format, use yaml or json.", nil)
+	}
+
+	limit := 0
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		var err error
+		if limit, err = strconv.Atoi(rawLimit); err != nil {
+			return e.BadRequestError("Invalid limit.", err)
+		}
+	}
+
+	doc, err := a.openapiInferrer.Infer(e.Request.PathValue("routeId"), limit)
+	if err != nil {
+		return e.BadRequestError("Failed to infer the OpenAPI document.", err)
+	}
+
+	var buf bytes.Buffer
+	if err := openapi.EncodeDocument(&buf, doc, format); err != nil {
+		return e.InternalServerError("Failed to encode the OpenAPI document.", err)
+	}
+
+	contentType := "application/yaml"
+	if format == openapi.FormatJSON {
+		contentType = "application/json"
+	}
+	return e.Blob(http.StatusOK, contentType, buf.Bytes())
+}
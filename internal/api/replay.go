@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/replay"
+)
+
+// replay sends one stored request, or the ones matching a filter, again
+// through the gateway.
+func (a *API) replay(e *core.RequestEvent) error {
+	opts := replay.Options{}
+	if err := e.BindBody(&opts); err != nil {
+		return e.BadRequestError("Invalid request body.", err)
+	}
+
+	result, err := a.replayer.Replay(e.Request.Context(), opts)
+	if err != nil {
+		return e.BadRequestError("Failed to replay the requests.", err)
+	}
+
+	return e.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/db"
+)
+
+// downloadRequestBody streams back the stored request (part req) or
+// response (part res) body of a request, decoded if it was stored decoded.
+func (a *API) downloadRequestBody(e *core.RequestEvent) error {
+	part := e.Request.PathValue("part")
+	if part != "req" && part != "res" {
+		return e.BadRequestError("Invalid body part, use req or res.", nil)
+	}
+
+	record, err := a.db.GetRequestRecordByID(e.Request.PathValue("requestId"))
+	if err != nil {
+		return e.NotFoundError("Request not found.", err)
+	}
+
+	storedRequest := db.NewStoredRequestFromRecord(record)
+	// bodies stored as files are served from the filesystem instead
+	meta, body := storedRequest.ReqBodyMeta, storedRequest.ReqBodyBytes()
+	if part == "res" {
+		meta, body = storedRequest.ResBodyMeta, storedRequest.ResBodyBytes()
+	}
+
+	contentType := meta.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	fileKey := db.BodyFileKey(record, part)
+	filename := record.Id + "_" + part + "_body" + path.Ext(fileKey)
+
+	header := e.Response.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	header.Set("Cache-Control", "no-store")
+
+	if fileKey == "" {
+		return e.Blob(http.StatusOK, contentType, body)
+	}
+
+	fsys, err := e.App.NewFilesystem()
+	if err != nil {
+		return e.InternalServerError("Failed to open the filesystem.", err)
+	}
+	defer fsys.Close()
+
+	if err := fsys.Serve(e.Response, e.Request, fileKey, filename); err != nil {
+		return e.NotFoundError("Body file not found.", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/routestats"
+	"github.com/uforg/ufogateway/internal/util/timeutil"
+)
+
+// maxStatsPoints is the maximum number of buckets returned by a single
+// stats query.
+const maxStatsPoints = 10000
+
+// defaultStatsRanges is the time range of a stats query without from, per
+// granularity.
+var defaultStatsRanges = map[string]time.Duration{
+	routestats.GranularityMinute: time.Hour,
+	routestats.GranularityHour:   7 * 24 * time.Hour,
+	routestats.GranularityDay:    90 * 24 * time.Hour,
+}
+
+// statsResponse is the time series of the traffic of a route or a project.
+type statsResponse struct {
+	Route       string               `json:"route,omitempty"`
+	Project     string               `json:"project,omitempty"`
+	Granularity string               `json:"granularity"`
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Series      []routestats.Summary `json:"series"`
+}
+
+// routeStats returns the traffic time series of the route or project query
+// parameter, one bucket of the granularity query parameter per point from
+// the from time to the to time.
+func (a *API) routeStats(e *core.RequestEvent) error {
+	query := e.Request.URL.Query()
+
+	routeID := query.Get("route")
+	projectID := query.Get("project")
+	if (routeID == "") == (projectID == "") {
+		return e.BadRequestError("Either a route or a project id is required.", nil)
+	}
+
+	granularity, err := routestats.ParseGranularity(query.Get("granularity"))
+	if err != nil {
+		return e.BadRequestError("Invalid granularity.", err)
+	}
+
+	to, err := timeutil.ParseTime(query.Get("to"))
+	if err != nil {
+		return e.BadRequestError("Invalid to time.", err)
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from, err := timeutil.ParseTime(query.Get("from"))
+	if err != nil {
+		return e.BadRequestError("Invalid from time.", err)
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultStatsRanges[granularity])
+	}
+	from = routestats.Truncate(from, granularity)
+
+	if routestats.SeriesLength(granularity, from, to) > maxStatsPoints {
+		return e.BadRequestError("The time range has too many buckets for the granularity.", nil)
+	}
+
+	stats, err := a.db.FindRouteStats(routeID, projectID, granularity, from, to)
+	if err != nil {
+		return e.InternalServerError("Failed to find the stats.", err)
+	}
+
+	points := make([]routestats.Point, 0, len(stats))
+	for _, stat := range stats {
+		points = append(points, routestats.Point{Start: stat.Start, Bucket: stat.Bucket})
+	}
+
+	return e.JSON(http.StatusOK, statsResponse{
+		Route:       routeID,
+		Project:     projectID,
+		Granularity: granularity,
+		From:        from,
+		To:          to,
+		Series:      routestats.Series(granularity, from, to, points),
+	})
+}
@@ -0,0 +1,94 @@
+package bodycodec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrTooLarge is returned when a decoded body exceeds the size limit.
+var ErrTooLarge = errors.New("decoded body too large")
+
+// Encodings returns the content codings of a Content-Encoding header in the
+// order they were applied, without identity.
+func Encodings(contentEncoding string) []string {
+	encodings := []string{}
+	for _, encoding := range strings.Split(contentEncoding, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding != "" && encoding != "identity" {
+			encodings = append(encodings, encoding)
+		}
+	}
+	return encodings
+}
+
+// Decode reverses the content codings of a Content-Encoding header applied
+// to body. Supported codings are gzip, deflate, br and zstd.
+//
+// Decoding stops with ErrTooLarge once the result exceeds maxBytes, a
+// maxBytes of zero or less means no limit.
+func Decode(body []byte, contentEncoding string, maxBytes int) ([]byte, error) {
+	encodings := Encodings(contentEncoding)
+
+	data := body
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decoded, err := decodeOne(data, encodings[i], maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+	return data, nil
+}
+
+func decodeOne(body []byte, encoding string, maxBytes int) ([]byte, error) {
+	var r io.Reader
+	switch encoding {
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		r = gr
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but some servers send raw
+		// deflate streams
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			r = flate.NewReader(bytes.NewReader(body))
+		} else {
+			r = zr
+		}
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(body))
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(body), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd body: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	if maxBytes > 0 {
+		r = io.LimitReader(r, int64(maxBytes)+1)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body: %w", encoding, err)
+	}
+	if maxBytes > 0 && len(decoded) > maxBytes {
+		return nil, ErrTooLarge
+	}
+	return decoded, nil
+}
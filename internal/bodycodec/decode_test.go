@@ -0,0 +1,77 @@
+package bodycodec
+
+import (
+	"bytes"
+	"compress/flate"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodings(t *testing.T) {
+	assert.Equal(t, []string{}, Encodings(""))
+	assert.Equal(t, []string{}, Encodings("identity"))
+	assert.Equal(t, []string{"gzip"}, Encodings(" GZIP "))
+	assert.Equal(t, []string{"deflate", "br"}, Encodings("deflate, identity,br"))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	body := []byte(strings.Repeat(`{"hello":"world"}`, 10))
+
+	for _, encoding := range []string{"", "gzip", "x-gzip", "deflate", "br", "zstd", "gzip, br"} {
+		t.Run(encoding, func(t *testing.T) {
+			encoded, err := Encode(body, encoding)
+			require.NoError(t, err)
+			if encoding != "" {
+				assert.NotEqual(t, body, encoded)
+			}
+
+			decoded, err := Decode(encoded, encoding, 0)
+			require.NoError(t, err)
+			assert.Equal(t, body, decoded)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	body := []byte(strings.Repeat("a", 100))
+
+	t.Run("raw deflate", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+		_, _ = w.Write(body)
+		require.NoError(t, w.Close())
+
+		decoded, err := Decode(buf.Bytes(), "deflate", 0)
+		require.NoError(t, err)
+		assert.Equal(t, body, decoded)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		encoded, err := Encode(body, "gzip")
+		require.NoError(t, err)
+
+		_, err = Decode(encoded, "gzip", 99)
+		assert.ErrorIs(t, err, ErrTooLarge)
+
+		decoded, err := Decode(encoded, "gzip", 100)
+		require.NoError(t, err)
+		assert.Equal(t, body, decoded)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		_, err := Decode([]byte("not gzip"), "gzip", 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		_, err := Decode(body, "compress", 0)
+		assert.EqualError(t, err, `unsupported content encoding "compress"`)
+
+		_, err = Encode(body, "compress")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,104 @@
+package bodycodec
+
+import (
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxDecodedBytes caps the decoded size of bodies stored without a
+// size limit, so a small compressed body can't expand without bounds.
+const DefaultMaxDecodedBytes = 10 << 20
+
+// Meta describes how a stored body relates to the body that was sent.
+type Meta struct {
+	Encoding    string `json:"encoding,omitempty"`     // is the Content-Encoding of the body as sent
+	Size        int    `json:"size"`                   // is the size in bytes of the body as sent
+	Decoded     bool   `json:"decoded,omitempty"`      // is true when the body is stored without its content encoding
+	DecodedSize int    `json:"decoded_size,omitempty"` // is the size in bytes of the decoded body
+	DecodeError string `json:"decode_error,omitempty"` // is why the body is stored as sent although it is encoded
+	ContentType string `json:"content_type,omitempty"` // is the declared media type of the body, or the detected one
+	File        bool   `json:"file,omitempty"`         // is true when the body is stored as a file instead of inline
+	Base64      bool   `json:"base64,omitempty"`       // is true when the body is stored inline base64 encoded
+}
+
+// ForStorage prepares a body for storage and returns the bytes to store.
+//
+// Bodies with a content encoding are decoded, unless they can't be decoded.
+// The media type is the one declared in contentType or else the one detected
+// from the stored bytes. It returns ErrTooLarge when the bytes to store
+// exceed maxBytes, with a maxBytes of zero or less encoded bodies are still
+// only decoded up to DefaultMaxDecodedBytes.
+func ForStorage(body []byte, contentEncoding string, contentType string, maxBytes int) ([]byte, Meta, error) {
+	meta := Meta{
+		Encoding: strings.Join(Encodings(contentEncoding), ", "),
+		Size:     len(body),
+	}
+
+	data := body
+	if meta.Encoding != "" {
+		decodeLimit := maxBytes
+		if decodeLimit <= 0 {
+			decodeLimit = DefaultMaxDecodedBytes
+		}
+
+		decoded, err := Decode(body, meta.Encoding, decodeLimit)
+		switch {
+		case errors.Is(err, ErrTooLarge) && maxBytes > 0:
+			return nil, meta, err
+		case err != nil:
+			meta.DecodeError = err.Error()
+		default:
+			data = decoded
+			meta.Decoded = true
+			meta.DecodedSize = len(decoded)
+		}
+	}
+
+	if maxBytes > 0 && len(data) > maxBytes {
+		return nil, meta, ErrTooLarge
+	}
+
+	meta.ContentType = mediaType(contentType, data, meta.Decoded || meta.Encoding == "")
+	return data, meta, nil
+}
+
+// mediaType returns the media type of a Content-Type header, or the one
+// detected from data when there is none. Bodies that are still encoded are
+// detected as binary.
+func mediaType(contentType string, data []byte, plain bool) string {
+	if parsed, _, err := mime.ParseMediaType(contentType); err == nil {
+		return parsed
+	}
+	if len(data) == 0 {
+		return ""
+	}
+	if !plain {
+		return "application/octet-stream"
+	}
+
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	return detected
+}
+
+// IsText reports whether data can be stored in a text field as it is.
+func IsText(data []byte) bool {
+	return utf8.Valid(data) && !strings.ContainsRune(string(data), 0)
+}
+
+// FromStorage returns the bytes of a body stored inline, they are the
+// decoded body when meta.Decoded is true.
+func FromStorage(stored string, meta Meta) []byte {
+	if !meta.Base64 {
+		return []byte(stored)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil {
+		return []byte(stored)
+	}
+	return data
+}
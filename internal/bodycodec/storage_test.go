@@ -0,0 +1,124 @@
+package bodycodec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForStorage(t *testing.T) {
+	text := []byte(strings.Repeat("hello ", 20))
+	binary := []byte{0x00, 0x01, 0xff, 0x80}
+
+	gzipText, err := Encode(text, "gzip")
+	require.NoError(t, err)
+	brBinary, err := Encode(binary, "br")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		body            []byte
+		contentEncoding string
+		contentType     string
+		maxBytes        int
+		want            []byte
+		wantMeta        Meta
+		wantErr         error
+	}{
+		{
+			name:        "declared content type",
+			body:        text,
+			contentType: "Text/Plain; charset=utf-8",
+			want:        text,
+			wantMeta:    Meta{Size: len(text), ContentType: "text/plain"},
+		},
+		{
+			name:     "detected content type",
+			body:     []byte("<html><body>hi</body></html>"),
+			want:     []byte("<html><body>hi</body></html>"),
+			wantMeta: Meta{Size: 28, ContentType: "text/html"},
+		},
+		{
+			name:     "empty body",
+			body:     nil,
+			want:     nil,
+			wantMeta: Meta{},
+		},
+		{
+			name:     "binary",
+			body:     binary,
+			want:     binary,
+			wantMeta: Meta{Size: 4, ContentType: "application/octet-stream"},
+		},
+		{
+			name:            "gzip text",
+			body:            gzipText,
+			contentEncoding: "gzip",
+			contentType:     "text/plain",
+			want:            text,
+			wantMeta:        Meta{Encoding: "gzip", Size: len(gzipText), Decoded: true, DecodedSize: len(text), ContentType: "text/plain"},
+		},
+		{
+			name:            "brotli binary",
+			body:            brBinary,
+			contentEncoding: "BR",
+			want:            binary,
+			wantMeta:        Meta{Encoding: "br", Size: len(brBinary), Decoded: true, DecodedSize: 4, ContentType: "application/octet-stream"},
+		},
+		{
+			name:            "invalid encoded body",
+			body:            text,
+			contentEncoding: "gzip",
+			want:            text,
+			wantMeta: Meta{
+				Encoding:    "gzip",
+				Size:        len(text),
+				DecodeError: "invalid gzip body: gzip: invalid header",
+				ContentType: "application/octet-stream",
+			},
+		},
+		{
+			name:     "too large",
+			body:     text,
+			maxBytes: 10,
+			wantMeta: Meta{Size: len(text)},
+			wantErr:  ErrTooLarge,
+		},
+		{
+			name:            "decoded too large",
+			body:            gzipText,
+			contentEncoding: "gzip",
+			maxBytes:        len(gzipText),
+			wantMeta:        Meta{Encoding: "gzip", Size: len(gzipText)},
+			wantErr:         ErrTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, meta, err := ForStorage(tt.body, tt.contentEncoding, tt.contentType, tt.maxBytes)
+			assert.Equal(t, tt.wantMeta, meta)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIsText(t *testing.T) {
+	assert.True(t, IsText([]byte("héllo")))
+	assert.True(t, IsText(nil))
+	assert.False(t, IsText([]byte{0xff, 0xfe}))
+	assert.False(t, IsText([]byte("a\x00b")))
+}
+
+func TestFromStorage(t *testing.T) {
+	assert.Equal(t, []byte("hello"), FromStorage("hello", Meta{}))
+	assert.Equal(t, []byte{0xff, 0xfe}, FromStorage("//4=", Meta{Base64: true}))
+	assert.Equal(t, []byte("not base64!"), FromStorage("not base64!", Meta{Base64: true}))
+}
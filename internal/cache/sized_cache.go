@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// sizedCacheItem represents an item of the sized cache with its size in bytes.
+type sizedCacheItem struct {
+	cacheItem
+	key  string
+	size int64
+}
+
+// SizedCacheInstance is a least recently used cache bounded by the total size
+// in bytes of its items, with support for time-to-live (TTL) expiration.
+//
+// When adding an item would exceed the maximum size, the least recently used
+// items are evicted until the new item fits.
+type SizedCacheInstance struct {
+	maxBytes int64                    // The maximum total size of the cache items.
+	size     int64                    // The current total size of the cache items.
+	items    map[string]*list.Element // The map storing the cache items.
+	lru      *list.List               // The items ordered from most to least recently used.
+	mu       sync.Mutex               // Mutex for controlling concurrent access to the cache.
+}
+
+// NewSizedCacheInstance creates a new sized cache that holds at most maxBytes.
+func NewSizedCacheInstance(maxBytes int64) *SizedCacheInstance {
+	return &SizedCacheInstance{
+		maxBytes: maxBytes,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Set adds a new item to the cache with the specified key, value, size in bytes
+// and time-to-live (TTL).
+//
+// It returns false when the item is bigger than the maximum size of the cache
+// and therefore was not stored.
+func (c *SizedCacheInstance) Set(key string, value any, size int64, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, found := c.items[key]; found {
+		c.removeElement(el)
+	}
+
+	if size > c.maxBytes {
+		return false
+	}
+
+	for c.size+size > c.maxBytes {
+		c.removeElement(c.lru.Back())
+	}
+
+	el := c.lru.PushFront(&sizedCacheItem{
+		cacheItem: cacheItem{
+			value:  value,
+			expiry: time.Now().Add(ttl),
+		},
+		key:  key,
+		size: size,
+	})
+	c.items[key] = el
+	c.size += size
+
+	return true
+}
+
+// Get retrieves the value associated with the given key from the cache and
+// marks it as the most recently used.
+func (c *SizedCacheInstance) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.items[key]
+	if !found {
+		return nil, false
+	}
+
+	entry := el.Value.(*sizedCacheItem)
+	if entry.isExpired() {
+		c.removeElement(el)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+	return entry.value, true
+}
+
+// Del removes the item with the specified key from the cache.
+func (c *SizedCacheInstance) Del(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, found := c.items[key]; found {
+		c.removeElement(el)
+	}
+}
+
+// Len returns the number of items in the cache.
+func (c *SizedCacheInstance) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Size returns the total size in bytes of the items in the cache.
+func (c *SizedCacheInstance) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Clear removes all items from the cache.
+func (c *SizedCacheInstance) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+	c.size = 0
+}
+
+// removeElement removes the given element from the cache, the caller must
+// hold the lock.
+func (c *SizedCacheInstance) removeElement(el *list.Element) {
+	entry := c.lru.Remove(el).(*sizedCacheItem)
+	delete(c.items, entry.key)
+	c.size -= entry.size
+}
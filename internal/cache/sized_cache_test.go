@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizedCacheInstance_SetGet(t *testing.T) {
+	cache := NewSizedCacheInstance(100)
+
+	assert.True(t, cache.Set("a", "value-a", 10, time.Minute))
+	assert.True(t, cache.Set("b", "value-b", 20, time.Minute))
+
+	value, found := cache.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, "value-a", value)
+
+	_, found = cache.Get("missing")
+	assert.False(t, found)
+
+	assert.Equal(t, 2, cache.Len())
+	assert.Equal(t, int64(30), cache.Size())
+}
+
+func TestSizedCacheInstance_Replace(t *testing.T) {
+	cache := NewSizedCacheInstance(100)
+
+	cache.Set("a", "old", 40, time.Minute)
+	cache.Set("a", "new", 10, time.Minute)
+
+	value, found := cache.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, "new", value)
+	assert.Equal(t, 1, cache.Len())
+	assert.Equal(t, int64(10), cache.Size())
+}
+
+func TestSizedCacheInstance_TooBig(t *testing.T) {
+	cache := NewSizedCacheInstance(100)
+
+	assert.False(t, cache.Set("big", "value", 101, time.Minute))
+	_, found := cache.Get("big")
+	assert.False(t, found)
+	assert.Equal(t, int64(0), cache.Size())
+}
+
+func TestSizedCacheInstance_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewSizedCacheInstance(100)
+
+	cache.Set("a", "a", 40, time.Minute)
+	cache.Set("b", "b", 40, time.Minute)
+
+	// Use "a" so "b" becomes the least recently used item
+	_, _ = cache.Get("a")
+
+	cache.Set("c", "c", 40, time.Minute)
+
+	_, foundA := cache.Get("a")
+	_, foundB := cache.Get("b")
+	_, foundC := cache.Get("c")
+	assert.True(t, foundA)
+	assert.False(t, foundB)
+	assert.True(t, foundC)
+	assert.Equal(t, int64(80), cache.Size())
+}
+
+func TestSizedCacheInstance_Expiration(t *testing.T) {
+	cache := NewSizedCacheInstance(100)
+
+	cache.Set("a", "a", 10, 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	_, found := cache.Get("a")
+	assert.False(t, found)
+	assert.Equal(t, 0, cache.Len())
+	assert.Equal(t, int64(0), cache.Size())
+}
+
+func TestSizedCacheInstance_DelAndClear(t *testing.T) {
+	cache := NewSizedCacheInstance(100)
+
+	cache.Set("a", "a", 10, time.Minute)
+	cache.Set("b", "b", 10, time.Minute)
+
+	cache.Del("a")
+	_, found := cache.Get("a")
+	assert.False(t, found)
+	assert.Equal(t, int64(10), cache.Size())
+
+	cache.Clear()
+	assert.Equal(t, 0, cache.Len())
+	assert.Equal(t, int64(0), cache.Size())
+}
+
+func TestSizedCacheInstance_ConcurrentAccess(t *testing.T) {
+	cache := NewSizedCacheInstance(1000)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := strconv.Itoa(i % 20)
+			cache.Set(key, i, 50, time.Minute)
+			_, _ = cache.Get(key)
+		}(i)
+	}
+
+	wg.Wait()
+	assert.LessOrEqual(t, cache.Size(), int64(1000))
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"mime"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/uforg/ufogateway/internal/bodycodec"
+)
+
+// inlineBodyMaxBytes is the largest text body kept in its text field, larger
+// or binary bodies are stored as a file through the PocketBase filesystem.
+const inlineBodyMaxBytes = 4096
+
+// bodyFields names the record fields that hold one stored body.
+type bodyFields struct {
+	text string
+	file string
+	meta string
+}
+
+var (
+	reqBodyFields = bodyFields{text: "req_body", file: "req_body_file", meta: "req_body_meta"}
+	resBodyFields = bodyFields{text: "res_body", file: "res_body_file", meta: "res_body_meta"}
+)
+
+// setBody sets a body and its metadata on the record, inline when it is a
+// small text body and as a file otherwise. Any previously stored file is
+// replaced when the record is saved.
+func setBody(record *core.Record, fields bodyFields, data []byte, meta bodycodec.Meta) error {
+	meta.Base64 = false
+	meta.File = len(data) > inlineBodyMaxBytes || !bodycodec.IsText(data)
+
+	if !meta.File {
+		record.Set(fields.text, string(data))
+		record.Set(fields.file, nil)
+		record.Set(fields.meta, meta)
+		return nil
+	}
+
+	file, err := filesystem.NewFileFromBytes(data, fields.text+bodyFileExtension(meta.ContentType))
+	if err != nil {
+		return err
+	}
+
+	record.Set(fields.text, "")
+	record.Set(fields.file, file)
+	record.Set(fields.meta, meta)
+	return nil
+}
+
+// bodyFileExtension returns the file extension for a media type, so stored
+// files keep a meaningful name when downloaded.
+func bodyFileExtension(contentType string) string {
+	extensions, _ := mime.ExtensionsByType(contentType)
+	if len(extensions) == 0 {
+		return ".bin"
+	}
+	return extensions[0]
+}
+
+// BodyFileKey returns the filesystem key of the file holding a stored body,
+// or an empty string when the body is stored inline.
+func BodyFileKey(record *core.Record, part string) string {
+	fields := reqBodyFields
+	if part == "res" {
+		fields = resBodyFields
+	}
+
+	filename := record.GetString(fields.file)
+	if filename == "" {
+		return ""
+	}
+	return record.BaseFilesPath() + "/" + filename
+}
+
+// bodyFileReader reads the bodies that stored requests keep as files. It
+// opens the filesystem once, on the first file that is read.
+type bodyFileReader struct {
+	app  core.App
+	fsys *filesystem.System
+}
+
+func (r *bodyFileReader) read(fileKey string) ([]byte, error) {
+	if r.fsys == nil {
+		fsys, err := r.app.NewFilesystem()
+		if err != nil {
+			return nil, err
+		}
+		r.fsys = fsys
+	}
+
+	file, err := r.fsys.GetFile(fileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open body file %q: %w", fileKey, err)
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+func (r *bodyFileReader) close() {
+	if r.fsys != nil {
+		r.fsys.Close()
+	}
+}
+
+// storedRequestsFromRecords converts request records into stored requests,
+// with the bodies kept as files read back into ReqBody and ResBody. A body
+// file that can't be read is logged and left empty, with the error in
+// ReqBodyError or ResBodyError, so the other requests are still returned.
+func (db *DB) storedRequestsFromRecords(records []*core.Record) []StoredRequest {
+	files := &bodyFileReader{app: db.app}
+	defer files.close()
+
+	readBody := func(recordID string, fileKey string) (string, string) {
+		body, err := files.read(fileKey)
+		if err != nil {
+			db.app.Logger().Error(
+				"failed to read body file",
+				"fn", "storedRequestsFromRecords",
+				"record_id", recordID,
+				"error", err,
+			)
+			return "", err.Error()
+		}
+		return string(body), ""
+	}
+
+	storedRequests := make([]StoredRequest, 0, len(records))
+	for _, record := range records {
+		storedRequest := NewStoredRequestFromRecord(record)
+
+		if key := BodyFileKey(record, "req"); key != "" {
+			storedRequest.ReqBody, storedRequest.ReqBodyError = readBody(record.Id, key)
+		}
+		if key := BodyFileKey(record, "res"); key != "" {
+			storedRequest.ResBody, storedRequest.ResBodyError = readBody(record.Id, key)
+		}
+
+		storedRequests = append(storedRequests, storedRequest)
+	}
+
+	return storedRequests
+}
+
+// deleteBodyFiles removes the body files of records deleted without going
+// through the PocketBase record deletion, which would remove them itself.
+func (db *DB) deleteBodyFiles(collectionID string, recordIDs []string) {
+	if len(recordIDs) == 0 {
+		return
+	}
+
+	fsys, err := db.app.NewFilesystem()
+	if err != nil {
+		db.app.Logger().Error("failed to open filesystem", "fn", "deleteBodyFiles", "error", err)
+		return
+	}
+	defer fsys.Close()
+
+	for _, id := range recordIDs {
+		for _, err := range fsys.DeletePrefix(collectionID + "/" + id + "/") {
+			db.app.Logger().Error(
+				"failed to delete body file",
+				"fn", "deleteBodyFiles",
+				"record_id", id,
+				"error", err,
+			)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+//go:build !goexperiment.jsonv2
+
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uforg/ufogateway/internal/bodycodec"
+)
+
+func TestStoredRequestsWithUnreadableBodyFile(t *testing.T) {
+	db := newTestDB(t)
+	routeID := createTestRoute(t, db)
+
+	fileBody := bytes.Repeat([]byte("a"), inlineBodyMaxBytes+1)
+	entries := []RequestEntry{}
+	for _, id := range []string{"bodyfileok00001", "bodyfilemissing"} {
+		entries = append(entries, RequestEntry{
+			ID:          id,
+			RouteID:     routeID,
+			HasRequest:  true,
+			ReqMethod:   "POST",
+			ReqBody:     &Body{Data: fileBody, Meta: bodycodec.Meta{Size: len(fileBody)}},
+			HasResponse: true,
+			ResStatus:   200,
+			ResBody:     &Body{Data: []byte("ok"), Meta: bodycodec.Meta{Size: 2}},
+		})
+	}
+	saved, err := db.SaveLogEntries(entries, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, saved)
+
+	record, err := db.app.FindRecordById("requests", "bodyfilemissing")
+	require.NoError(t, err)
+	fsys, err := db.app.NewFilesystem()
+	require.NoError(t, err)
+	require.NoError(t, fsys.Delete(BodyFileKey(record, "req")))
+	fsys.Close()
+
+	storedRequests, err := db.FindStoredRequests("route = {:route}", 0, dbx.Params{"route": routeID})
+	require.NoError(t, err)
+	require.Len(t, storedRequests, 2)
+
+	byID := map[string]StoredRequest{}
+	for _, storedRequest := range storedRequests {
+		byID[storedRequest.ID] = storedRequest
+	}
+
+	assert.Equal(t, fileBody, byID["bodyfileok00001"].ReqBodyBytes())
+	assert.Empty(t, byID["bodyfileok00001"].ReqBodyError)
+
+	missing := byID["bodyfilemissing"]
+	assert.Empty(t, missing.ReqBody)
+	assert.NotEmpty(t, missing.ReqBodyError)
+	assert.Equal(t, "ok", missing.ResBody)
+	assert.Empty(t, missing.ResBodyError)
+}
@@ -2,11 +2,12 @@ package db
 
 import (
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
 	"github.com/uforg/ufogateway/internal/cache"
 )
 
 type DB struct {
-	app           *pocketbase.PocketBase
+	app           core.App
 	cacheInstance *cache.CacheInstance
 }
 
@@ -19,3 +20,11 @@ func NewDB(
 		cacheInstance: cacheInstance,
 	}
 }
+
+// RunInTransaction calls fn with a DB whose writes run in a single
+// transaction, which is rolled back when fn returns an error.
+func (db *DB) RunInTransaction(fn func(txDB *DB) error) error {
+	return db.app.RunInTransaction(func(txApp core.App) error {
+		return fn(&DB{app: txApp, cacheInstance: db.cacheInstance})
+	})
+}
@@ -0,0 +1,16 @@
+package db
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/bodycodec"
+)
+
+// getBodyMeta reads a json field holding the metadata of a stored body. Bodies
+// stored before the metadata existed get an empty one.
+func getBodyMeta(r *core.Record, key string) bodycodec.Meta {
+	meta := bodycodec.Meta{}
+	if err := r.UnmarshalJSONField(key, &meta); err != nil {
+		return bodycodec.Meta{}
+	}
+	return meta
+}
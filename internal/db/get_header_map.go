@@ -0,0 +1,30 @@
+package db
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// getHeaderMap reads a json field holding a header map. The values of the
+// map can be either a single string or a list of strings.
+func getHeaderMap(r *core.Record, key string) map[string][]string {
+	raw := map[string]any{}
+	if err := r.UnmarshalJSONField(key, &raw); err != nil {
+		return map[string][]string{}
+	}
+
+	headers := make(map[string][]string, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			headers[name] = []string{v}
+		case []any:
+			for _, item := range v {
+				if str, ok := item.(string); ok {
+					headers[name] = append(headers[name], str)
+				}
+			}
+		}
+	}
+
+	return headers
+}
@@ -0,0 +1,16 @@
+package db
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/redact"
+)
+
+// getRedactions reads the json field recording what was masked in each
+// stored field. Records without redactions get an empty marker.
+func getRedactions(r *core.Record, key string) redact.Marker {
+	redactions := redact.Marker{}
+	if err := r.UnmarshalJSONField(key, &redactions); err != nil || redactions == nil {
+		return redact.Marker{}
+	}
+	return redactions
+}
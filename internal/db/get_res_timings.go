@@ -0,0 +1,49 @@
+package db
+
+import (
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ResTimings is the stored breakdown of the time spent in the request to the
+// origin. Phases that didn't happen, like DNS on a reused connection, are
+// zero.
+type ResTimings struct {
+	DNS      time.Duration `json:"dns"`
+	Connect  time.Duration `json:"connect"`
+	TLS      time.Duration `json:"tls"`
+	Wait     time.Duration `json:"wait"`
+	Transfer time.Duration `json:"transfer"`
+	Total    time.Duration `json:"total"`
+}
+
+// getResTimings reads the json field holding the timings of the request to the
+// origin. It returns nil when there are none, like for mocked responses or
+// requests stored before the timings existed.
+func getResTimings(r *core.Record, key string) *ResTimings {
+	if raw := r.GetString(key); raw == "" || raw == "null" {
+		return nil
+	}
+
+	stored := struct {
+		DNS      int64 `json:"dns_us"`
+		Connect  int64 `json:"connect_us"`
+		TLS      int64 `json:"tls_us"`
+		Wait     int64 `json:"wait_us"`
+		Transfer int64 `json:"transfer_us"`
+		Total    int64 `json:"total_us"`
+	}{}
+	if err := r.UnmarshalJSONField(key, &stored); err != nil {
+		return nil
+	}
+
+	return &ResTimings{
+		DNS:      time.Duration(stored.DNS) * time.Microsecond,
+		Connect:  time.Duration(stored.Connect) * time.Microsecond,
+		TLS:      time.Duration(stored.TLS) * time.Microsecond,
+		Wait:     time.Duration(stored.Wait) * time.Microsecond,
+		Transfer: time.Duration(stored.Transfer) * time.Microsecond,
+		Total:    time.Duration(stored.Total) * time.Microsecond,
+	}
+}
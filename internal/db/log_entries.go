@@ -0,0 +1,221 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/bodycodec"
+	"github.com/uforg/ufogateway/internal/redact"
+)
+
+const mirrorResponsesCollectionName = "mirror_responses"
+
+// Body is a body ready to be stored along with its metadata.
+type Body struct {
+	Data []byte
+	Meta bodycodec.Meta
+}
+
+// RequestEntry holds every field of a requests record, so the record is
+// written once instead of once per field.
+//
+// An entry may only hold the request or only the response part, when the
+// other part was written in an earlier batch. A response-only entry updates
+// the existing record.
+type RequestEntry struct {
+	ID      string
+	RouteID string
+
+	HasRequest     bool
+	ReqTimestamp   time.Time
+	ReqIP          string
+	ReqMethod      string
+	ReqGatewayURL  string
+	ReqOriginURL   string
+	ReqHeaders     map[string][]string // nil when the route does not store them
+	ReqBody        *Body               // nil when the route does not store it
+	ReqBodySize    int64
+	ReqBlockReason string
+	ReplayOf       string
+	ReplayBatch    string
+	TraceID        string
+
+	HasResponse           bool
+	ResTimestamp          time.Time
+	ResDuration           time.Duration
+	ResStatus             int
+	ResCacheHit           bool
+	ResCoalescedWith      string
+	ResCoalescedFollowers int
+	ResClientEncoding     string
+	ResHeaders            map[string][]string // nil when the route does not store them
+	ResBody               *Body               // nil when the route does not store it
+	ResTimings            any                 // encoded as JSON, nil when there are none
+	UpstreamError         any                 // encoded as JSON, nil when there is none
+	ErrorKind             string
+
+	ContractFindings []any
+	Redactions       redact.Marker
+}
+
+// MirrorResponseEntry holds every field of a mirror_responses record.
+type MirrorResponseEntry struct {
+	RouteID     string
+	RequestID   string
+	MirrorURL   string
+	ResStatus   int
+	ResDuration time.Duration
+	ResHeaders  map[string][]string
+	ResBody     *Body
+	Redactions  redact.Marker
+	Error       string
+}
+
+// ErrRequestNotFound is returned for a response-only entry whose request
+// record does not exist, usually because its request entry was dropped.
+var ErrRequestNotFound = errors.New("request record not found")
+
+// SaveLogEntries writes the entries in a single transaction.
+//
+// An entry that fails to be written does not roll back the others, its error
+// is returned in the joined error along with the ones of other failed
+// entries. The returned count is the number of entries written.
+func (db *DB) SaveLogEntries(requests []RequestEntry, mirrors []MirrorResponseEntry) (int, error) {
+	saved := 0
+	errs := []error{}
+
+	err := db.app.RunInTransaction(func(txApp core.App) error {
+		requestsCollection, err := txApp.FindCachedCollectionByNameOrId(requestsCollectionName)
+		if err != nil {
+			return err
+		}
+		mirrorsCollection, err := txApp.FindCachedCollectionByNameOrId(mirrorResponsesCollectionName)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range requests {
+			if err := saveRequestEntry(txApp, requestsCollection, entry); err != nil {
+				errs = append(errs, fmt.Errorf("request %s: %w", entry.ID, err))
+				continue
+			}
+			saved++
+		}
+
+		for _, entry := range mirrors {
+			if err := saveMirrorResponseEntry(txApp, mirrorsCollection, entry); err != nil {
+				errs = append(errs, fmt.Errorf("mirror response of request %s: %w", entry.RequestID, err))
+				continue
+			}
+			saved++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return saved, errors.Join(errs...)
+}
+
+func saveRequestEntry(app core.App, collection *core.Collection, entry RequestEntry) error {
+	var record *core.Record
+	if entry.HasRequest {
+		record = core.NewRecord(collection)
+		record.Id = entry.ID
+		record.Set("route", entry.RouteID)
+		record.Set("req_timestamp", entry.ReqTimestamp)
+		record.Set("req_ip", entry.ReqIP)
+		record.Set("req_method", entry.ReqMethod)
+		record.Set("req_gateway_url", entry.ReqGatewayURL)
+		record.Set("req_origin_url", entry.ReqOriginURL)
+		record.Set("req_body_size", entry.ReqBodySize)
+		record.Set("req_block_reason", entry.ReqBlockReason)
+		record.Set("replay_of", entry.ReplayOf)
+		record.Set("replay_batch", entry.ReplayBatch)
+		record.Set("trace_id", entry.TraceID)
+		if entry.ReqHeaders != nil {
+			record.Set("req_headers", entry.ReqHeaders)
+		}
+		if entry.ReqBody != nil {
+			if err := setBody(record, reqBodyFields, entry.ReqBody.Data, entry.ReqBody.Meta); err != nil {
+				return err
+			}
+		}
+	} else {
+		var err error
+		record, err = app.FindRecordById(collection, entry.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRequestNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if entry.HasResponse {
+		record.Set("res_timestamp", entry.ResTimestamp)
+		record.Set("res_duration_us", entry.ResDuration.Microseconds())
+		record.Set("res_status", entry.ResStatus)
+		record.Set("res_cache_hit", entry.ResCacheHit)
+		record.Set("res_coalesced_with", entry.ResCoalescedWith)
+		record.Set("res_coalesced_followers", entry.ResCoalescedFollowers)
+		record.Set("res_client_encoding", entry.ResClientEncoding)
+		record.Set("error_kind", entry.ErrorKind)
+		if entry.ResTimings != nil {
+			record.Set("res_timings", entry.ResTimings)
+		}
+		if entry.UpstreamError != nil {
+			record.Set("upstream_error", entry.UpstreamError)
+		}
+		if entry.ResHeaders != nil {
+			record.Set("res_headers", entry.ResHeaders)
+		}
+		if entry.ResBody != nil {
+			if err := setBody(record, resBodyFields, entry.ResBody.Data, entry.ResBody.Meta); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(entry.ContractFindings) > 0 {
+		findings := []any{}
+		if err := record.UnmarshalJSONField("contract_findings", &findings); err != nil {
+			findings = []any{}
+		}
+		record.Set("contract_findings", append(findings, entry.ContractFindings...))
+	}
+
+	if len(entry.Redactions) > 0 {
+		redactions := getRedactions(record, "redactions")
+		for field, masked := range entry.Redactions {
+			redactions.Add(field, masked)
+		}
+		record.Set("redactions", redactions)
+	}
+
+	return app.Save(record)
+}
+
+func saveMirrorResponseEntry(app core.App, collection *core.Collection, entry MirrorResponseEntry) error {
+	record := core.NewRecord(collection)
+	record.Set("route", entry.RouteID)
+	record.Set("request_id", entry.RequestID)
+	record.Set("mirror_url", entry.MirrorURL)
+	record.Set("res_status", entry.ResStatus)
+	record.Set("res_duration_us", entry.ResDuration.Microseconds())
+	record.Set("res_headers", entry.ResHeaders)
+	record.Set("redactions", entry.Redactions)
+	record.Set("error", entry.Error)
+	if entry.ResBody != nil {
+		if err := setBody(record, resBodyFields, entry.ResBody.Data, entry.ResBody.Meta); err != nil {
+			return err
+		}
+	}
+
+	return app.Save(record)
+}
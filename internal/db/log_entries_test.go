@@ -0,0 +1,169 @@
+//go:build !goexperiment.jsonv2
+
+package db
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uforg/ufogateway/internal/bodycodec"
+	"github.com/uforg/ufogateway/internal/redact"
+)
+
+func TestSaveLogEntries(t *testing.T) {
+	db := newTestDB(t)
+	routeID := createTestRoute(t, db)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	requestPart := func(id string) RequestEntry {
+		return RequestEntry{
+			ID:               id,
+			RouteID:          routeID,
+			HasRequest:       true,
+			ReqTimestamp:     now,
+			ReqMethod:        "POST",
+			ReqGatewayURL:    "http://gateway.test/api/users",
+			ReqHeaders:       map[string][]string{"Authorization": {redact.Mask}},
+			ReqBody:          &Body{Data: []byte(`{"name":"ann"}`), Meta: bodycodec.Meta{Size: 14}},
+			ContractFindings: []any{map[string]any{"kind": "request", "location": "body", "message": "a"}},
+			Redactions:       redact.Marker{"req_headers": {"Authorization"}},
+		}
+	}
+	responsePart := func(id string) RequestEntry {
+		return RequestEntry{
+			ID:               id,
+			RouteID:          routeID,
+			HasResponse:      true,
+			ResTimestamp:     now.Add(time.Second),
+			ResDuration:      1500 * time.Microsecond,
+			ResStatus:        201,
+			ResHeaders:       map[string][]string{"Content-Type": {"application/json"}},
+			ResBody:          &Body{Data: []byte(`{"id":1}`), Meta: bodycodec.Meta{Size: 8}},
+			ContractFindings: []any{map[string]any{"kind": "response", "location": "status", "message": "b"}},
+			Redactions:       redact.Marker{"req_headers": {"Cookie"}, "res_body": {"$.token"}},
+		}
+	}
+
+	t.Run("writes complete entries", func(t *testing.T) {
+		entry := requestPart("complete0000001")
+		response := responsePart("complete0000001")
+		entry.HasResponse = true
+		entry.ResStatus = response.ResStatus
+		entry.ResDuration = response.ResDuration
+
+		saved, err := db.SaveLogEntries([]RequestEntry{entry}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1, saved)
+
+		record, err := db.app.FindRecordById("requests", "complete0000001")
+		require.NoError(t, err)
+		assert.Equal(t, routeID, record.GetString("route"))
+		assert.Equal(t, 201, record.GetInt("res_status"))
+		assert.Equal(t, 1500, record.GetInt("res_duration_us"))
+	})
+
+	t.Run("a response-only entry updates the written request", func(t *testing.T) {
+		saved, err := db.SaveLogEntries([]RequestEntry{requestPart("pairedrequest01")}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1, saved)
+
+		saved, err = db.SaveLogEntries([]RequestEntry{responsePart("pairedrequest01")}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1, saved)
+
+		storedRequest, err := db.GetStoredRequestByID("pairedrequest01")
+		require.NoError(t, err)
+		assert.Equal(t, "POST", storedRequest.ReqMethod)
+		assert.Equal(t, `{"name":"ann"}`, string(storedRequest.ReqBodyBytes()))
+		assert.Equal(t, 201, storedRequest.ResStatus)
+		assert.Equal(t, map[string][]string{"Content-Type": {"application/json"}}, storedRequest.ResHeaders)
+		assert.Equal(t, `{"id":1}`, string(storedRequest.ResBodyBytes()))
+
+		record, err := db.app.FindRecordById("requests", "pairedrequest01")
+		require.NoError(t, err)
+
+		findings := []map[string]any{}
+		require.NoError(t, record.UnmarshalJSONField("contract_findings", &findings))
+		assert.Len(t, findings, 2)
+
+		redactions := redact.Marker{}
+		require.NoError(t, record.UnmarshalJSONField("redactions", &redactions))
+		assert.Equal(t, redact.Marker{
+			"req_headers": {"Authorization", "Cookie"},
+			"res_body":    {"$.token"},
+		}, redactions)
+	})
+
+	t.Run("counts the entries written when some fail", func(t *testing.T) {
+		invalidRoute := requestPart("invalidroute001")
+		invalidRoute.RouteID = "missingroute001"
+
+		saved, err := db.SaveLogEntries([]RequestEntry{
+			requestPart("partialwrite001"),
+			responsePart("neverrequested1"),
+			invalidRoute,
+		}, nil)
+
+		assert.Equal(t, 1, saved)
+		assert.ErrorIs(t, err, ErrRequestNotFound)
+		assert.ErrorContains(t, err, "invalidroute001")
+
+		_, err = db.app.FindRecordById("requests", "partialwrite001")
+		assert.NoError(t, err)
+		_, err = db.app.FindRecordById("requests", "invalidroute001")
+		assert.Error(t, err)
+	})
+
+	t.Run("writes mirror responses", func(t *testing.T) {
+		largeBody := bytes.Repeat([]byte{0xff, 0x00}, inlineBodyMaxBytes)
+
+		saved, err := db.SaveLogEntries(nil, []MirrorResponseEntry{
+			{
+				RouteID:     routeID,
+				RequestID:   "mirroredreq0001",
+				MirrorURL:   "http://shadow.test/users",
+				ResStatus:   200,
+				ResDuration: 2 * time.Millisecond,
+				ResHeaders:  map[string][]string{"Content-Type": {"text/plain"}},
+				ResBody:     &Body{Data: []byte("shadow"), Meta: bodycodec.Meta{Size: 6}},
+			},
+			{
+				RouteID:   routeID,
+				RequestID: "mirroredreq0001",
+				MirrorURL: "http://shadow.test/users",
+				ResBody:   &Body{Data: largeBody, Meta: bodycodec.Meta{Size: len(largeBody)}},
+			},
+			{
+				RouteID:   routeID,
+				RequestID: "mirroredreq0002",
+				MirrorURL: "http://unreachable.test/users",
+				Error:     "connection refused",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, saved)
+
+		records, err := db.app.FindAllRecords(
+			mirrorResponsesCollectionName,
+			dbx.HashExp{"request_id": "mirroredreq0001"},
+		)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		bodies := map[string]string{}
+		for _, record := range records {
+			bodies[record.GetString("res_body")] = record.GetString("res_body_file")
+		}
+		assert.Contains(t, bodies, "shadow")
+		assert.NotEmpty(t, bodies[""], "the large body is stored as a file")
+
+		failed, err := db.app.FindFirstRecordByData(mirrorResponsesCollectionName, "request_id", "mirroredreq0002")
+		require.NoError(t, err)
+		assert.Equal(t, "connection refused", failed.GetString("error"))
+		assert.Equal(t, 0, failed.GetInt("res_status"))
+	})
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const openapiSpecsCollectionName = "openapi_specs"
+
+type OpenAPISpec struct {
+	ID       string    `db:"id" json:"id"`
+	Project  string    `db:"project" json:"project"`
+	Name     string    `db:"name" json:"name"`
+	Document string    `db:"document" json:"document"`
+	Created  time.Time `db:"created" json:"created"`
+	Updated  time.Time `db:"updated" json:"updated"`
+}
+
+func NewOpenAPISpecFromRecord(r *core.Record) OpenAPISpec {
+	return OpenAPISpec{
+		ID:       r.Id,
+		Project:  r.GetString("project"),
+		Name:     r.GetString("name"),
+		Document: r.GetString("document"),
+		Created:  r.GetDateTime("created").Time(),
+		Updated:  r.GetDateTime("updated").Time(),
+	}
+}
+
+func (db *DB) GetOpenAPISpecByID(specID string) (OpenAPISpec, error) {
+	record, err := db.app.FindRecordById(openapiSpecsCollectionName, specID)
+	if err != nil {
+		return OpenAPISpec{}, err
+	}
+
+	return NewOpenAPISpecFromRecord(record), nil
+}
+
+func (db *DB) GetOpenAPISpecByIDCached(specID string) (OpenAPISpec, error) {
+	key := "db.GetOpenAPISpecByIDCached." + specID
+
+	cachedSpec, found := db.cacheInstance.Get(key)
+	if found {
+		return cachedSpec.(OpenAPISpec), nil
+	}
+
+	dbSpec, err := db.GetOpenAPISpecByID(specID)
+	if err != nil {
+		return OpenAPISpec{}, err
+	}
+
+	db.cacheInstance.Set(key, dbSpec, 5*time.Second)
+	return dbSpec, nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+type Project struct {
+	ID                 string    `db:"id" json:"id"`
+	Name               string    `db:"name" json:"name"`
+	Owner              string    `db:"owner" json:"owner"`
+	IPAllowList        string    `db:"ip_allow_list" json:"ip_allow_list"`
+	IPDenyList         string    `db:"ip_deny_list" json:"ip_deny_list"`
+	RedactHeaders      string    `db:"redact_headers" json:"redact_headers"`
+	RedactJSONPaths    string    `db:"redact_json_paths" json:"redact_json_paths"`
+	RedactFormFields   string    `db:"redact_form_fields" json:"redact_form_fields"`
+	RedactPatterns     string    `db:"redact_patterns" json:"redact_patterns"`
+	RedactSkipDefaults bool      `db:"redact_skip_defaults" json:"redact_skip_defaults"`
+	ErrorTemplate      string    `db:"error_template" json:"error_template"`
+	ErrorContentType   string    `db:"error_content_type" json:"error_content_type"`
+	Created            time.Time `db:"created" json:"created"`
+	Updated            time.Time `db:"updated" json:"updated"`
+}
+
+func NewProjectFromRecord(r *core.Record) Project {
+	return Project{
+		ID:                 r.Id,
+		Name:               r.GetString("name"),
+		Owner:              r.GetString("owner"),
+		IPAllowList:        r.GetString("ip_allow_list"),
+		IPDenyList:         r.GetString("ip_deny_list"),
+		RedactHeaders:      r.GetString("redact_headers"),
+		RedactJSONPaths:    r.GetString("redact_json_paths"),
+		RedactFormFields:   r.GetString("redact_form_fields"),
+		RedactPatterns:     r.GetString("redact_patterns"),
+		RedactSkipDefaults: r.GetBool("redact_skip_defaults"),
+		ErrorTemplate:      r.GetString("error_template"),
+		ErrorContentType:   r.GetString("error_content_type"),
+		Created:            r.GetDateTime("created").Time(),
+		Updated:            r.GetDateTime("updated").Time(),
+	}
+}
+
+func (db *DB) GetProjectByID(projectID string) (Project, error) {
+	record, err := db.app.FindRecordById("projects", projectID)
+	if err != nil {
+		return Project{}, err
+	}
+
+	return NewProjectFromRecord(record), nil
+}
+
+func (db *DB) GetProjectByIDCached(projectID string) (Project, error) {
+	key := "db.GetProjectByIDCached." + projectID
+
+	cachedProject, found := db.cacheInstance.Get(key)
+	if found {
+		return cachedProject.(Project), nil
+	}
+
+	dbProject, err := db.GetProjectByID(projectID)
+	if err != nil {
+		return Project{}, err
+	}
+
+	db.cacheInstance.Set(key, dbProject, 5*time.Second)
+	return dbProject, nil
+}
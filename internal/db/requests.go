@@ -1,9 +1,14 @@
 package db
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/uforg/ufogateway/internal/bodycodec"
+	"github.com/uforg/ufogateway/internal/redact"
 )
 
 const requestsCollectionName = "requests"
@@ -12,98 +17,135 @@ func (db *DB) getRequestsCollection() (*core.Collection, error) {
 	return db.app.FindCollectionByNameOrId(requestsCollectionName)
 }
 
-func (db *DB) CreateRequest(
-	requestID string,
-	routeID string,
-	reqTimestamp time.Time,
-	reqIP string,
-	reqMethod string,
-	reqGatewayURL string,
-	reqOriginURL string,
-) error {
-	collection, err := db.getRequestsCollection()
-	if err != nil {
-		return err
-	}
-
-	record := core.NewRecord(collection)
-	record.Id = requestID
-	record.Set("route", routeID)
-	record.Set("req_timestamp", reqTimestamp)
-	record.Set("req_ip", reqIP)
-	record.Set("req_method", reqMethod)
-	record.Set("req_gateway_url", reqGatewayURL)
-	record.Set("req_origin_url", reqOriginURL)
-
-	return db.app.Save(record)
-}
-
 func (db *DB) GetRequestRecordByID(requestID string) (*core.Record, error) {
 	return db.app.FindRecordById(requestsCollectionName, requestID)
 }
 
-func (db *DB) StoreRequestReqHeaders(requestID string, reqHeaders map[string][]string) error {
-	record, err := db.GetRequestRecordByID(requestID)
-	if err != nil {
-		return err
+// StoredRequest is the part of a requests record needed to send the request
+// again and to compare its response. Bodies stored as files are only filled
+// in when the stored request is loaded through DB.
+type StoredRequest struct {
+	ID            string              `db:"id" json:"id"`
+	RouteID       string              `db:"route" json:"route"`
+	ReqTimestamp  time.Time           `db:"req_timestamp" json:"req_timestamp"`
+	ReqMethod     string              `db:"req_method" json:"req_method"`
+	ReqGatewayURL string              `db:"req_gateway_url" json:"req_gateway_url"`
+	ReqHeaders    map[string][]string `db:"req_headers" json:"req_headers"`
+	ReqBody       string              `db:"req_body" json:"req_body"`
+	ReqBodyMeta   bodycodec.Meta      `db:"req_body_meta" json:"req_body_meta"`
+	ResDuration   time.Duration       `db:"res_duration_us" json:"res_duration"`
+	ResStatus     int                 `db:"res_status" json:"res_status"`
+	ResHeaders    map[string][]string `db:"res_headers" json:"res_headers"`
+	ResBody       string              `db:"res_body" json:"res_body"`
+	ResBodyMeta   bodycodec.Meta      `db:"res_body_meta" json:"res_body_meta"`
+	ReqBodyError  string              `db:"-" json:"req_body_error,omitempty"` // is set when the stored body file couldn't be read
+	ResBodyError  string              `db:"-" json:"res_body_error,omitempty"` // is set when the stored body file couldn't be read
+	ResTimings    *ResTimings         `db:"res_timings" json:"res_timings"`
+	Redactions    redact.Marker       `db:"redactions" json:"redactions"`
+	ReplayOf      string              `db:"replay_of" json:"replay_of"`
+	ReplayBatch   string              `db:"replay_batch" json:"replay_batch"`
+}
+
+func NewStoredRequestFromRecord(r *core.Record) StoredRequest {
+	return StoredRequest{
+		ID:            r.Id,
+		RouteID:       r.GetString("route"),
+		ReqTimestamp:  r.GetDateTime("req_timestamp").Time(),
+		ReqMethod:     r.GetString("req_method"),
+		ReqGatewayURL: r.GetString("req_gateway_url"),
+		ReqHeaders:    getHeaderMap(r, "req_headers"),
+		ReqBody:       r.GetString("req_body"),
+		ReqBodyMeta:   getBodyMeta(r, "req_body_meta"),
+		ResDuration:   time.Duration(r.GetInt("res_duration_us")) * time.Microsecond,
+		ResStatus:     r.GetInt("res_status"),
+		ResHeaders:    getHeaderMap(r, "res_headers"),
+		ResBody:       r.GetString("res_body"),
+		ResBodyMeta:   getBodyMeta(r, "res_body_meta"),
+		ResTimings:    getResTimings(r, "res_timings"),
+		Redactions:    getRedactions(r, "redactions"),
+		ReplayOf:      r.GetString("replay_of"),
+		ReplayBatch:   r.GetString("replay_batch"),
 	}
+}
 
-	record.Set("req_headers", reqHeaders)
+// ReqBodyBytes returns the stored request body, which is decoded if
+// ReqBodyMeta.Decoded is true.
+func (sr StoredRequest) ReqBodyBytes() []byte {
+	return bodycodec.FromStorage(sr.ReqBody, sr.ReqBodyMeta)
+}
 
-	return db.app.Save(record)
+// ResBodyBytes returns the stored response body, which is decoded if
+// ResBodyMeta.Decoded is true.
+func (sr StoredRequest) ResBodyBytes() []byte {
+	return bodycodec.FromStorage(sr.ResBody, sr.ResBodyMeta)
 }
 
-func (db *DB) StoreRequestReqBody(requestID string, reqBody string) error {
+func (db *DB) GetStoredRequestByID(requestID string) (StoredRequest, error) {
 	record, err := db.GetRequestRecordByID(requestID)
 	if err != nil {
-		return err
+		return StoredRequest{}, err
 	}
 
-	record.Set("req_body", reqBody)
-
-	return db.app.Save(record)
+	return db.storedRequestsFromRecords([]*core.Record{record})[0], nil
 }
 
-func (db *DB) StoreRequestResTimestamp(
-	requestID string,
-	resTimestamp time.Time,
-	resDuration time.Duration,
-) error {
-	record, err := db.GetRequestRecordByID(requestID)
+// FindStoredRequests returns the oldest stored requests first that match the
+// given PocketBase filter expression, up to limit records.
+func (db *DB) FindStoredRequests(
+	filter string,
+	limit int,
+	params ...dbx.Params,
+) ([]StoredRequest, error) {
+	records, err := db.app.FindRecordsByFilter(
+		requestsCollectionName,
+		filter,
+		"req_timestamp",
+		limit,
+		0,
+		params...,
+	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	record.Set("res_timestamp", resTimestamp)
-	record.Set("res_duration", resDuration.String())
-
-	return db.app.Save(record)
+	return db.storedRequestsFromRecords(records), nil
 }
 
-func (db *DB) StoreRequestResHeaders(requestID string, resHeaders map[string][]string) error {
-	record, err := db.GetRequestRecordByID(requestID)
+// FindStoredRequestsByReplayBatch returns the replays of the given batch in
+// the order they were sent.
+func (db *DB) FindStoredRequestsByReplayBatch(batchID string) ([]StoredRequest, error) {
+	records, err := db.app.FindRecordsByFilter(
+		requestsCollectionName,
+		"replay_batch = {:batch}",
+		"req_timestamp",
+		0,
+		0,
+		dbx.Params{"batch": batchID},
+	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	record.Set("res_headers", resHeaders)
-
-	return db.app.Save(record)
+	return db.storedRequestsFromRecords(records), nil
 }
 
-func (db *DB) StoreRequestResBody(requestID string, resBody string) error {
-	record, err := db.GetRequestRecordByID(requestID)
+// DeleteExpiredRequests deletes the requests past the retention of their
+// route, along with their body files and mirror responses, and returns how
+// many requests were deleted.
+func (db *DB) DeleteExpiredRequests() (int64, error) {
+	collection, err := db.getRequestsCollection()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	record.Set("res_body", resBody)
-
-	return db.app.Save(record)
-}
+	type deletedRequest struct {
+		ID          string `db:"id"`
+		ReqBodyFile string `db:"req_body_file"`
+		ResBodyFile string `db:"res_body_file"`
+	}
 
-func (db *DB) DeleteExpiredRequests() (int64, error) {
-	res1, err := db.app.DB().
+	deletedByDays := []deletedRequest{}
+	err = db.app.DB().
 		NewQuery(`
 			WITH requests_to_delete AS (
 				SELECT requests.id
@@ -114,18 +156,16 @@ func (db *DB) DeleteExpiredRequests() (int64, error) {
 					AND requests.created < date('now', '-' || routes.retention_days || ' days')
 			)
 			DELETE FROM requests
-			WHERE id IN (SELECT id FROM requests_to_delete);
+			WHERE id IN (SELECT id FROM requests_to_delete)
+			RETURNING id, req_body_file, res_body_file;
 		`).
-		Execute()
-	if err != nil {
-		return 0, err
-	}
-	res1AffectedRows, err := res1.RowsAffected()
+		All(&deletedByDays)
 	if err != nil {
 		return 0, err
 	}
 
-	res2, err := db.app.DB().
+	deletedByHits := []deletedRequest{}
+	err = db.app.DB().
 		NewQuery(`
 			WITH ranked_requests AS (
 				SELECT 
@@ -144,16 +184,83 @@ func (db *DB) DeleteExpiredRequests() (int64, error) {
 				SELECT id 
 				FROM ranked_requests 
 				WHERE rn > retention_hits
-			);
+			)
+			RETURNING id, req_body_file, res_body_file;
 		`).
-		Execute()
+		All(&deletedByHits)
 	if err != nil {
 		return 0, err
 	}
-	res2AffectedRows, err := res2.RowsAffected()
-	if err != nil {
+
+	deleted := append(deletedByDays, deletedByHits...)
+	deletedIDs := make([]string, 0, len(deleted))
+	withFiles := []string{}
+	for _, request := range deleted {
+		deletedIDs = append(deletedIDs, request.ID)
+		if request.ReqBodyFile != "" || request.ResBodyFile != "" {
+			withFiles = append(withFiles, request.ID)
+		}
+	}
+	db.deleteBodyFiles(collection.Id, withFiles)
+
+	if err := db.deleteOrphanMirrorResponses(deletedIDs); err != nil {
 		return 0, err
 	}
 
-	return res1AffectedRows + res2AffectedRows, nil
+	return int64(len(deleted)), nil
+}
+
+// mirrorResponseOrphanGrace is how long a mirror response is kept without a
+// stored primary request, which may be waiting in the log queue or not be
+// stored at all because of the route sampling.
+const mirrorResponseOrphanGrace = time.Hour
+
+// deleteOrphanMirrorResponses deletes the mirror responses of the deleted
+// requests, and those older than mirrorResponseOrphanGrace whose primary
+// request is not stored, along with their body files.
+func (db *DB) deleteOrphanMirrorResponses(deletedRequestIDs []string) error {
+	collection, err := db.app.FindCachedCollectionByNameOrId(mirrorResponsesCollectionName)
+	if err != nil {
+		return err
+	}
+
+	ids, err := json.Marshal(deletedRequestIDs)
+	if err != nil {
+		return err
+	}
+	orphanBefore, err := types.ParseDateTime(time.Now().Add(-mirrorResponseOrphanGrace))
+	if err != nil {
+		return err
+	}
+
+	deleted := []struct {
+		ID          string `db:"id"`
+		ResBodyFile string `db:"res_body_file"`
+	}{}
+	err = db.app.DB().
+		NewQuery(`
+			DELETE FROM mirror_responses
+			WHERE
+				request_id IN (SELECT value FROM json_each({:ids}))
+				OR (
+					created < {:orphanBefore}
+					AND request_id NOT IN (SELECT id FROM requests)
+				)
+			RETURNING id, res_body_file;
+		`).
+		Bind(dbx.Params{"ids": string(ids), "orphanBefore": orphanBefore.String()}).
+		All(&deleted)
+	if err != nil {
+		return err
+	}
+
+	withFiles := []string{}
+	for _, mirror := range deleted {
+		if mirror.ResBodyFile != "" {
+			withFiles = append(withFiles, mirror.ID)
+		}
+	}
+	db.deleteBodyFiles(collection.Id, withFiles)
+
+	return nil
 }
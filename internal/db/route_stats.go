@@ -0,0 +1,319 @@
+package db
+
+import (
+	"slices"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/uforg/ufogateway/internal/routestats"
+)
+
+const routeStatsCollectionName = "route_stats"
+
+const (
+	// routeStatsMinuteRetention is how long minute buckets are kept once
+	// they are compacted into hour buckets.
+	routeStatsMinuteRetention = 48 * time.Hour
+	// routeStatsHourRetention is how long hour buckets are kept once they
+	// are compacted into day buckets. Day buckets are kept forever.
+	routeStatsHourRetention = 90 * 24 * time.Hour
+)
+
+// RouteStat is the traffic of a route during a bucket of time.
+type RouteStat struct {
+	RouteID     string
+	ProjectID   string
+	Granularity string
+	Start       time.Time
+	routestats.Bucket
+}
+
+func NewRouteStatFromRecord(r *core.Record) RouteStat {
+	histogram := routestats.Histogram{}
+	if err := r.UnmarshalJSONField("latency_histogram", &histogram); err != nil || histogram == nil {
+		histogram = routestats.Histogram{}
+	}
+
+	return RouteStat{
+		RouteID:     r.GetString("route"),
+		ProjectID:   r.GetString("project"),
+		Granularity: r.GetString("granularity"),
+		Start:       r.GetDateTime("bucket").Time(),
+		Bucket: routestats.Bucket{
+			Requests:   int64(r.GetInt("requests")),
+			Status1xx:  int64(r.GetInt("status_1xx")),
+			Status2xx:  int64(r.GetInt("status_2xx")),
+			Status3xx:  int64(r.GetInt("status_3xx")),
+			Status4xx:  int64(r.GetInt("status_4xx")),
+			Status5xx:  int64(r.GetInt("status_5xx")),
+			BytesIn:    int64(r.GetInt("bytes_in")),
+			BytesOut:   int64(r.GetInt("bytes_out")),
+			LatencySum: time.Duration(r.GetInt("latency_sum_us")) * time.Microsecond,
+			LatencyMax: time.Duration(r.GetInt("latency_max_us")) * time.Microsecond,
+			Latency:    histogram,
+		},
+	}
+}
+
+// setRouteStatBucket sets the traffic of the bucket on the record, along
+// with the percentiles estimated from it.
+func setRouteStatBucket(record *core.Record, bucket routestats.Bucket) {
+	record.Set("requests", bucket.Requests)
+	record.Set("status_1xx", bucket.Status1xx)
+	record.Set("status_2xx", bucket.Status2xx)
+	record.Set("status_3xx", bucket.Status3xx)
+	record.Set("status_4xx", bucket.Status4xx)
+	record.Set("status_5xx", bucket.Status5xx)
+	record.Set("bytes_in", bucket.BytesIn)
+	record.Set("bytes_out", bucket.BytesOut)
+	record.Set("latency_sum_us", bucket.LatencySum.Microseconds())
+	record.Set("latency_max_us", bucket.LatencyMax.Microseconds())
+	record.Set("latency_p50_us", bucket.Percentile(50).Microseconds())
+	record.Set("latency_p90_us", bucket.Percentile(90).Microseconds())
+	record.Set("latency_p95_us", bucket.Percentile(95).Microseconds())
+	record.Set("latency_p99_us", bucket.Percentile(99).Microseconds())
+	record.Set("latency_histogram", bucket.Latency)
+}
+
+// findRouteStatRecord returns the stored bucket of the route, or a new record
+// for it when there is none.
+func findRouteStatRecord(
+	app core.App,
+	collection *core.Collection,
+	routeID string,
+	granularity string,
+	start time.Time,
+) (*core.Record, error) {
+	bucket, err := types.ParseDateTime(start)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := app.FindAllRecords(collection, dbx.HashExp{
+		"route":       routeID,
+		"granularity": granularity,
+		"bucket":      bucket.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		return records[0], nil
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("route", routeID)
+	record.Set("granularity", granularity)
+	record.Set("bucket", bucket)
+	return record, nil
+}
+
+// AddRouteStats adds the traffic of the stats to their stored buckets, in a
+// single transaction.
+func (db *DB) AddRouteStats(stats []RouteStat) error {
+	return db.app.RunInTransaction(func(txApp core.App) error {
+		collection, err := txApp.FindCachedCollectionByNameOrId(routeStatsCollectionName)
+		if err != nil {
+			return err
+		}
+
+		for _, stat := range stats {
+			record, err := findRouteStatRecord(txApp, collection, stat.RouteID, stat.Granularity, stat.Start)
+			if err != nil {
+				return err
+			}
+
+			bucket := NewRouteStatFromRecord(record).Bucket
+			bucket.Merge(stat.Bucket)
+
+			record.Set("project", stat.ProjectID)
+			record.Set("compacted", false)
+			setRouteStatBucket(record, bucket)
+			if err := txApp.Save(record); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// FindRouteStats returns the buckets of the granularity that start from
+// from and before to, oldest first, of a route or, when routeID is empty, of
+// every route of a project.
+func (db *DB) FindRouteStats(
+	routeID string,
+	projectID string,
+	granularity string,
+	from time.Time,
+	to time.Time,
+) ([]RouteStat, error) {
+	owner := dbx.HashExp{"route": routeID}
+	if routeID == "" {
+		owner = dbx.HashExp{"project": projectID}
+	}
+
+	fromDate, err := types.ParseDateTime(from)
+	if err != nil {
+		return nil, err
+	}
+	toDate, err := types.ParseDateTime(to)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := db.app.FindAllRecords(
+		routeStatsCollectionName,
+		owner,
+		dbx.HashExp{"granularity": granularity},
+		dbx.NewExp("bucket >= {:from} AND bucket < {:to}", dbx.Params{
+			"from": fromDate.String(),
+			"to":   toDate.String(),
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]RouteStat, 0, len(records))
+	for _, record := range records {
+		stats = append(stats, NewRouteStatFromRecord(record))
+	}
+	slices.SortFunc(stats, func(a, b RouteStat) int {
+		return a.Start.Compare(b.Start)
+	})
+	return stats, nil
+}
+
+// CompactRouteStats rolls the minute buckets of the hours completed before
+// now up into hour buckets, and the hour buckets of the completed days into
+// day buckets. The compacted buckets are deleted once past their retention.
+func (db *DB) CompactRouteStats(now time.Time) error {
+	return db.app.RunInTransaction(func(txApp core.App) error {
+		collection, err := txApp.FindCachedCollectionByNameOrId(routeStatsCollectionName)
+		if err != nil {
+			return err
+		}
+
+		steps := []struct {
+			from      string
+			to        string
+			retention time.Duration
+		}{
+			{from: routestats.GranularityMinute, to: routestats.GranularityHour, retention: routeStatsMinuteRetention},
+			{from: routestats.GranularityHour, to: routestats.GranularityDay, retention: routeStatsHourRetention},
+		}
+
+		for _, step := range steps {
+			err := compactRouteStats(txApp, collection, step.from, step.to, routestats.Truncate(now, step.to))
+			if err != nil {
+				return err
+			}
+
+			expired, err := types.ParseDateTime(now.Add(-step.retention))
+			if err != nil {
+				return err
+			}
+			_, err = txApp.DB().
+				Delete(routeStatsCollectionName, dbx.And(
+					dbx.HashExp{"granularity": step.from, "compacted": true},
+					dbx.NewExp("bucket < {:expired}", dbx.Params{"expired": expired.String()}),
+				)).
+				Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// compactRouteStats rebuilds the buckets of granularity to that hold buckets
+// of granularity from not compacted yet and starting before before. A bucket
+// is rebuilt from all the buckets it holds, so buckets written after their
+// bucket was compacted are included the next time.
+func compactRouteStats(
+	app core.App,
+	collection *core.Collection,
+	from string,
+	to string,
+	before time.Time,
+) error {
+	beforeDate, err := types.ParseDateTime(before)
+	if err != nil {
+		return err
+	}
+
+	pending, err := app.FindAllRecords(
+		collection,
+		dbx.HashExp{"granularity": from, "compacted": false},
+		dbx.NewExp("bucket < {:before}", dbx.Params{"before": beforeDate.String()}),
+	)
+	if err != nil {
+		return err
+	}
+
+	type target struct {
+		routeID string
+		start   time.Time
+	}
+	targets := map[target]bool{}
+	for _, record := range pending {
+		stat := NewRouteStatFromRecord(record)
+		targets[target{routeID: stat.RouteID, start: routestats.Truncate(stat.Start, to)}] = true
+	}
+
+	for t := range targets {
+		startDate, err := types.ParseDateTime(t.start)
+		if err != nil {
+			return err
+		}
+		endDate, err := types.ParseDateTime(t.start.Add(routestats.Duration(to)))
+		if err != nil {
+			return err
+		}
+
+		parts, err := app.FindAllRecords(
+			collection,
+			dbx.HashExp{"route": t.routeID, "granularity": from},
+			dbx.NewExp("bucket >= {:start} AND bucket < {:end}", dbx.Params{
+				"start": startDate.String(),
+				"end":   endDate.String(),
+			}),
+		)
+		if err != nil {
+			return err
+		}
+
+		bucket := routestats.NewBucket()
+		projectID := ""
+		for _, part := range parts {
+			stat := NewRouteStatFromRecord(part)
+			bucket.Merge(stat.Bucket)
+			projectID = stat.ProjectID
+		}
+
+		record, err := findRouteStatRecord(app, collection, t.routeID, to, t.start)
+		if err != nil {
+			return err
+		}
+		record.Set("project", projectID)
+		record.Set("compacted", false)
+		setRouteStatBucket(record, bucket)
+		if err := app.Save(record); err != nil {
+			return err
+		}
+	}
+
+	for _, record := range pending {
+		record.Set("compacted", true)
+		if err := app.Save(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
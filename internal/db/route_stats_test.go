@@ -0,0 +1,182 @@
+//go:build !goexperiment.jsonv2
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uforg/ufogateway/internal/routestats"
+)
+
+func TestRouteStats(t *testing.T) {
+	db := newTestDB(t)
+	routeID := createTestRoute(t, db)
+	route, err := db.app.FindRecordById("routes", routeID)
+	require.NoError(t, err)
+	projectID := route.GetString("project")
+
+	// base is the start of an hour, the stats are added during it
+	base := time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC)
+
+	minuteStat := func(start time.Time, requests int, latency time.Duration) RouteStat {
+		bucket := routestats.NewBucket()
+		for range requests {
+			bucket.Add(200, latency, 10, 20)
+		}
+		return RouteStat{
+			RouteID:     routeID,
+			ProjectID:   projectID,
+			Granularity: routestats.GranularityMinute,
+			Start:       start,
+			Bucket:      bucket,
+		}
+	}
+	findStats := func(t *testing.T, granularity string) []RouteStat {
+		t.Helper()
+		stats, err := db.FindRouteStats(routeID, "", granularity, base.Add(-24*time.Hour), base.Add(7*24*time.Hour))
+		require.NoError(t, err)
+		return stats
+	}
+	compacted := func(t *testing.T, granularity string) map[time.Time]bool {
+		t.Helper()
+		records, err := db.app.FindAllRecords(routeStatsCollectionName, dbx.HashExp{
+			"route":       routeID,
+			"granularity": granularity,
+		})
+		require.NoError(t, err)
+		result := map[time.Time]bool{}
+		for _, record := range records {
+			result[record.GetDateTime("bucket").Time()] = record.GetBool("compacted")
+		}
+		return result
+	}
+
+	t.Run("merges stats into their stored buckets", func(t *testing.T) {
+		require.NoError(t, db.AddRouteStats([]RouteStat{
+			minuteStat(base.Add(time.Minute), 2, 10*time.Millisecond),
+			minuteStat(base.Add(2*time.Minute), 1, 30*time.Millisecond),
+		}))
+		require.NoError(t, db.AddRouteStats([]RouteStat{
+			minuteStat(base.Add(time.Minute), 1, 50*time.Millisecond),
+		}))
+
+		stats := findStats(t, routestats.GranularityMinute)
+		require.Len(t, stats, 2)
+
+		assert.Equal(t, base.Add(time.Minute), stats[0].Start)
+		assert.Equal(t, projectID, stats[0].ProjectID)
+		assert.Equal(t, int64(3), stats[0].Requests)
+		assert.Equal(t, int64(3), stats[0].Status2xx)
+		assert.Equal(t, int64(30), stats[0].BytesIn)
+		assert.Equal(t, int64(60), stats[0].BytesOut)
+		assert.Equal(t, 70*time.Millisecond, stats[0].LatencySum)
+		assert.Equal(t, 50*time.Millisecond, stats[0].LatencyMax)
+		assert.Equal(t, int64(3), stats[0].Latency.Count())
+
+		assert.Equal(t, base.Add(2*time.Minute), stats[1].Start)
+		assert.Equal(t, int64(1), stats[1].Requests)
+	})
+
+	t.Run("compacts the minutes of completed hours", func(t *testing.T) {
+		// the next hour is still in progress
+		require.NoError(t, db.AddRouteStats([]RouteStat{
+			minuteStat(base.Add(time.Hour+5*time.Minute), 4, 10*time.Millisecond),
+		}))
+
+		require.NoError(t, db.CompactRouteStats(base.Add(time.Hour+30*time.Minute)))
+
+		hours := findStats(t, routestats.GranularityHour)
+		require.Len(t, hours, 1)
+		assert.Equal(t, base, hours[0].Start)
+		assert.Equal(t, projectID, hours[0].ProjectID)
+		assert.Equal(t, int64(4), hours[0].Requests)
+		assert.Equal(t, 100*time.Millisecond, hours[0].LatencySum)
+		assert.Equal(t, 50*time.Millisecond, hours[0].LatencyMax)
+
+		assert.Equal(t, map[time.Time]bool{
+			base.Add(time.Minute):               true,
+			base.Add(2 * time.Minute):           true,
+			base.Add(time.Hour + 5*time.Minute): false,
+		}, compacted(t, routestats.GranularityMinute))
+		assert.Empty(t, findStats(t, routestats.GranularityDay), "the day is still in progress")
+	})
+
+	t.Run("rebuilds an hour after a late minute write", func(t *testing.T) {
+		require.NoError(t, db.AddRouteStats([]RouteStat{
+			minuteStat(base.Add(time.Minute), 1, 20*time.Millisecond),
+		}))
+		assert.False(t, compacted(t, routestats.GranularityMinute)[base.Add(time.Minute)])
+
+		require.NoError(t, db.CompactRouteStats(base.Add(time.Hour+40*time.Minute)))
+
+		hours := findStats(t, routestats.GranularityHour)
+		require.Len(t, hours, 1)
+		assert.Equal(t, int64(5), hours[0].Requests, "the hour holds every minute, not only the late one")
+		assert.Equal(t, 120*time.Millisecond, hours[0].LatencySum)
+		assert.True(t, compacted(t, routestats.GranularityMinute)[base.Add(time.Minute)])
+	})
+
+	t.Run("compacts completed days and deletes buckets past their retention", func(t *testing.T) {
+		require.NoError(t, db.CompactRouteStats(base.Add(3*24*time.Hour)))
+
+		days := findStats(t, routestats.GranularityDay)
+		require.Len(t, days, 1)
+		assert.Equal(t, routestats.Truncate(base, routestats.GranularityDay), days[0].Start)
+		assert.Equal(t, int64(9), days[0].Requests)
+
+		assert.Empty(t, compacted(t, routestats.GranularityMinute), "minutes are kept for 48 hours")
+		assert.Equal(t, map[time.Time]bool{
+			base:                true,
+			base.Add(time.Hour): true,
+		}, compacted(t, routestats.GranularityHour), "hours are kept for 90 days")
+	})
+}
+
+func TestCompactRouteStatsBefore(t *testing.T) {
+	db := newTestDB(t)
+	routeID := createTestRoute(t, db)
+	route, err := db.app.FindRecordById("routes", routeID)
+	require.NoError(t, err)
+	base := time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC)
+
+	stats := []RouteStat{}
+	for _, offset := range []time.Duration{0, time.Minute, time.Hour, 2 * time.Hour} {
+		bucket := routestats.NewBucket()
+		bucket.Add(200, time.Millisecond, 0, 0)
+		stats = append(stats, RouteStat{
+			RouteID:     routeID,
+			ProjectID:   route.GetString("project"),
+			Granularity: routestats.GranularityMinute,
+			Start:       base.Add(offset),
+			Bucket:      bucket,
+		})
+	}
+	require.NoError(t, db.AddRouteStats(stats))
+
+	collection, err := db.app.FindCachedCollectionByNameOrId(routeStatsCollectionName)
+	require.NoError(t, err)
+	require.NoError(t, compactRouteStats(
+		db.app,
+		collection,
+		routestats.GranularityMinute,
+		routestats.GranularityHour,
+		base.Add(time.Hour),
+	))
+
+	hours, err := db.FindRouteStats(routeID, "", routestats.GranularityHour, base, base.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, hours, 1, "only the buckets starting before the limit are compacted")
+	assert.Equal(t, base, hours[0].Start)
+	assert.Equal(t, int64(2), hours[0].Requests)
+
+	pending, err := db.app.FindAllRecords(routeStatsCollectionName, dbx.HashExp{
+		"granularity": routestats.GranularityMinute,
+		"compacted":   false,
+	})
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+}
@@ -3,31 +3,65 @@ package db
 import (
 	"time"
 
+	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
 )
 
 type Route struct {
-	ID                   string    `db:"id" json:"id"`
-	Project              string    `db:"project" json:"project"`
-	Name                 string    `db:"name" json:"name"`
-	Active               bool      `db:"active" json:"active"`
-	Endpoint             string    `db:"endpoint" json:"endpoint"`
-	OriginURL            string    `db:"origin_url" json:"origin_url"`
-	StoreHits            bool      `db:"store_hits" json:"store_hits"`
-	StoreReqHeaders      bool      `db:"store_req_headers" json:"store_req_headers"`
-	StoreReqBody         bool      `db:"store_req_body" json:"store_req_body"`
-	StoreReqBodyMaxBytes int       `db:"store_req_body_max_bytes" json:"store_req_body_max_bytes"`
-	StoreResHeaders      bool      `db:"store_res_headers" json:"store_res_headers"`
-	StoreResBody         bool      `db:"store_res_body" json:"store_res_body"`
-	StoreResBodyMaxBytes int       `db:"store_res_body_max_bytes" json:"store_res_body_max_bytes"`
-	RetentionDays        int       `db:"retention_days" json:"retention_days"`
-	RetentionHits        int       `db:"retention_hits" json:"retention_hits"`
-	TLSClientCert        string    `db:"tls_client_cert" json:"tls_client_cert"`
-	TLSClientKey         string    `db:"tls_client_key" json:"tls_client_key"`
-	TLSCaCert            string    `db:"tls_ca_cert" json:"tls_ca_cert"`
-	TLSSkipCertVerify    bool      `db:"tls_skip_cert_verify" json:"tls_skip_cert_verify"`
-	Created              time.Time `db:"created" json:"created"`
-	Updated              time.Time `db:"updated" json:"updated"`
+	ID                   string              `db:"id" json:"id"`
+	Project              string              `db:"project" json:"project"`
+	Name                 string              `db:"name" json:"name"`
+	Type                 string              `db:"type" json:"type"`
+	Active               bool                `db:"active" json:"active"`
+	Endpoint             string              `db:"endpoint" json:"endpoint"`
+	OriginURL            string              `db:"origin_url" json:"origin_url"`
+	StoreHits            bool                `db:"store_hits" json:"store_hits"`
+	StoreReqHeaders      bool                `db:"store_req_headers" json:"store_req_headers"`
+	StoreReqBody         bool                `db:"store_req_body" json:"store_req_body"`
+	StoreReqBodyMaxBytes int                 `db:"store_req_body_max_bytes" json:"store_req_body_max_bytes"`
+	StoreResHeaders      bool                `db:"store_res_headers" json:"store_res_headers"`
+	StoreResBody         bool                `db:"store_res_body" json:"store_res_body"`
+	StoreResBodyMaxBytes int                 `db:"store_res_body_max_bytes" json:"store_res_body_max_bytes"`
+	RetentionDays        int                 `db:"retention_days" json:"retention_days"`
+	RetentionHits        int                 `db:"retention_hits" json:"retention_hits"`
+	TLSClientCert        string              `db:"tls_client_cert" json:"tls_client_cert"`
+	TLSClientKey         string              `db:"tls_client_key" json:"tls_client_key"`
+	TLSCaCert            string              `db:"tls_ca_cert" json:"tls_ca_cert"`
+	TLSSkipCertVerify    bool                `db:"tls_skip_cert_verify" json:"tls_skip_cert_verify"`
+	IPAllowList          string              `db:"ip_allow_list" json:"ip_allow_list"`
+	IPDenyList           string              `db:"ip_deny_list" json:"ip_deny_list"`
+	IPBlockStatus        int                 `db:"ip_block_status" json:"ip_block_status"`
+	IPBlockMessage       string              `db:"ip_block_message" json:"ip_block_message"`
+	CacheEnabled         bool                `db:"cache_enabled" json:"cache_enabled"`
+	CacheVaryHeaders     string              `db:"cache_vary_headers" json:"cache_vary_headers"`
+	CacheTTLSeconds      int                 `db:"cache_ttl_seconds" json:"cache_ttl_seconds"`
+	CoalesceRequests     bool                `db:"coalesce_requests" json:"coalesce_requests"`
+	MockStatus           int                 `db:"mock_status" json:"mock_status"`
+	MockHeaders          map[string][]string `db:"mock_headers" json:"mock_headers"`
+	MockBody             string              `db:"mock_body" json:"mock_body"`
+	MockLatencyMs        int                 `db:"mock_latency_ms" json:"mock_latency_ms"`
+	MockPathPattern      string              `db:"mock_path_pattern" json:"mock_path_pattern"`
+	MirrorURL            string              `db:"mirror_url" json:"mirror_url"`
+	MirrorPercent        float64             `db:"mirror_percent" json:"mirror_percent"`
+	OpenAPISpec          string              `db:"openapi_spec" json:"openapi_spec"`
+	ContractMode         string              `db:"contract_mode" json:"contract_mode"`
+	ContractValidateRes  bool                `db:"contract_validate_responses" json:"contract_validate_responses"`
+	ReqBodyMaxBytes      int                 `db:"req_body_max_bytes" json:"req_body_max_bytes"`
+	ReqHeadersMaxBytes   int                 `db:"req_headers_max_bytes" json:"req_headers_max_bytes"`
+	ReqHeadersMaxCount   int                 `db:"req_headers_max_count" json:"req_headers_max_count"`
+	CompressResponses    bool                `db:"compress_responses" json:"compress_responses"`
+	CompressMinBytes     int                 `db:"compress_min_bytes" json:"compress_min_bytes"`
+	CompressContentTypes string              `db:"compress_content_types" json:"compress_content_types"`
+	CompressEncodings    string              `db:"compress_encodings" json:"compress_encodings"`
+	ServerTiming         bool                `db:"server_timing" json:"server_timing"`
+	SampleMode           string              `db:"sample_mode" json:"sample_mode"`
+	SamplePercent        float64             `db:"sample_percent" json:"sample_percent"`
+	SampleOneIn          int                 `db:"sample_one_in" json:"sample_one_in"`
+	CaptureMinStatus     int                 `db:"capture_min_status" json:"capture_min_status"`
+	CaptureMinDurationMs int                 `db:"capture_min_duration_ms" json:"capture_min_duration_ms"`
+	CaptureHeader        string              `db:"capture_header" json:"capture_header"`
+	Created              time.Time           `db:"created" json:"created"`
+	Updated              time.Time           `db:"updated" json:"updated"`
 }
 
 func NewRouteFromRecord(r *core.Record) Route {
@@ -35,6 +69,7 @@ func NewRouteFromRecord(r *core.Record) Route {
 		ID:                   r.Id,
 		Project:              r.GetString("project"),
 		Name:                 r.GetString("name"),
+		Type:                 r.GetString("type"),
 		Active:               r.GetBool("active"),
 		Endpoint:             r.GetString("endpoint"),
 		OriginURL:            r.GetString("origin_url"),
@@ -51,6 +86,38 @@ func NewRouteFromRecord(r *core.Record) Route {
 		TLSClientKey:         r.GetString("tls_client_key"),
 		TLSCaCert:            r.GetString("tls_ca_cert"),
 		TLSSkipCertVerify:    r.GetBool("tls_skip_cert_verify"),
+		IPAllowList:          r.GetString("ip_allow_list"),
+		IPDenyList:           r.GetString("ip_deny_list"),
+		IPBlockStatus:        r.GetInt("ip_block_status"),
+		IPBlockMessage:       r.GetString("ip_block_message"),
+		CacheEnabled:         r.GetBool("cache_enabled"),
+		CacheVaryHeaders:     r.GetString("cache_vary_headers"),
+		CacheTTLSeconds:      r.GetInt("cache_ttl_seconds"),
+		CoalesceRequests:     r.GetBool("coalesce_requests"),
+		MockStatus:           r.GetInt("mock_status"),
+		MockHeaders:          getHeaderMap(r, "mock_headers"),
+		MockBody:             r.GetString("mock_body"),
+		MockLatencyMs:        r.GetInt("mock_latency_ms"),
+		MockPathPattern:      r.GetString("mock_path_pattern"),
+		MirrorURL:            r.GetString("mirror_url"),
+		MirrorPercent:        r.GetFloat("mirror_percent"),
+		OpenAPISpec:          r.GetString("openapi_spec"),
+		ContractMode:         r.GetString("contract_mode"),
+		ContractValidateRes:  r.GetBool("contract_validate_responses"),
+		ReqBodyMaxBytes:      r.GetInt("req_body_max_bytes"),
+		ReqHeadersMaxBytes:   r.GetInt("req_headers_max_bytes"),
+		ReqHeadersMaxCount:   r.GetInt("req_headers_max_count"),
+		CompressResponses:    r.GetBool("compress_responses"),
+		CompressMinBytes:     r.GetInt("compress_min_bytes"),
+		CompressContentTypes: r.GetString("compress_content_types"),
+		CompressEncodings:    r.GetString("compress_encodings"),
+		ServerTiming:         r.GetBool("server_timing"),
+		SampleMode:           r.GetString("sample_mode"),
+		SamplePercent:        r.GetFloat("sample_percent"),
+		SampleOneIn:          r.GetInt("sample_one_in"),
+		CaptureMinStatus:     r.GetInt("capture_min_status"),
+		CaptureMinDurationMs: r.GetInt("capture_min_duration_ms"),
+		CaptureHeader:        r.GetString("capture_header"),
 		Created:              r.GetDateTime("created").Time(),
 		Updated:              r.GetDateTime("updated").Time(),
 	}
@@ -118,3 +185,65 @@ func (db *DB) GetRouteByIDCached(routeID string) (Route, error) {
 	db.cacheInstance.Set(key, dbRoute, 5*time.Second)
 	return dbRoute, nil
 }
+
+// GetRoutesByEndpoint returns every route with the given endpoint, active or
+// not, across all the projects.
+func (db *DB) GetRoutesByEndpoint(endpoint string) ([]Route, error) {
+	records, err := db.app.FindRecordsByFilter(
+		"routes",
+		"endpoint = {:endpoint}",
+		"created",
+		0,
+		0,
+		dbx.Params{"endpoint": endpoint},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := []Route{}
+	for _, record := range records {
+		routes = append(routes, NewRouteFromRecord(record))
+	}
+
+	return routes, nil
+}
+
+// CreateProxyRoute creates an active proxy route and returns its ID.
+func (db *DB) CreateProxyRoute(
+	projectID string,
+	name string,
+	endpoint string,
+	originURL string,
+) (string, error) {
+	collection, err := db.app.FindCollectionByNameOrId("routes")
+	if err != nil {
+		return "", err
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("project", projectID)
+	record.Set("name", name)
+	record.Set("type", "proxy")
+	record.Set("active", true)
+	record.Set("endpoint", endpoint)
+	record.Set("origin_url", originURL)
+
+	if err := db.app.Save(record); err != nil {
+		return "", err
+	}
+
+	return record.Id, nil
+}
+
+func (db *DB) UpdateRouteNameAndOriginURL(routeID string, name string, originURL string) error {
+	record, err := db.app.FindRecordById("routes", routeID)
+	if err != nil {
+		return err
+	}
+
+	record.Set("name", name)
+	record.Set("origin_url", originURL)
+
+	return db.app.Save(record)
+}
@@ -0,0 +1,53 @@
+//go:build !goexperiment.jsonv2
+
+// The collections of PocketBase v0.23 can't be decoded with the JSON v2
+// based encoding/json, so the tests backed by an app need it disabled.
+
+package db
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/require"
+	"github.com/uforg/ufogateway/internal/cache"
+	_ "github.com/uforg/ufogateway/internal/migrations"
+)
+
+// newTestDB returns a DB backed by a new app with every migration applied.
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	app := pocketbase.NewWithConfig(pocketbase.Config{DefaultDataDir: t.TempDir()})
+	require.NoError(t, app.Bootstrap())
+	require.NoError(t, app.RunAllMigrations())
+	t.Cleanup(func() { _ = app.ResetBootstrapState() })
+
+	return NewDB(app, cache.NewCacheInstance())
+}
+
+// createTestRoute creates a project with a proxy route and returns the id of
+// the route.
+func createTestRoute(t *testing.T, db *DB) string {
+	t.Helper()
+
+	users, err := db.app.FindCollectionByNameOrId("users")
+	require.NoError(t, err)
+	user := core.NewRecord(users)
+	user.Set("email", "owner@example.com")
+	user.Set("name", "owner")
+	user.SetPassword("password123")
+	require.NoError(t, db.app.Save(user))
+
+	projects, err := db.app.FindCollectionByNameOrId("projects")
+	require.NoError(t, err)
+	project := core.NewRecord(projects)
+	project.Set("name", "project")
+	project.Set("owner", user.Id)
+	require.NoError(t, db.app.Save(project))
+
+	routeID, err := db.CreateProxyRoute(project.Id, "route", "/api", "http://origin.test")
+	require.NoError(t, err)
+	return routeID
+}
@@ -0,0 +1,133 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// BodyDiff is a difference in the response bodies. Path is the dot separated
+// JSON path of the difference, empty when the bodies are compared as text or
+// the whole documents differ. A nil side means that the value is missing.
+type BodyDiff struct {
+	Path string `json:"path"`
+	A    any    `json:"a"`
+	B    any    `json:"b"`
+}
+
+// compareBodies compares the bodies as JSON when both of them are valid
+// JSON and as text otherwise. It also reports whether JSON was used.
+func compareBodies(a, b string, ignorePaths []string) ([]BodyDiff, bool) {
+	var ja, jb any
+	if json.Unmarshal([]byte(a), &ja) != nil || json.Unmarshal([]byte(b), &jb) != nil {
+		if a == b {
+			return []BodyDiff{}, false
+		}
+		return []BodyDiff{{A: a, B: b}}, false
+	}
+
+	patterns := make([][]string, 0, len(ignorePaths))
+	for _, p := range ignorePaths {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, strings.Split(p, "."))
+		}
+	}
+
+	diffs := []BodyDiff{}
+	compareJSON(nil, ja, jb, patterns, &diffs)
+	return diffs, true
+}
+
+// compareJSON walks two decoded JSON values and appends every difference
+// found below path to diffs.
+func compareJSON(path []string, a, b any, ignore [][]string, diffs *[]BodyDiff) {
+	if isIgnoredPath(path, ignore) {
+		return
+	}
+
+	switch va := a.(type) {
+	case map[string]any:
+		vb, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+
+		keys := []string{}
+		for k := range va {
+			keys = append(keys, k)
+		}
+		for k := range vb {
+			if _, ok := va[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			childPath := append(slices.Clone(path), k)
+			ca, okA := va[k]
+			cb, okB := vb[k]
+			if okA && okB {
+				compareJSON(childPath, ca, cb, ignore, diffs)
+				continue
+			}
+			if !isIgnoredPath(childPath, ignore) {
+				*diffs = append(*diffs, BodyDiff{Path: strings.Join(childPath, "."), A: ca, B: cb})
+			}
+		}
+		return
+
+	case []any:
+		vb, ok := b.([]any)
+		if !ok {
+			break
+		}
+
+		for i := 0; i < max(len(va), len(vb)); i++ {
+			childPath := append(slices.Clone(path), strconv.Itoa(i))
+			var ca, cb any
+			if i < len(va) {
+				ca = va[i]
+			}
+			if i < len(vb) {
+				cb = vb[i]
+			}
+			if i < len(va) && i < len(vb) {
+				compareJSON(childPath, ca, cb, ignore, diffs)
+				continue
+			}
+			if !isIgnoredPath(childPath, ignore) {
+				*diffs = append(*diffs, BodyDiff{Path: strings.Join(childPath, "."), A: ca, B: cb})
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, BodyDiff{Path: strings.Join(path, "."), A: a, B: b})
+	}
+}
+
+// isIgnoredPath reports whether path matches one of the ignore patterns,
+// where a "*" segment matches any single segment.
+func isIgnoredPath(path []string, ignore [][]string) bool {
+	for _, pattern := range ignore {
+		if len(pattern) != len(path) {
+			continue
+		}
+
+		matches := true
+		for i := range pattern {
+			if pattern[i] != "*" && pattern[i] != path[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
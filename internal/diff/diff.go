@@ -0,0 +1,126 @@
+package diff
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DefaultIgnoredHeaders are the response headers that are never compared.
+//
+// Age, Content-Length and Date change between otherwise identical responses,
+// the rest are added by the server middlewares to the responses of live
+// requests but not to replays.
+var DefaultIgnoredHeaders = []string{
+	"Age",
+	"Content-Length",
+	"Date",
+	"Vary",
+	"X-Content-Type-Options",
+	"X-Frame-Options",
+	"X-Xss-Protection",
+}
+
+// Response is the stored response of a request to compare.
+type Response struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers"`
+	Body    string              `json:"body"`
+}
+
+// Options configures what is left out of a comparison.
+type Options struct {
+	// IgnoreHeaders are header names, case insensitive, that are not
+	// compared in addition to DefaultIgnoredHeaders.
+	IgnoreHeaders []string `json:"ignore_headers"`
+	// IgnorePaths are dot separated JSON body paths that are not compared,
+	// a "*" segment matches any object key or array index, for example
+	// "meta.timestamp" or "items.*.id".
+	IgnorePaths []string `json:"ignore_paths"`
+}
+
+// Result is the structured difference between two responses.
+type Result struct {
+	Match    bool         `json:"match"`
+	Status   *StatusDiff  `json:"status,omitempty"`
+	Headers  []HeaderDiff `json:"headers,omitempty"`
+	Body     []BodyDiff   `json:"body,omitempty"`
+	BodyJSON bool         `json:"body_json"` // Whether both bodies were compared as JSON
+}
+
+// StatusDiff is a difference in the response status codes.
+type StatusDiff struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+// HeaderDiff is a difference in the values of a response header, a nil side
+// means that the header is missing in that response.
+type HeaderDiff struct {
+	Name string   `json:"name"`
+	A    []string `json:"a"`
+	B    []string `json:"b"`
+}
+
+// Compare returns the differences between the responses a and b.
+//
+// When both bodies are valid JSON they are compared semantically, so key
+// order and formatting do not matter, otherwise they are compared as text.
+func Compare(a Response, b Response, opts Options) Result {
+	result := Result{}
+
+	if a.Status != b.Status {
+		result.Status = &StatusDiff{A: a.Status, B: b.Status}
+	}
+
+	result.Headers = compareHeaders(a.Headers, b.Headers, opts.IgnoreHeaders)
+	result.Body, result.BodyJSON = compareBodies(a.Body, b.Body, opts.IgnorePaths)
+
+	result.Match = result.Status == nil &&
+		len(result.Headers) == 0 &&
+		len(result.Body) == 0
+
+	return result
+}
+
+// compareHeaders returns the differences between two header maps sorted by
+// header name, skipping the ignored headers.
+func compareHeaders(a, b map[string][]string, ignore []string) []HeaderDiff {
+	canonical := func(h map[string][]string) map[string][]string {
+		out := make(map[string][]string, len(h))
+		for name, values := range h {
+			key := http.CanonicalHeaderKey(name)
+			out[key] = append(out[key], values...)
+		}
+		return out
+	}
+	ca, cb := canonical(a), canonical(b)
+
+	ignored := map[string]bool{}
+	for _, name := range append(slices.Clone(DefaultIgnoredHeaders), ignore...) {
+		ignored[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+	}
+
+	names := []string{}
+	for name := range ca {
+		names = append(names, name)
+	}
+	for name := range cb {
+		if _, ok := ca[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	diffs := []HeaderDiff{}
+	for _, name := range names {
+		if ignored[name] {
+			continue
+		}
+		if !slices.Equal(ca[name], cb[name]) {
+			diffs = append(diffs, HeaderDiff{Name: name, A: ca[name], B: cb[name]})
+		}
+	}
+
+	return diffs
+}
@@ -0,0 +1,125 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Response
+		b    Response
+		opts Options
+		want Result
+	}{
+		{
+			name: "identical responses",
+			a:    Response{Status: 200, Headers: map[string][]string{"Content-Type": {"text/plain"}}, Body: "ok"},
+			b:    Response{Status: 200, Headers: map[string][]string{"content-type": {"text/plain"}}, Body: "ok"},
+			want: Result{Match: true, Headers: []HeaderDiff{}, Body: []BodyDiff{}},
+		},
+		{
+			name: "different status",
+			a:    Response{Status: 200},
+			b:    Response{Status: 500},
+			want: Result{
+				Status:  &StatusDiff{A: 200, B: 500},
+				Headers: []HeaderDiff{},
+				Body:    []BodyDiff{},
+			},
+		},
+		{
+			name: "different, missing and ignored headers",
+			a: Response{Status: 200, Headers: map[string][]string{
+				"Date":         {"Mon, 01 Jan 2024 00:00:00 GMT"},
+				"X-Version":    {"1"},
+				"X-Request-Id": {"a"},
+				"X-Old":        {"yes"},
+			}},
+			b: Response{Status: 200, Headers: map[string][]string{
+				"Date":         {"Tue, 02 Jan 2024 00:00:00 GMT"},
+				"X-Version":    {"2"},
+				"X-Request-Id": {"b"},
+			}},
+			opts: Options{IgnoreHeaders: []string{"x-request-id"}},
+			want: Result{
+				Headers: []HeaderDiff{
+					{Name: "X-Old", A: []string{"yes"}, B: nil},
+					{Name: "X-Version", A: []string{"1"}, B: []string{"2"}},
+				},
+				Body: []BodyDiff{},
+			},
+		},
+		{
+			name: "text bodies",
+			a:    Response{Status: 200, Body: "hello"},
+			b:    Response{Status: 200, Body: "bye"},
+			want: Result{
+				Headers: []HeaderDiff{},
+				Body:    []BodyDiff{{A: "hello", B: "bye"}},
+			},
+		},
+		{
+			name: "json bodies with different formatting and key order",
+			a:    Response{Status: 200, Body: `{"a":1,"b":[1,2]}`},
+			b:    Response{Status: 200, Body: "{\n  \"b\": [1, 2],\n  \"a\": 1\n}"},
+			want: Result{Match: true, Headers: []HeaderDiff{}, Body: []BodyDiff{}, BodyJSON: true},
+		},
+		{
+			name: "json bodies with differences",
+			a:    Response{Status: 200, Body: `{"user":{"name":"ann","age":30},"tags":["a","b"],"old":true}`},
+			b:    Response{Status: 200, Body: `{"user":{"name":"bob","age":30},"tags":["a"],"new":1}`},
+			want: Result{
+				Headers: []HeaderDiff{},
+				Body: []BodyDiff{
+					{Path: "new", A: nil, B: float64(1)},
+					{Path: "old", A: true, B: nil},
+					{Path: "tags.1", A: "b", B: nil},
+					{Path: "user.name", A: "ann", B: "bob"},
+				},
+				BodyJSON: true,
+			},
+		},
+		{
+			name: "json bodies with different types",
+			a:    Response{Status: 200, Body: `{"id":"1"}`},
+			b:    Response{Status: 200, Body: `{"id":1}`},
+			want: Result{
+				Headers:  []HeaderDiff{},
+				Body:     []BodyDiff{{Path: "id", A: "1", B: float64(1)}},
+				BodyJSON: true,
+			},
+		},
+		{
+			name: "json bodies with ignored paths",
+			a:    Response{Status: 200, Body: `{"meta":{"timestamp":1},"items":[{"id":"x","v":1},{"id":"y","v":2}]}`},
+			b:    Response{Status: 200, Body: `{"meta":{"timestamp":2},"items":[{"id":"z","v":1},{"id":"w","v":2}]}`},
+			opts: Options{IgnorePaths: []string{"meta.timestamp", "items.*.id"}},
+			want: Result{Match: true, Headers: []HeaderDiff{}, Body: []BodyDiff{}, BodyJSON: true},
+		},
+		{
+			name: "ignored path missing on one side",
+			a:    Response{Status: 200, Body: `{"id":"x","v":1}`},
+			b:    Response{Status: 200, Body: `{"v":1}`},
+			opts: Options{IgnorePaths: []string{"id"}},
+			want: Result{Match: true, Headers: []HeaderDiff{}, Body: []BodyDiff{}, BodyJSON: true},
+		},
+		{
+			name: "json and text bodies",
+			a:    Response{Status: 200, Body: `{"a":1}`},
+			b:    Response{Status: 200, Body: `oops`},
+			want: Result{
+				Headers: []HeaderDiff{},
+				Body:    []BodyDiff{{A: `{"a":1}`, B: "oops"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Compare(tt.a, tt.b, tt.opts))
+		})
+	}
+}
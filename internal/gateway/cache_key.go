@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// responseCacheKey returns the key used to store the response to the given
+// request in the response cache.
+//
+// HEAD requests share the key of GET requests so they can be answered from
+// cached GET responses. The values of the configured vary headers are part
+// of the key so different representations are stored separately.
+func responseCacheKey(r *http.Request, routeID string, varyHeaders []string) string {
+	method := r.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	var key strings.Builder
+	key.WriteString(routeID)
+	key.WriteString("\n")
+	key.WriteString(method)
+	key.WriteString("\n")
+	key.WriteString(r.Host)
+	key.WriteString(r.URL.RequestURI())
+
+	names := make([]string, 0, len(varyHeaders))
+	for _, name := range varyHeaders {
+		names = append(names, http.CanonicalHeaderKey(name))
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	for _, name := range names {
+		key.WriteString("\n")
+		key.WriteString(name)
+		key.WriteString(": ")
+		key.WriteString(strings.Join(r.Header.Values(name), ", "))
+	}
+
+	return key.String()
+}
+
+// varyCoveredBy returns true if every header listed in the Vary header of a
+// response is part of the configured vary headers, so the cache key already
+// tells apart all the representations of the resource.
+func varyCoveredBy(header http.Header, varyHeaders []string) bool {
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			covered := slices.ContainsFunc(varyHeaders, func(h string) bool {
+				return strings.EqualFold(h, name)
+			})
+			if !covered {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	newRequest := func(method, target string, headers map[string]string) *http.Request {
+		r := httptest.NewRequest(method, target, nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		return r
+	}
+
+	base := responseCacheKey(newRequest(http.MethodGet, "http://gw/api/users?page=1", nil), "r1", nil)
+
+	t.Run("HEAD shares the GET key", func(t *testing.T) {
+		key := responseCacheKey(newRequest(http.MethodHead, "http://gw/api/users?page=1", nil), "r1", nil)
+		assert.Equal(t, base, key)
+	})
+
+	t.Run("query is part of the key", func(t *testing.T) {
+		key := responseCacheKey(newRequest(http.MethodGet, "http://gw/api/users?page=2", nil), "r1", nil)
+		assert.NotEqual(t, base, key)
+	})
+
+	t.Run("route is part of the key", func(t *testing.T) {
+		key := responseCacheKey(newRequest(http.MethodGet, "http://gw/api/users?page=1", nil), "r2", nil)
+		assert.NotEqual(t, base, key)
+	})
+
+	t.Run("unconfigured headers are ignored", func(t *testing.T) {
+		r := newRequest(http.MethodGet, "http://gw/api/users?page=1", map[string]string{"Accept-Language": "es"})
+		assert.Equal(t, base, responseCacheKey(r, "r1", nil))
+	})
+
+	t.Run("vary headers are part of the key", func(t *testing.T) {
+		es := newRequest(http.MethodGet, "http://gw/api/users?page=1", map[string]string{"Accept-Language": "es"})
+		en := newRequest(http.MethodGet, "http://gw/api/users?page=1", map[string]string{"Accept-Language": "en"})
+		assert.NotEqual(t,
+			responseCacheKey(es, "r1", []string{"accept-language"}),
+			responseCacheKey(en, "r1", []string{"accept-language"}),
+		)
+	})
+
+	t.Run("vary header order and case do not matter", func(t *testing.T) {
+		r := newRequest(http.MethodGet, "http://gw/api/users", map[string]string{"Accept": "a", "Accept-Language": "b"})
+		assert.Equal(t,
+			responseCacheKey(r, "r1", []string{"Accept", "accept-language"}),
+			responseCacheKey(r, "r1", []string{"Accept-Language", "accept", "Accept"}),
+		)
+	})
+}
+
+func TestVaryCoveredBy(t *testing.T) {
+	tests := []struct {
+		name        string
+		vary        []string
+		varyHeaders []string
+		want        bool
+	}{
+		{name: "no vary", vary: nil, varyHeaders: nil, want: true},
+		{name: "covered", vary: []string{"Accept-Encoding"}, varyHeaders: []string{"accept-encoding"}, want: true},
+		{name: "multiple covered", vary: []string{"Accept, Accept-Language"}, varyHeaders: []string{"Accept-Language", "Accept"}, want: true},
+		{name: "not covered", vary: []string{"Accept-Encoding, Origin"}, varyHeaders: []string{"Accept-Encoding"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for _, v := range tt.vary {
+				header.Add("Vary", v)
+			}
+			assert.Equal(t, tt.want, varyCoveredBy(header, tt.varyHeaders))
+		})
+	}
+}
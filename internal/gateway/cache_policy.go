@@ -0,0 +1,173 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheableStatusCodes are the status codes whose responses can be stored by
+// the gateway response cache.
+var cacheableStatusCodes = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusGone:                 true,
+}
+
+// cacheControl holds the directives of a Cache-Control header, the keys are
+// the lowercased directive names and the values their arguments, if any.
+type cacheControl map[string]string
+
+// parseCacheControl parses all the values of a Cache-Control header.
+func parseCacheControl(values []string) cacheControl {
+	cc := cacheControl{}
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+
+			name, arg, _ := strings.Cut(directive, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			cc[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return cc
+}
+
+// has returns true if the directive is present.
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// seconds returns the duration of a delta-seconds directive such as max-age.
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	arg, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+
+	secs, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+
+	return time.Duration(secs) * time.Second, true
+}
+
+// isCacheableRequest returns true if the response to the request can be
+// served from or stored in the gateway response cache.
+func isCacheableRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if r.Header.Get("Authorization") != "" {
+		return false
+	}
+	return !parseCacheControl(r.Header.Values("Cache-Control")).has("no-store")
+}
+
+// requestForcesRevalidation returns true if the client asked not to be
+// served a cached response without validating it with the origin first.
+func requestForcesRevalidation(r *http.Request) bool {
+	cc := parseCacheControl(r.Header.Values("Cache-Control"))
+	if cc.has("no-cache") {
+		return true
+	}
+	if maxAge, ok := cc.seconds("max-age"); ok && maxAge == 0 {
+		return true
+	}
+	return strings.EqualFold(r.Header.Get("Pragma"), "no-cache")
+}
+
+// responseFreshness computes for how long a response can be served from the
+// cache without revalidation.
+//
+// The freshness lifetime comes from s-maxage, max-age or Expires, in that
+// order, and falls back to defaultTTL when the origin doesn't provide any.
+// It returns false if the response must not be stored at all.
+func responseFreshness(statusCode int, header http.Header, now time.Time, defaultTTL time.Duration) (time.Duration, bool) {
+	if !cacheableStatusCodes[statusCode] {
+		return 0, false
+	}
+	if header.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+	for _, vary := range header.Values("Vary") {
+		if strings.TrimSpace(vary) == "*" {
+			return 0, false
+		}
+	}
+
+	cc := parseCacheControl(header.Values("Cache-Control"))
+	if cc.has("no-store") || cc.has("private") {
+		return 0, false
+	}
+
+	hasValidator := header.Get("ETag") != ""
+	if cc.has("no-cache") {
+		return 0, hasValidator
+	}
+
+	freshness, explicit := cc.seconds("s-maxage")
+	if !explicit {
+		freshness, explicit = cc.seconds("max-age")
+	}
+	if !explicit && header.Get("Expires") != "" {
+		explicit = true
+		if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+			date := now
+			if d, err := http.ParseTime(header.Get("Date")); err == nil {
+				date = d
+			}
+			freshness = max(expires.Sub(date), 0)
+		}
+	}
+	if !explicit {
+		freshness = defaultTTL
+	}
+
+	if freshness <= 0 && !hasValidator {
+		return 0, false
+	}
+	return freshness, true
+}
+
+// responseInitialAge returns the age reported by the origin in the Age header.
+func responseInitialAge(header http.Header) time.Duration {
+	secs, err := strconv.ParseInt(header.Get("Age"), 10, 64)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// etagMatches checks an If-None-Match header value against an entity tag
+// using the weak comparison function.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	normalizedETag := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == normalizedETag {
+			return true
+		}
+	}
+
+	return false
+}
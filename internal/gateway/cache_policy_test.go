@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	cc := parseCacheControl([]string{`public, max-age=60`, `s-maxage="120", No-Cache`})
+
+	assert.True(t, cc.has("public"))
+	assert.True(t, cc.has("no-cache"))
+	assert.False(t, cc.has("private"))
+
+	maxAge, ok := cc.seconds("max-age")
+	assert.True(t, ok)
+	assert.Equal(t, 60*time.Second, maxAge)
+
+	sMaxAge, ok := cc.seconds("s-maxage")
+	assert.True(t, ok)
+	assert.Equal(t, 120*time.Second, sMaxAge)
+
+	_, ok = cc.seconds("public")
+	assert.False(t, ok)
+}
+
+func TestIsCacheableRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "GET", method: http.MethodGet, want: true},
+		{name: "HEAD", method: http.MethodHead, want: true},
+		{name: "POST", method: http.MethodPost, want: false},
+		{
+			name:    "authorized request",
+			method:  http.MethodGet,
+			headers: map[string]string{"Authorization": "Bearer token"},
+			want:    false,
+		},
+		{
+			name:    "no-store request",
+			method:  http.MethodGet,
+			headers: map[string]string{"Cache-Control": "no-store"},
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, isCacheableRequest(r))
+		})
+	}
+}
+
+func TestRequestForcesRevalidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "no headers", want: false},
+		{name: "no-cache", headers: map[string]string{"Cache-Control": "no-cache"}, want: true},
+		{name: "max-age=0", headers: map[string]string{"Cache-Control": "max-age=0"}, want: true},
+		{name: "max-age=10", headers: map[string]string{"Cache-Control": "max-age=10"}, want: false},
+		{name: "pragma", headers: map[string]string{"Pragma": "no-cache"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, requestForcesRevalidation(r))
+		})
+	}
+}
+
+func TestResponseFreshness(t *testing.T) {
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		status        int
+		headers       map[string]string
+		defaultTTL    time.Duration
+		wantFreshness time.Duration
+		wantOK        bool
+	}{
+		{
+			name:          "max-age",
+			status:        http.StatusOK,
+			headers:       map[string]string{"Cache-Control": "max-age=60"},
+			wantFreshness: 60 * time.Second,
+			wantOK:        true,
+		},
+		{
+			name:          "s-maxage wins over max-age",
+			status:        http.StatusOK,
+			headers:       map[string]string{"Cache-Control": "max-age=60, s-maxage=300"},
+			wantFreshness: 300 * time.Second,
+			wantOK:        true,
+		},
+		{
+			name:   "expires relative to date",
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Date":    now.Format(http.TimeFormat),
+				"Expires": now.Add(90 * time.Second).Format(http.TimeFormat),
+			},
+			wantFreshness: 90 * time.Second,
+			wantOK:        true,
+		},
+		{
+			name:          "default ttl without caching headers",
+			status:        http.StatusOK,
+			defaultTTL:    30 * time.Second,
+			wantFreshness: 30 * time.Second,
+			wantOK:        true,
+		},
+		{
+			name:   "no caching headers and no default ttl",
+			status: http.StatusOK,
+			wantOK: false,
+		},
+		{
+			name:          "no-cache with etag is stored for revalidation",
+			status:        http.StatusOK,
+			headers:       map[string]string{"Cache-Control": "no-cache", "ETag": `"v1"`},
+			wantFreshness: 0,
+			wantOK:        true,
+		},
+		{
+			name:    "no-cache without etag",
+			status:  http.StatusOK,
+			headers: map[string]string{"Cache-Control": "no-cache"},
+			wantOK:  false,
+		},
+		{
+			name:    "no-store",
+			status:  http.StatusOK,
+			headers: map[string]string{"Cache-Control": "no-store, max-age=60"},
+			wantOK:  false,
+		},
+		{
+			name:    "private",
+			status:  http.StatusOK,
+			headers: map[string]string{"Cache-Control": "private, max-age=60"},
+			wantOK:  false,
+		},
+		{
+			name:    "set-cookie",
+			status:  http.StatusOK,
+			headers: map[string]string{"Cache-Control": "max-age=60", "Set-Cookie": "a=b"},
+			wantOK:  false,
+		},
+		{
+			name:    "vary star",
+			status:  http.StatusOK,
+			headers: map[string]string{"Cache-Control": "max-age=60", "Vary": "*"},
+			wantOK:  false,
+		},
+		{
+			name:    "uncacheable status",
+			status:  http.StatusInternalServerError,
+			headers: map[string]string{"Cache-Control": "max-age=60"},
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+
+			freshness, ok := responseFreshness(tt.status, header, now, tt.defaultTTL)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.wantFreshness, freshness)
+			}
+		})
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		etag        string
+		want        bool
+	}{
+		{name: "exact match", ifNoneMatch: `"v1"`, etag: `"v1"`, want: true},
+		{name: "weak match", ifNoneMatch: `W/"v1"`, etag: `"v1"`, want: true},
+		{name: "list match", ifNoneMatch: `"v0", "v1"`, etag: `"v1"`, want: true},
+		{name: "wildcard", ifNoneMatch: `*`, etag: `"v1"`, want: true},
+		{name: "no match", ifNoneMatch: `"v2"`, etag: `"v1"`, want: false},
+		{name: "empty header", ifNoneMatch: "", etag: `"v1"`, want: false},
+		{name: "empty etag", ifNoneMatch: `"v1"`, etag: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, etagMatches(tt.ifNoneMatch, tt.etag))
+		})
+	}
+}
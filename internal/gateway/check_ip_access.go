@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ipAccessList represents a named list of CIDR ranges used to filter clients.
+type ipAccessList struct {
+	name    string         // is the human readable name of the list used in block reasons
+	entries []netip.Prefix // is the list of CIDR ranges, single IP addresses are single address ranges
+	allow   bool           // is true when the list is an allowlist, false for a denylist
+}
+
+// checkIPAccess evaluates the IP filters of the route and its project against
+// the given client IP.
+//
+// Denylists are evaluated first, then allowlists. A nil allowlist allows
+// every IP address while an empty one, left by a list with only invalid
+// entries, blocks every IP address. It returns an empty string when the IP
+// is allowed or the reason why it was blocked otherwise.
+func checkIPAccess(ip string, route Route) (string, error) {
+	lists := []ipAccessList{
+		{name: "project denylist", entries: route.ProjectIPDenyList},
+		{name: "route denylist", entries: route.IPDenyList},
+		{name: "project allowlist", entries: route.ProjectIPAllowList, allow: true},
+		{name: "route allowlist", entries: route.IPAllowList, allow: true},
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", err
+	}
+	addr = addr.Unmap()
+
+	for _, list := range lists {
+		if list.entries == nil {
+			continue
+		}
+
+		entry, matched := matchIPAccessList(addr, list.entries)
+
+		if !list.allow && matched {
+			return fmt.Sprintf("ip %s matched %s entry %s", addr, list.name, formatIPAccessEntry(entry)), nil
+		}
+		if list.allow && !matched {
+			return fmt.Sprintf("ip %s is not in %s", addr, list.name), nil
+		}
+	}
+
+	return "", nil
+}
+
+// matchIPAccessList returns the first entry of the list that contains the
+// given address and a boolean indicating whether a match was found.
+func matchIPAccessList(addr netip.Addr, entries []netip.Prefix) (netip.Prefix, bool) {
+	for _, entry := range entries {
+		if entry.Contains(addr) {
+			return entry, true
+		}
+	}
+
+	return netip.Prefix{}, false
+}
+
+// formatIPAccessEntry returns an entry as it is usually written, single
+// addresses without their prefix length.
+func formatIPAccessEntry(entry netip.Prefix) string {
+	if entry.IsSingleIP() {
+		return entry.Addr().String()
+	}
+	return entry.String()
+}
+
+// ParseIPAccessList parses the entries of an IP access list, returning apart
+// the entries that are neither a CIDR range nor a single IP address. The
+// ranges are nil only when there are no entries, so a list with only invalid
+// entries is still an allowlist that blocks every IP address.
+func ParseIPAccessList(entries []string) ([]netip.Prefix, []string) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	var invalid []string
+	for _, entry := range entries {
+		prefix, err := ParseIPAccessEntry(entry)
+		if err != nil {
+			invalid = append(invalid, entry)
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, invalid
+}
+
+// InvalidIPAccessEntries returns the entries of the list that are neither a
+// CIDR range nor a single IP address.
+func InvalidIPAccessEntries(entries []string) []string {
+	_, invalid := ParseIPAccessList(entries)
+	return invalid
+}
+
+// ParseIPAccessEntry parses a CIDR range or a single IP address, the latter
+// is converted to a single address prefix.
+func ParseIPAccessEntry(entry string) (netip.Prefix, error) {
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
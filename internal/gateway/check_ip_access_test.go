@@ -0,0 +1,200 @@
+package gateway
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIPAccess(t *testing.T) {
+	tests := []struct {
+		name        string
+		ip          string
+		route       Route
+		wantBlocked bool
+		wantReason  string
+		wantErr     bool
+	}{
+		{
+			name:        "no lists",
+			ip:          "203.0.113.7",
+			route:       Route{},
+			wantBlocked: false,
+		},
+		{
+			name:        "allowed by route allowlist",
+			ip:          "10.8.0.15",
+			route:       Route{IPAllowList: mustParseIPAccessList("10.8.0.0/16")},
+			wantBlocked: false,
+		},
+		{
+			name:        "not in route allowlist",
+			ip:          "203.0.113.7",
+			route:       Route{IPAllowList: mustParseIPAccessList("10.8.0.0/16")},
+			wantBlocked: true,
+			wantReason:  "ip 203.0.113.7 is not in route allowlist",
+		},
+		{
+			name:        "single IP allowlist entry",
+			ip:          "192.168.1.10",
+			route:       Route{IPAllowList: mustParseIPAccessList("192.168.1.10")},
+			wantBlocked: false,
+		},
+		{
+			name:        "denied by route denylist",
+			ip:          "10.8.0.15",
+			route:       Route{IPDenyList: mustParseIPAccessList("10.8.0.0/24")},
+			wantBlocked: true,
+			wantReason:  "ip 10.8.0.15 matched route denylist entry 10.8.0.0/24",
+		},
+		{
+			name: "denylist takes precedence over allowlist",
+			ip:   "10.8.0.15",
+			route: Route{
+				IPAllowList: mustParseIPAccessList("10.0.0.0/8"),
+				IPDenyList:  mustParseIPAccessList("10.8.0.15"),
+			},
+			wantBlocked: true,
+			wantReason:  "ip 10.8.0.15 matched route denylist entry 10.8.0.15",
+		},
+		{
+			name:        "denied by project denylist",
+			ip:          "198.51.100.1",
+			route:       Route{ProjectIPDenyList: mustParseIPAccessList("198.51.100.0/24")},
+			wantBlocked: true,
+			wantReason:  "ip 198.51.100.1 matched project denylist entry 198.51.100.0/24",
+		},
+		{
+			name: "allowed by route but not by project",
+			ip:   "172.16.0.1",
+			route: Route{
+				IPAllowList:        mustParseIPAccessList("172.16.0.0/12"),
+				ProjectIPAllowList: mustParseIPAccessList("10.0.0.0/8"),
+			},
+			wantBlocked: true,
+			wantReason:  "ip 172.16.0.1 is not in project allowlist",
+		},
+		{
+			name:        "IPv6 range",
+			ip:          "2001:db8::1",
+			route:       Route{IPAllowList: mustParseIPAccessList("2001:db8::/32")},
+			wantBlocked: false,
+		},
+		{
+			name:        "IPv4-mapped IPv6 client",
+			ip:          "::ffff:10.8.0.15",
+			route:       Route{IPAllowList: mustParseIPAccessList("10.8.0.0/16")},
+			wantBlocked: false,
+		},
+		{
+			name:        "empty allowlist blocks every IP",
+			ip:          "10.8.0.15",
+			route:       Route{IPAllowList: []netip.Prefix{}},
+			wantBlocked: true,
+			wantReason:  "ip 10.8.0.15 is not in route allowlist",
+		},
+		{
+			name:    "invalid client IP",
+			ip:      "invalid",
+			route:   Route{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, err := checkIPAccess(tt.ip, tt.route)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBlocked, reason != "")
+			if tt.wantReason != "" {
+				assert.Equal(t, tt.wantReason, reason)
+			}
+		})
+	}
+}
+
+func TestIPBlockResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		route       Route
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "defaults", route: Route{}, wantStatus: 403, wantMessage: "Forbidden"},
+		{name: "custom status", route: Route{IPBlockStatus: 404}, wantStatus: 404, wantMessage: "Not Found"},
+		{name: "custom message", route: Route{IPBlockStatus: 451, IPBlockMessage: "nope"}, wantStatus: 451, wantMessage: "nope"},
+		{name: "status below 400", route: Route{IPBlockStatus: 42}, wantStatus: 403, wantMessage: "Forbidden"},
+		{name: "status above 599", route: Route{IPBlockStatus: 600}, wantStatus: 403, wantMessage: "Forbidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message := ipBlockResponse(tt.route)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
+// mustParseIPAccessList parses a list of valid IP access entries.
+func mustParseIPAccessList(entries ...string) []netip.Prefix {
+	prefixes, invalid := ParseIPAccessList(entries)
+	if len(invalid) > 0 {
+		panic("invalid IP access entries")
+	}
+	return prefixes
+}
+
+func TestParseIPAccessList(t *testing.T) {
+	tests := []struct {
+		name        string
+		entries     []string
+		wantEntries []netip.Prefix
+		wantInvalid []string
+	}{
+		{
+			name: "no entries",
+		},
+		{
+			name:    "valid entries",
+			entries: []string{"10.8.0.1/16", "192.168.1.10", "::ffff:10.0.0.1", "2001:db8::/32"},
+			wantEntries: []netip.Prefix{
+				netip.MustParsePrefix("10.8.0.0/16"),
+				netip.MustParsePrefix("192.168.1.10/32"),
+				netip.MustParsePrefix("10.0.0.1/32"),
+				netip.MustParsePrefix("2001:db8::/32"),
+			},
+		},
+		{
+			name:        "invalid entries are left out",
+			entries:     []string{"not-an-ip", "10.8.0.0/33", "10.8.0.0/16"},
+			wantEntries: []netip.Prefix{netip.MustParsePrefix("10.8.0.0/16")},
+			wantInvalid: []string{"not-an-ip", "10.8.0.0/33"},
+		},
+		{
+			name:        "only invalid entries",
+			entries:     []string{"not-an-ip"},
+			wantEntries: []netip.Prefix{},
+			wantInvalid: []string{"not-an-ip"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, invalid := ParseIPAccessList(tt.entries)
+			assert.Equal(t, tt.wantEntries, entries)
+			assert.Equal(t, tt.wantInvalid, invalid)
+		})
+	}
+}
+
+func TestInvalidIPAccessEntries(t *testing.T) {
+	assert.Nil(t, InvalidIPAccessEntries([]string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"}))
+	assert.Equal(t, []string{"10.0.0.0/33", "vpn"}, InvalidIPAccessEntries([]string{"10.0.0.0/33", "10.0.0.1", "vpn"}))
+}
@@ -0,0 +1,403 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings the gateway can compress responses with.
+const (
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+	EncodingGzip   = "gzip"
+)
+
+// defaultCompressEncodings are the encodings used by routes that don't list
+// their own, in order of preference.
+var defaultCompressEncodings = []string{EncodingBrotli, EncodingZstd, EncodingGzip}
+
+// defaultCompressContentTypes are the compressed media types of routes that
+// don't list their own.
+var defaultCompressContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/*+json",
+	"application/javascript",
+	"application/xml",
+	"application/*+xml",
+	"image/svg+xml",
+}
+
+// defaultCompressMinBytes is the smallest compressed response of routes that
+// don't set their own minimum.
+const defaultCompressMinBytes = 1024
+
+// compressEncoder is implemented by the writers of every supported encoding.
+type compressEncoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// newCompressEncoder returns a writer that compresses to w with the given
+// encoding.
+func newCompressEncoder(w io.Writer, encoding string) (compressEncoder, error) {
+	switch encoding {
+	case EncodingBrotli:
+		return brotli.NewWriterLevel(w, 4), nil
+	case EncodingZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	default:
+		return gzip.NewWriter(w), nil
+	}
+}
+
+// negotiateEncoding returns the encoding from supported with the highest
+// quality in an Accept-Encoding header, ties are broken by the order of
+// supported. It returns an empty string if none of them is acceptable.
+func negotiateEncoding(acceptEncoding string, supported []string) string {
+	qualities := map[string]float64{}
+	wildcard := -1.0
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		if name == "*" {
+			wildcard = quality
+			continue
+		}
+		qualities[name] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range supported {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = wildcard
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// matchesContentType reports whether the media type of a Content-Type header
+// matches one of the patterns. Patterns are media types that can use a
+// wildcard subtype (text/*) or a wildcard before a structured syntax suffix
+// (application/*+json).
+func matchesContentType(contentType string, patterns []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		prefix, suffix, hasWildcard := strings.Cut(pattern, "*")
+		if !hasWildcard {
+			if mediaType == pattern {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(mediaType, prefix) && strings.HasSuffix(mediaType, suffix) &&
+			len(mediaType) > len(prefix)+len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter is an http.ResponseWriter that compresses the response with
+// the best encoding accepted by the client when the response qualifies.
+//
+// It keeps the headers written by the gateway apart from the ones sent to the
+// client, so the logged headers and the shared coalesced or cached responses
+// never see the compression. Responses of unknown length are buffered until
+// they reach the minimum size, smaller ones are sent uncompressed.
+type compressWriter struct {
+	http.ResponseWriter
+	header       http.Header // are the headers written by the gateway
+	accepted     string      // is the encoding negotiated with the client
+	isHead       bool        // is true for HEAD requests, which have no body to compress
+	minBytes     int
+	contentTypes []string
+
+	statusCode  int
+	wroteHeader bool
+	vary        bool // is true when the encoding depends on the client
+	decided     bool
+	encoding    string // is the encoding applied to the response, if any
+	encoder     compressEncoder
+	buf         bytes.Buffer
+}
+
+// newCompressWriter returns a compressWriter for the response to r on the
+// given route.
+func newCompressWriter(w http.ResponseWriter, r *http.Request, route Route) *compressWriter {
+	encodings := []string{}
+	for _, encoding := range route.CompressEncodings {
+		encoding = strings.ToLower(encoding)
+		if slices.Contains(defaultCompressEncodings, encoding) {
+			encodings = append(encodings, encoding)
+		}
+	}
+	if len(encodings) == 0 {
+		encodings = defaultCompressEncodings
+	}
+	contentTypes := route.CompressTypes
+	if len(contentTypes) == 0 {
+		contentTypes = defaultCompressContentTypes
+	}
+	minBytes := route.CompressMinBytes
+	if minBytes <= 0 {
+		minBytes = defaultCompressMinBytes
+	}
+
+	return &compressWriter{
+		ResponseWriter: w,
+		header:         w.Header().Clone(),
+		accepted:       negotiateEncoding(r.Header.Get("Accept-Encoding"), encodings),
+		isHead:         r.Method == http.MethodHead,
+		minBytes:       minBytes,
+		contentTypes:   contentTypes,
+	}
+}
+
+// Header returns the headers written by the gateway, without the changes
+// made to compress the response.
+func (cw *compressWriter) Header() http.Header {
+	return cw.header
+}
+
+// WriteHeader decides whether the response is compressed, unless its size
+// is still unknown.
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.wroteHeader {
+		return
+	}
+
+	// informational responses are sent as they are
+	if !isFinalStatus(statusCode) {
+		cw.syncHeader()
+		cw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	cw.wroteHeader = true
+	cw.statusCode = statusCode
+
+	if !cw.compressible() {
+		_ = cw.decide(false)
+		return
+	}
+	cw.vary = true
+	if cw.accepted == "" || cw.isHead {
+		_ = cw.decide(false)
+		return
+	}
+
+	if contentLength := cw.header.Get("Content-Length"); contentLength != "" {
+		size, err := strconv.Atoi(contentLength)
+		_ = cw.decide(err == nil && size >= cw.minBytes)
+	}
+}
+
+// Write compresses b if the response is compressed, or buffers it while the
+// response is still too small to decide.
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.decided {
+		cw.buf.Write(b)
+		if cw.buf.Len() < cw.minBytes {
+			return len(b), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends what was written so far to the client. A response of unknown
+// size keeps being buffered until it reaches the minimum size, the reverse
+// proxy flushes those after every write.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		return
+	}
+	if cw.encoder != nil {
+		_ = cw.encoder.Flush()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter so http.ResponseController
+// can reach optional interfaces such as http.Hijacker
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close finishes the response, it must be called once the gateway is done
+// writing it.
+func (cw *compressWriter) Close() error {
+	if !cw.wroteHeader {
+		cw.syncHeader()
+		return nil
+	}
+	if !cw.decided {
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if cw.encoder != nil {
+		err = cw.encoder.Close()
+	}
+	cw.syncTrailers()
+	return err
+}
+
+// compressible reports whether the response qualifies for compression by
+// its status and headers, regardless of its size and of the client.
+func (cw *compressWriter) compressible() bool {
+	contentEncoding := cw.header.Get("Content-Encoding")
+
+	switch {
+	case cw.statusCode == http.StatusSwitchingProtocols,
+		cw.statusCode == http.StatusNoContent,
+		cw.statusCode == http.StatusPartialContent,
+		cw.statusCode == http.StatusNotModified:
+		return false
+	case contentEncoding != "" && !strings.EqualFold(contentEncoding, "identity"):
+		return false
+	case strings.Contains(strings.ToLower(cw.header.Get("Cache-Control")), "no-transform"):
+		return false
+	case matchesContentType(cw.header.Get("Content-Type"), []string{"text/event-stream"}):
+		// buffering would hold back the events
+		return false
+	}
+	return matchesContentType(cw.header.Get("Content-Type"), cw.contentTypes)
+}
+
+// decide sends the headers to the client, compressing the response if
+// compress is true, followed by the buffered body.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	cw.syncHeader()
+
+	header := cw.ResponseWriter.Header()
+	if cw.vary {
+		addVary(header, "Accept-Encoding")
+	}
+	if compress {
+		encoder, err := newCompressEncoder(cw.ResponseWriter, cw.accepted)
+		if err == nil {
+			cw.encoder = encoder
+			cw.encoding = cw.accepted
+			header.Del("Content-Length")
+			header.Set("Content-Encoding", cw.accepted)
+			// the compressed representation is not byte for byte the one
+			// the origin tagged
+			if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				header.Set("ETag", "W/"+etag)
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	var w io.Writer = cw.ResponseWriter
+	if cw.encoder != nil {
+		w = cw.encoder
+	}
+	_, err := w.Write(cw.buf.Bytes())
+	cw.buf.Reset()
+	return err
+}
+
+// syncHeader replaces the headers sent to the client with the ones written
+// by the gateway.
+func (cw *compressWriter) syncHeader() {
+	header := cw.ResponseWriter.Header()
+	clear(header)
+	for name, values := range cw.header {
+		header[name] = slices.Clone(values)
+	}
+}
+
+// syncTrailers copies the trailers written by the gateway after the body to
+// the headers sent to the client.
+func (cw *compressWriter) syncTrailers() {
+	declared := map[string]bool{}
+	for _, value := range cw.header.Values("Trailer") {
+		for _, name := range strings.Split(value, ",") {
+			declared[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+
+	header := cw.ResponseWriter.Header()
+	for name, values := range cw.header {
+		if declared[name] || strings.HasPrefix(name, http.TrailerPrefix) {
+			header[name] = slices.Clone(values)
+		}
+	}
+}
+
+// addVary adds a header name to the Vary header unless it is already there.
+func addVary(header http.Header, name string) {
+	for _, value := range header.Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			existing = strings.TrimSpace(existing)
+			if existing == "*" || strings.EqualFold(existing, name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
+
+// clientEncoding returns the content encoding of the response sent to the
+// client when w compresses responses.
+func clientEncoding(w http.ResponseWriter) string {
+	if cw, ok := w.(*compressWriter); ok {
+		return cw.encoding
+	}
+	return ""
+}
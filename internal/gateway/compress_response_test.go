@@ -0,0 +1,270 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{EncodingBrotli, EncodingZstd, EncodingGzip}
+
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{acceptEncoding: "", want: ""},
+		{acceptEncoding: "identity", want: ""},
+		{acceptEncoding: "gzip", want: EncodingGzip},
+		{acceptEncoding: "gzip, deflate, br", want: EncodingBrotli},
+		{acceptEncoding: "gzip, br;q=0.5", want: EncodingGzip},
+		{acceptEncoding: "zstd;q=0.9, br;q=0.9, gzip;q=0.8", want: EncodingBrotli},
+		{acceptEncoding: "br;q=0, *", want: EncodingZstd},
+		{acceptEncoding: "*;q=0", want: ""},
+		{acceptEncoding: "GZIP;q=invalid, ZSTD", want: EncodingZstd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			assert.Equal(t, tt.want, negotiateEncoding(tt.acceptEncoding, supported))
+		})
+	}
+}
+
+func TestMatchesContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{contentType: "application/json", want: true},
+		{contentType: "Application/JSON; charset=utf-8", want: true},
+		{contentType: "application/problem+json", want: true},
+		{contentType: "application/+json", want: false},
+		{contentType: "text/html", want: true},
+		{contentType: "image/svg+xml", want: true},
+		{contentType: "image/png", want: false},
+		{contentType: "application/octet-stream", want: false},
+		{contentType: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchesContentType(tt.contentType, defaultCompressContentTypes))
+		})
+	}
+}
+
+func decodeBody(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var r io.Reader
+	switch encoding {
+	case EncodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		r = gr
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		defer zr.Close()
+		r = zr
+	default:
+		r = bytes.NewReader(body)
+	}
+
+	decoded, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(decoded)
+}
+
+func TestGatewayCompressResponses(t *testing.T) {
+	largeJSON := `{"items":"` + strings.Repeat("a", 2048) + `"}`
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := largeJSON
+		switch r.URL.Path {
+		case "/small":
+			body = `{"ok":true}`
+		case "/png":
+			w.Header().Set("Content-Type", "image/png")
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "gzip")
+		case "/chunked":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"v1"`)
+			for i := 0; i < len(body); i += 100 {
+				_, _ = w.Write([]byte(body[i:min(i+100, len(body))]))
+				w.(http.Flusher).Flush()
+			}
+			return
+		}
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = io.WriteString(w, body)
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		name           string
+		path           string
+		method         string
+		acceptEncoding string
+		compress       bool
+		encodings      []string
+		wantEncoding   string
+		wantVary       bool
+		wantETag       string
+	}{
+		{
+			name:           "prefers brotli",
+			path:           "/json",
+			acceptEncoding: "gzip, deflate, br, zstd",
+			compress:       true,
+			wantEncoding:   EncodingBrotli,
+			wantVary:       true,
+		},
+		{
+			name:           "gzip",
+			path:           "/json",
+			acceptEncoding: "gzip",
+			compress:       true,
+			wantEncoding:   EncodingGzip,
+			wantVary:       true,
+		},
+		{
+			name:           "route encodings",
+			path:           "/json",
+			acceptEncoding: "gzip, br, zstd",
+			compress:       true,
+			encodings:      []string{"deflate", "ZSTD", "gzip"},
+			wantEncoding:   EncodingZstd,
+			wantVary:       true,
+		},
+		{
+			name:           "chunked response",
+			path:           "/chunked",
+			acceptEncoding: "gzip",
+			compress:       true,
+			wantEncoding:   EncodingGzip,
+			wantVary:       true,
+			wantETag:       `W/"v1"`,
+		},
+		{
+			name:           "compression disabled",
+			path:           "/json",
+			acceptEncoding: "gzip",
+		},
+		{
+			name:     "client without compression",
+			path:     "/json",
+			compress: true,
+			wantVary: true,
+		},
+		{
+			name:           "small response",
+			path:           "/small",
+			acceptEncoding: "gzip",
+			compress:       true,
+			wantVary:       true,
+		},
+		{
+			name:           "not allowed content type",
+			path:           "/png",
+			acceptEncoding: "gzip",
+			compress:       true,
+		},
+		{
+			name:           "already encoded",
+			path:           "/encoded",
+			acceptEncoding: "gzip",
+			compress:       true,
+		},
+		{
+			name:           "head request",
+			path:           "/json",
+			method:         http.MethodHead,
+			acceptEncoding: "gzip",
+			compress:       true,
+			wantVary:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storer := &fakeLogStorer{}
+			routes := &fakeRouteProvider{routes: []Route{{
+				ID:                "route1",
+				Endpoint:          "/api",
+				OriginURL:         origin.URL,
+				CompressResponses: tt.compress,
+				CompressEncodings: tt.encodings,
+			}}}
+			g := NewGateway(routes, storer)
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/api"+tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			g.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			if tt.path == "/encoded" {
+				assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+			} else {
+				assert.Equal(t, tt.wantEncoding, rec.Header().Get("Content-Encoding"))
+			}
+			assert.Equal(t, tt.wantVary, rec.Header().Get("Vary") == "Accept-Encoding")
+			if tt.wantETag != "" {
+				assert.Equal(t, tt.wantETag, rec.Header().Get("ETag"))
+			}
+			if tt.wantEncoding != "" {
+				assert.Empty(t, rec.Header().Get("Content-Length"))
+			}
+
+			require.Len(t, storer.responseLogs, 1)
+			resLog := storer.responseLogs[0]
+			assert.Equal(t, tt.wantEncoding, resLog.ClientEncoding)
+			assert.Empty(t, resLog.ResponseHeaders["Vary"])
+			if tt.path != "/encoded" {
+				assert.Empty(t, resLog.ResponseHeaders["Content-Encoding"])
+			}
+
+			if method == http.MethodHead {
+				assert.Empty(t, rec.Body.Bytes())
+				return
+			}
+
+			loggedBody, _ := io.ReadAll(resLog.ResponseBody)
+			if tt.path == "/encoded" {
+				assert.Equal(t, largeJSON, string(loggedBody))
+				assert.Equal(t, largeJSON, rec.Body.String())
+				return
+			}
+			assert.Equal(t, decodeBody(t, tt.wantEncoding, rec.Body.Bytes()), string(loggedBody))
+			if tt.path == "/small" {
+				assert.Equal(t, `{"ok":true}`, string(loggedBody))
+			} else {
+				assert.Equal(t, largeJSON, string(loggedBody))
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/uforg/ufogateway/internal/util/strutil"
+)
+
+// contractBlockReason is the block reason of requests rejected because they
+// violate the route contract.
+const contractBlockReason = "request violates the route contract"
+
+// validatesContract reports whether the traffic of the route is validated
+// against its contract.
+func (route Route) validatesContract() bool {
+	if route.ContractValidator == nil {
+		return false
+	}
+	return route.ContractMode == ContractModeReport || route.ContractMode == ContractModeEnforce
+}
+
+// contractOriginPath returns the path a request to the gateway has at the
+// origin, which is the path described by the route contract.
+func contractOriginPath(originURLPath string, gatewayPath string, endpoint string) string {
+	path := "/" + strutil.RemoveAllTrailingSlashes(strutil.RemoveAllLeadingSlashes(originURLPath))
+	if rest := gatewayToOriginPath(gatewayPath, endpoint); rest != "" {
+		path = strutil.RemoveAllTrailingSlashes(path) + "/" + rest
+	}
+	return path
+}
+
+// serveContractViolation rejects a request that violates the route contract
+// with a problem listing the violations and logs it along with them.
+func (g *Gateway) serveContractViolation(
+	w http.ResponseWriter,
+	r *http.Request,
+	route Route,
+	requestID string,
+	requestIP string,
+	startTime time.Time,
+	reqBody []byte,
+	findings []ContractFinding,
+	replay *replayInfo,
+) {
+	requestGatewayURL, requestOriginURL := getRequestURL(r, route)
+	g.logStorer.StoreRequestLog(RequestLog{
+		RouteID:           route.ID,
+		Timestamp:         startTime,
+		RequestID:         requestID,
+		RequestIP:         requestIP,
+		RequestMethod:     r.Method,
+		RequestGatewayURL: requestGatewayURL,
+		RequestOriginURL:  requestOriginURL,
+		RequestHeaders:    cloneHeaderMap(r.Header),
+		RequestBody:       bytes.NewReader(reqBody),
+		RequestBodySize:   int64(len(reqBody)),
+		BlockReason:       contractBlockReason,
+		ReplayOf:          replay.originalRequestID(),
+		ReplayBatch:       replay.batchID(),
+		TraceID:           requestTraceID(r),
+		ContractFindings:  findings,
+	})
+
+	problem := newProblem(requestID, r.URL.Path, http.StatusBadRequest, ErrorKindRejected, contractBlockReason)
+	problem.Extra = map[string]any{"findings": findings}
+
+	customWriter := newResponseWriter(w)
+	writeProblem(customWriter, route, problem)
+
+	g.logStorer.StoreResponseLog(ResponseLog{
+		RouteID:         route.ID,
+		Timestamp:       time.Now(),
+		Duration:        time.Since(startTime),
+		RequestID:       requestID,
+		StatusCode:      customWriter.getStatusCode(),
+		ResponseHeaders: cloneHeaderMap(w.Header()),
+		ResponseBody:    bytes.NewReader(customWriter.getBody()),
+		ErrorKind:       ErrorKindRejected,
+	})
+}
@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractOriginPath(t *testing.T) {
+	tests := []struct {
+		name          string
+		originURLPath string
+		gatewayPath   string
+		endpoint      string
+		want          string
+	}{
+		{
+			name:          "origin without path",
+			originURLPath: "",
+			gatewayPath:   "/api/users/1",
+			endpoint:      "/api",
+			want:          "/users/1",
+		},
+		{
+			name:          "origin with path",
+			originURLPath: "/v1/",
+			gatewayPath:   "/api/users",
+			endpoint:      "/api",
+			want:          "/v1/users",
+		},
+		{
+			name:          "request to the endpoint itself",
+			originURLPath: "/v1",
+			gatewayPath:   "/api",
+			endpoint:      "/api",
+			want:          "/v1",
+		},
+		{
+			name:          "request to the endpoint of an origin without path",
+			originURLPath: "",
+			gatewayPath:   "/api",
+			endpoint:      "/api",
+			want:          "/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, contractOriginPath(tt.originURLPath, tt.gatewayPath, tt.endpoint))
+		})
+	}
+}
+
+// fakeContractValidator reports a finding for requests without an X-Valid
+// header and for responses with a status code other than 200.
+type fakeContractValidator struct {
+	originPaths []string
+}
+
+func (v *fakeContractValidator) ValidateRequest(r *http.Request, originPath string, body []byte) []ContractFinding {
+	v.originPaths = append(v.originPaths, originPath)
+	if r.Header.Get("X-Valid") == "" {
+		return []ContractFinding{{Kind: "request", Location: "header.X-Valid", Message: "missing"}}
+	}
+	return nil
+}
+
+func (v *fakeContractValidator) ValidateResponse(r *http.Request, originPath string, statusCode int, header http.Header, body []byte) []ContractFinding {
+	if statusCode != http.StatusOK {
+		return []ContractFinding{{Kind: "response", Location: "status", Message: "undocumented"}}
+	}
+	return nil
+}
+
+func TestGatewayContractValidation(t *testing.T) {
+	originHits := 0
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originHits++
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer origin.Close()
+
+	newGateway := func(mode string) (*Gateway, *fakeLogStorer, *fakeContractValidator) {
+		validator := &fakeContractValidator{}
+		storer := &fakeLogStorer{}
+		routes := &fakeRouteProvider{routes: []Route{{
+			ID:                "route1",
+			Endpoint:          "/api",
+			OriginURL:         origin.URL + "/v1",
+			ContractMode:      mode,
+			ContractValidator: validator,
+			ContractResponses: true,
+		}}}
+		return NewGateway(routes, storer), storer, validator
+	}
+
+	t.Run("enforce mode rejects invalid requests", func(t *testing.T) {
+		originHits = 0
+		g, storer, validator := newGateway(ContractModeEnforce)
+
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, 0, originHits)
+		assert.Equal(t, []string{"/v1/users"}, validator.originPaths)
+
+		assert.Equal(t, problemContentType, rec.Header().Get("Content-Type"))
+		body := map[string]any{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, ErrorKindRejected, body["code"])
+		assert.Equal(t, contractBlockReason, body["detail"])
+		assert.Len(t, body["findings"], 1)
+
+		require.Len(t, storer.requestLogs, 1)
+		assert.Equal(t, contractBlockReason, storer.requestLogs[0].BlockReason)
+		assert.Len(t, storer.requestLogs[0].ContractFindings, 1)
+		require.Len(t, storer.responseLogs, 1)
+		assert.Equal(t, ErrorKindRejected, storer.responseLogs[0].ErrorKind)
+	})
+
+	t.Run("enforce mode lets valid requests through", func(t *testing.T) {
+		originHits = 0
+		g, storer, _ := newGateway(ContractModeEnforce)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/users?fail=1", nil)
+		req.Header.Set("X-Valid", "1")
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusTeapot, rec.Code)
+		assert.Equal(t, 1, originHits)
+		require.Len(t, storer.requestLogs, 1)
+		assert.Empty(t, storer.requestLogs[0].ContractFindings)
+		require.Len(t, storer.responseLogs, 1)
+		assert.Equal(t, []ContractFinding{{Kind: "response", Location: "status", Message: "undocumented"}}, storer.responseLogs[0].ContractFindings)
+	})
+
+	t.Run("report mode only logs violations", func(t *testing.T) {
+		originHits = 0
+		g, storer, _ := newGateway(ContractModeReport)
+
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		body, _ := io.ReadAll(rec.Body)
+		assert.Equal(t, "ok", string(body))
+		assert.Equal(t, 1, originHits)
+		require.Len(t, storer.requestLogs, 1)
+		assert.Empty(t, storer.requestLogs[0].BlockReason)
+		assert.Len(t, storer.requestLogs[0].ContractFindings, 1)
+		require.Len(t, storer.responseLogs, 1)
+		assert.Empty(t, storer.responseLogs[0].ContractFindings)
+	})
+
+	t.Run("off mode does not validate", func(t *testing.T) {
+		g, storer, validator := newGateway(ContractModeOff)
+
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users?fail=1", nil))
+
+		assert.Equal(t, http.StatusTeapot, rec.Code)
+		assert.Empty(t, validator.originPaths)
+		require.Len(t, storer.responseLogs, 1)
+		assert.Empty(t, storer.responseLogs[0].ContractFindings)
+	})
+}
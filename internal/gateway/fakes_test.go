@@ -0,0 +1,73 @@
+package gateway
+
+import "sync"
+
+// fakeRouteProvider is a RouteProvider that returns a fixed list of routes.
+type fakeRouteProvider struct {
+	routes []Route
+}
+
+func (p *fakeRouteProvider) Routes() ([]Route, error) {
+	return p.routes, nil
+}
+
+// fakeLogStorer is a LogStorer that keeps all the logs in memory.
+type fakeLogStorer struct {
+	mu           sync.Mutex
+	requestLogs  []RequestLog
+	responseLogs []ResponseLog
+	mirrorLogs   []MirrorLog
+}
+
+func (s *fakeLogStorer) StoreRequestLog(reqLog RequestLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requestLogs = append(s.requestLogs, reqLog)
+}
+
+func (s *fakeLogStorer) StoreResponseLog(respLog ResponseLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responseLogs = append(s.responseLogs, respLog)
+}
+
+func (s *fakeLogStorer) StoreMirrorLog(mirrorLog MirrorLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mirrorLogs = append(s.mirrorLogs, mirrorLog)
+}
+
+// fakeHitRecorder is a HitRecorder that keeps all the hits in memory.
+type fakeHitRecorder struct {
+	mu   sync.Mutex
+	hits []Hit
+}
+
+func (r *fakeHitRecorder) RecordHit(hit Hit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hits = append(r.hits, hit)
+}
+
+// fakeMetrics is a Metrics that keeps the started requests and the finished
+// hits in memory.
+type fakeMetrics struct {
+	mu       sync.Mutex
+	started  []string
+	finished []Hit
+	inFlight int
+}
+
+func (m *fakeMetrics) RequestStarted(route Route) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.started = append(m.started, route.ID)
+	m.inFlight++
+}
+
+func (m *fakeMetrics) RequestFinished(_ Route, hit Hit) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finished = append(m.finished, hit)
+	m.inFlight--
+}
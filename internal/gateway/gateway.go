@@ -2,24 +2,74 @@ package gateway
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httputil"
+	"net/netip"
 	"net/url"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/uforg/ufogateway/internal/util/randutil"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Route types supported by the gateway.
+const (
+	RouteTypeProxy = "proxy" // proxies requests to the origin URL
+	RouteTypeMock  = "mock"  // answers requests with a configured response
+)
+
+// Contract modes supported by the gateway.
+const (
+	ContractModeOff     = "off"     // requests are not validated
+	ContractModeReport  = "report"  // contract violations are only logged
+	ContractModeEnforce = "enforce" // requests that violate the contract are rejected
 )
 
 // Route represents a routing rule that maps an endpoint prefix to a destination URL.
 type Route struct {
-	ID                string // is the unique identifier for the route
-	Endpoint          string // is the prefix to match incoming requests
-	OriginURL         string // is the destination URL to proxy requests to
-	TLSClientCert     string // is the content of the PEM file (optional)
-	TLSClientKey      string // is the content of the key file (optional)
-	TLSCaCert         string // is the content of the CA certificate (optional)
-	TLSSkipCertVerify bool   // is a flag to skip TLS verification
+	ID                 string              // is the unique identifier for the route
+	Name               string              // is the display name of the route (optional)
+	Type               string              // is the route type, defaults to RouteTypeProxy
+	Endpoint           string              // is the prefix to match incoming requests
+	OriginURL          string              // is the destination URL to proxy requests to
+	TLSClientCert      string              // is the content of the PEM file (optional)
+	TLSClientKey       string              // is the content of the key file (optional)
+	TLSCaCert          string              // is the content of the CA certificate (optional)
+	TLSSkipCertVerify  bool                // is a flag to skip TLS verification
+	IPAllowList        []netip.Prefix      // is the list of CIDR ranges allowed to use the route, nil allows every IP
+	IPDenyList         []netip.Prefix      // is the list of CIDR ranges denied to use the route (optional)
+	ProjectIPAllowList []netip.Prefix      // is the list of CIDR ranges allowed by the route project, nil allows every IP
+	ProjectIPDenyList  []netip.Prefix      // is the list of CIDR ranges denied by the route project (optional)
+	IPBlockStatus      int                 // is the status code for blocked requests, from 400 to 599, defaults to 403
+	IPBlockMessage     string              // is the response body for blocked requests (optional)
+	CacheEnabled       bool                // is a flag to cache GET and HEAD responses
+	CacheVaryHeaders   []string            // is the list of request headers that are part of the cache key
+	CacheTTL           time.Duration       // is the freshness for responses without explicit caching headers
+	CoalesceRequests   bool                // is a flag to share one origin response between identical concurrent GET requests
+	MockStatus         int                 // is the status code of mock responses, from 100 to 599, defaults to 200
+	MockHeaders        map[string][]string // are the headers of mock responses, values are templates
+	MockBody           string              // is the body template of mock responses
+	MockLatency        time.Duration       // is an artificial delay added to mock responses
+	MockPathPattern    string              // is the pattern for path params of mock responses, e.g. /users/{id}
+	MirrorURL          string              // is the shadow origin URL that receives copies of requests (optional)
+	MirrorPercent      float64             // is the percentage of requests mirrored to MirrorURL, from 0 to 100
+	ContractMode       string              // is the contract mode, defaults to ContractModeOff
+	ContractValidator  ContractValidator   // validates requests and responses against the route contract (optional)
+	ContractResponses  bool                // is a flag to also validate the origin responses, violations are only logged
+	MaxBodyBytes       int64               // is the maximum request body size in bytes, zero uses the gateway limit
+	MaxHeaderBytes     int                 // is the maximum request headers size in bytes, zero uses the gateway limit
+	MaxHeaderCount     int                 // is the maximum number of request header lines, zero uses the gateway limit
+	CompressResponses  bool                // is a flag to compress responses with the encodings accepted by clients
+	CompressMinBytes   int                 // is the size of the smallest compressed response, defaults to 1024
+	CompressTypes      []string            // are the compressed media types, e.g. text/* (optional)
+	CompressEncodings  []string            // are the encodings in order of preference, defaults to br, zstd and gzip
+	ErrorTemplate      string              // is the body template of the errors answered by the gateway (optional)
+	ErrorContentType   string              // is the content type of ErrorTemplate, defaults to application/problem+json
+	ServerTiming       bool                // is a flag to send the origin request timings in a Server-Timing header
 }
 
 // RouteProvider defines an interface to obtain the current list of routes.
@@ -28,12 +78,40 @@ type RouteProvider interface {
 	Routes() ([]Route, error)
 }
 
+// ResponseCache defines an interface for storing responses cached by the gateway.
+type ResponseCache interface {
+	// Get retrieves the value associated with the given key.
+	Get(key string) (any, bool)
+	// Set stores the value with its size in bytes for the given time-to-live.
+	Set(key string, value any, size int64, ttl time.Duration) bool
+}
+
+// ContractValidator defines an interface for validating the traffic of a
+// route against its API contract.
+type ContractValidator interface {
+	// ValidateRequest validates a request, originPath is the path of the
+	// request at the origin and body is the already read request body.
+	ValidateRequest(r *http.Request, originPath string, body []byte) []ContractFinding
+	// ValidateResponse validates the response to a request validated with
+	// ValidateRequest.
+	ValidateResponse(r *http.Request, originPath string, statusCode int, header http.Header, body []byte) []ContractFinding
+}
+
+// ContractFinding represents a single contract violation.
+type ContractFinding struct {
+	Kind     string `json:"kind"`     // is either "request" or "response"
+	Location string `json:"location"` // is the part that violates the contract, e.g. query.limit or body/name
+	Message  string `json:"message"`  // describes the violation
+}
+
 // LogStorer defines an interface for storing request and response logs.
 type LogStorer interface {
 	// StoreRequestLog stores the log entry for a request.
 	StoreRequestLog(reqLog RequestLog)
 	// StoreResponseLog stores the log entry for a response.
 	StoreResponseLog(respLog ResponseLog)
+	// StoreMirrorLog stores the log entry for a mirrored request.
+	StoreMirrorLog(mirrorLog MirrorLog)
 }
 
 // RequestLog represents the data to be logged for an incoming request.
@@ -47,76 +125,224 @@ type RequestLog struct {
 	RequestOriginURL  string              // URL of the origin server handling the request
 	RequestHeaders    map[string][]string // Headers of the request
 	RequestBody       io.Reader           // Body of the request
+	RequestBodySize   int64               // Size in bytes of the request body, the attempted size if it was too large
+	BlockReason       string              // Reason why the gateway blocked the request, if it did
+	ReplayOf          string              // Unique identifier of the request this one replays, if any
+	ReplayBatch       string              // Identifier of the batch of replays this one belongs to, if any
+	TraceID           string              // Identifier of the trace of the request, if it is traced
+	ContractFindings  []ContractFinding   // Contract violations of the request, if any
 }
 
 // ResponseLog represents the data to be logged for an outgoing response.
 type ResponseLog struct {
+	RouteID          string              // Identifier of the route handling the request
+	Timestamp        time.Time           // Timestamp when the response was sent
+	Duration         time.Duration       // Time taken to process the request
+	RequestID        string              // Unique identifier matching the request
+	StatusCode       int                 // Status code of the response
+	ResponseHeaders  map[string][]string // Headers of the response
+	ResponseBody     io.Reader           // Body of the response
+	CacheHit         bool                // Whether the response was served from the response cache
+	CoalescedWith    string              // Request ID of the request whose response was shared, if any
+	Followers        int                 // Number of coalesced requests that shared this response
+	ContractFindings []ContractFinding   // Contract violations of the response, if any
+	ClientEncoding   string              // Content encoding the gateway compressed the response with, if any
+	ErrorKind        string              // Kind of the error the gateway answered the request with, if any
+	UpstreamError    *UpstreamError      // Failure of the request to the origin, if any
+	UpstreamTimings  *UpstreamTimings    // Phase timings of the request to the origin, if it was sent
+}
+
+// MirrorLog represents the data to be logged for a request mirrored to a
+// shadow origin.
+type MirrorLog struct {
 	RouteID         string              // Identifier of the route handling the request
-	Timestamp       time.Time           // Timestamp when the response was sent
-	Duration        time.Duration       // Time taken to process the request
-	RequestID       string              // Unique identifier matching the request
-	ResponseHeaders map[string][]string // Headers of the response
-	ResponseBody    io.Reader           // Body of the response
+	RequestID       string              // Unique identifier of the mirrored request
+	MirrorURL       string              // URL of the shadow origin receiving the request
+	Timestamp       time.Time           // Timestamp when the mirrored request was sent
+	Duration        time.Duration       // Time taken by the shadow origin to respond
+	StatusCode      int                 // Status code of the shadow response
+	ResponseHeaders map[string][]string // Headers of the shadow response
+	ResponseBody    io.Reader           // Body of the shadow response
+	Error           string              // Error that prevented getting a shadow response, if any
 }
 
 // Gateway represents the main gateway instance that routes and proxies requests.
 type Gateway struct {
-	routeProvider RouteProvider // Provider for obtaining the current routes
-	logStorer     LogStorer     // Storer for logging requests and responses
+	routeProvider  RouteProvider        // Provider for obtaining the current routes
+	logStorer      LogStorer            // Storer for logging requests and responses
+	responseCache  ResponseCache        // Store for cached responses (optional)
+	coalescer      *requestCoalescer    // Groups identical concurrent requests
+	mirrorClient   *http.Client         // Client used to send requests to shadow origins
+	mirrorSlots    chan struct{}        // Slots of the mirrored requests in flight
+	requestLimits  RequestLimits        // Limits for the routes that don't set their own
+	trustedProxies []netip.Prefix       // Proxies whose X-Forwarded-For header is trusted (optional)
+	hitRecorder    HitRecorder          // Recorder of the requests served by the routes (optional)
+	metrics        Metrics              // Metrics of the requests served by the routes (optional)
+	tracerProvider trace.TracerProvider // Provider of the request spans (optional)
+	handler        http.Handler         // Handler of the requests, traced when tracing is enabled
+}
+
+// Option configures optional features of the gateway.
+type Option func(g *Gateway)
+
+// WithResponseCache sets the store used by routes with response caching enabled.
+func WithResponseCache(responseCache ResponseCache) Option {
+	return func(g *Gateway) {
+		g.responseCache = responseCache
+	}
+}
+
+// WithRequestLimits sets the request limits of the routes that don't set
+// their own.
+func WithRequestLimits(limits RequestLimits) Option {
+	return func(g *Gateway) {
+		g.requestLimits = limits
+	}
 }
 
 // NewGateway creates a new gateway instance with the given route provider and log storer.
-func NewGateway(routeProvider RouteProvider, logStorer LogStorer) *Gateway {
-	return &Gateway{
+func NewGateway(routeProvider RouteProvider, logStorer LogStorer, opts ...Option) *Gateway {
+	g := &Gateway{
 		routeProvider: routeProvider,
 		logStorer:     logStorer,
+		coalescer:     newRequestCoalescer(),
+		mirrorSlots:   make(chan struct{}, maxConcurrentMirrors),
+		mirrorClient: &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	for _, opt := range opts {
+		opt(g)
 	}
+	g.handler = g.traceHandler(http.HandlerFunc(g.serveHTTP))
+	return g
 }
 
 // ServeHTTP handles incoming HTTP requests and proxies them to the appropriate backend.
 // It implements the http.Handler interface.
 func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	requestIP, err := getRequestIP(r)
+	g.handler.ServeHTTP(w, r)
+}
+
+func (g *Gateway) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	requestIP, err := getRequestIP(r, g.trustedProxies)
 	if err != nil {
-		http.Error(w, "Gateway Error: failed to get request IP", http.StatusInternalServerError)
+		g.serveError(w, r, Route{}, randutil.GenerateIDForPocketBase(), "", time.Now(), false, "failed to get request IP")
 		return
 	}
 
 	if g.routeProvider == nil {
-		http.Error(w, "Gateway Error: routeProvider is nil", http.StatusInternalServerError)
+		g.serveError(w, r, Route{}, randutil.GenerateIDForPocketBase(), "", time.Now(), false, "routeProvider is nil")
 		return
 	}
 	if g.logStorer == nil {
-		http.Error(w, "Gateway Error: logStorer is nil", http.StatusInternalServerError)
+		g.serveError(w, r, Route{}, randutil.GenerateIDForPocketBase(), "", time.Now(), false, "logStorer is nil")
 		return
 	}
 
 	routes, err := g.routeProvider.Routes()
 	if err != nil {
-		http.Error(w, "Gateway Error: failed to get routes", http.StatusInternalServerError)
+		g.serveError(w, r, Route{}, randutil.GenerateIDForPocketBase(), "", time.Now(), false, "failed to get routes")
 		return
 	}
 
 	route, found := findRoute(routes, r.URL.Path)
 	if !found {
-		http.Error(w, "Not Found", http.StatusNotFound)
+		problem := newProblem(randutil.GenerateIDForPocketBase(), r.URL.Path, http.StatusNotFound, ErrorKindNoRoute, "")
+		writeProblem(w, Route{}, problem)
 		return
 	}
 
-	destURL, err := url.Parse(route.OriginURL)
-	if err != nil {
-		http.Error(w, "Gateway Error: failed to parse destination URL", http.StatusInternalServerError)
-		return
-	}
+	g.serveRoute(w, r, route, requestIP, nil)
+}
 
+// serveRoute handles a request for the given route and returns the ID
+// assigned to the request. Replayed requests skip the access checks, the
+// response cache, coalescing and mirroring so they always reach the origin.
+func (g *Gateway) serveRoute(
+	w http.ResponseWriter,
+	r *http.Request,
+	route Route,
+	requestIP string,
+	replay *replayInfo,
+) string {
+	var err error
 	requestID := randutil.GenerateIDForPocketBase()
 	startTime := time.Now()
+	annotateServerSpan(r, route, requestID)
+
+	var hit *hitTracker
+	if replay == nil && (g.hitRecorder != nil || g.metrics != nil) {
+		w, hit = g.trackHit(w, r, route, startTime)
+		defer g.recordHit(hit)
+	}
+
+	if replay == nil {
+		blockReason, err := checkIPAccess(requestIP, route)
+		if err != nil {
+			g.serveError(w, r, route, requestID, requestIP, startTime, false, "failed to check IP access")
+			return requestID
+		}
+		if blockReason != "" {
+			status, message := ipBlockResponse(route)
+			g.serveBlocked(w, r, route, requestID, requestIP, startTime, blockReason, status, message, 0)
+			return requestID
+		}
+	}
+
+	limits := g.routeRequestLimits(route)
+	if blockReason := checkHeaderLimits(r.Header, limits); blockReason != "" {
+		status := http.StatusRequestHeaderFieldsTooLarge
+		g.serveBlocked(w, r, route, requestID, requestIP, startTime, blockReason, status, http.StatusText(status), 0)
+		return requestID
+	}
+	if limits.MaxBodyBytes > 0 && r.ContentLength > limits.MaxBodyBytes {
+		g.serveBodyTooLarge(w, r, route, requestID, requestIP, startTime, limits.MaxBodyBytes)
+		return requestID
+	}
+	if limits.MaxBodyBytes > 0 && r.Body != nil {
+		// enforced while reading so bodies of unknown length are never
+		// buffered past the limit
+		r.Body = http.MaxBytesReader(w, r.Body, limits.MaxBodyBytes)
+	}
+
+	destURL := &url.URL{}
+	if route.Type != RouteTypeMock {
+		destURL, err = url.Parse(route.OriginURL)
+		if err != nil || route.OriginURL == "" {
+			g.serveError(w, r, route, requestID, requestIP, startTime, false, "failed to parse destination URL")
+			return requestID
+		}
+	}
 
 	var reqBody bytes.Buffer
 	r.Body, err = readAndRestoreBody(r.Body, &reqBody)
 	if err != nil {
-		http.Error(w, "Gateway Error: failed to read request body", http.StatusInternalServerError)
-		return
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			g.serveBodyTooLarge(w, r, route, requestID, requestIP, startTime, maxBytesErr.Limit)
+			return requestID
+		}
+		g.serveError(w, r, route, requestID, requestIP, startTime, false, "failed to read request body")
+		return requestID
+	}
+
+	originPath := contractOriginPath(destURL.Path, r.URL.Path, route.Endpoint)
+	var contractFindings []ContractFinding
+	if route.validatesContract() {
+		contractFindings = route.ContractValidator.ValidateRequest(r, originPath, reqBody.Bytes())
+		if route.ContractMode == ContractModeEnforce && len(contractFindings) > 0 {
+			g.serveContractViolation(w, r, route, requestID, requestIP, startTime, reqBody.Bytes(), contractFindings, replay)
+			return requestID
+		}
+	}
+
+	if route.CompressResponses {
+		compressor := newCompressWriter(w, r, route)
+		defer compressor.Close()
+		w = compressor
 	}
 
 	requestGatewayURL, requestOriginURL := getRequestURL(r, route)
@@ -130,31 +356,301 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		RequestOriginURL:  requestOriginURL,
 		RequestHeaders:    cloneHeaderMap(r.Header),
 		RequestBody:       bytes.NewReader(reqBody.Bytes()),
+		RequestBodySize:   int64(reqBody.Len()),
+		ReplayOf:          replay.originalRequestID(),
+		ReplayBatch:       replay.batchID(),
+		TraceID:           requestTraceID(r),
+		ContractFindings:  contractFindings,
 	})
 
+	if route.Type == RouteTypeMock {
+		g.serveMock(w, r, route, requestID, startTime, reqBody.Bytes())
+		return requestID
+	}
+
+	if replay == nil && route.MirrorURL != "" && shouldMirror(route.MirrorPercent) {
+		method := r.Method
+		mirrorPath := gatewayToOriginPath(r.URL.Path, route.Endpoint)
+		rawQuery := r.URL.RawQuery
+		header := r.Header.Clone()
+		body := bytes.Clone(reqBody.Bytes())
+		g.startMirror(func() {
+			g.mirrorRequest(route, requestID, method, mirrorPath, rawQuery, header, body)
+		})
+	}
+
+	cacheKey := ""
+	var cached *cachedResponse
+	if replay == nil && route.CacheEnabled && g.responseCache != nil && isCacheableRequest(r) {
+		cacheKey = responseCacheKey(r, route.ID, route.CacheVaryHeaders)
+		cached, _ = g.getCachedResponse(cacheKey)
+	}
+
+	if cached != nil && cached.isFresh(time.Now()) && !requestForcesRevalidation(r) {
+		customWriter := newResponseWriter(w)
+		writeCachedResponse(customWriter, r, cached, time.Now())
+
+		g.logStorer.StoreResponseLog(ResponseLog{
+			RouteID:         route.ID,
+			Timestamp:       time.Now(),
+			Duration:        time.Since(startTime),
+			RequestID:       requestID,
+			StatusCode:      customWriter.getStatusCode(),
+			ResponseHeaders: cloneHeaderMap(w.Header()),
+			ResponseBody:    bytes.NewReader(customWriter.getBody()),
+			CacheHit:        true,
+			ClientEncoding:  clientEncoding(w),
+		})
+		return requestID
+	}
+
+	coalesceKey := ""
+	var flight *inflightRequest
+	if replay == nil && route.CoalesceRequests && r.Method == http.MethodGet {
+		varyHeaders := slices.Concat(coalescedRequestHeaders, route.CacheVaryHeaders)
+		coalesceKey = responseCacheKey(r, route.ID, varyHeaders)
+
+		var isLeader bool
+		flight, isLeader = g.coalescer.join(coalesceKey, requestID, varyHeaders)
+		if !isLeader {
+			if g.serveCoalesced(w, r, route, requestID, startTime, flight) {
+				return requestID
+			}
+			flight = nil
+		}
+	}
+	if flight != nil {
+		// Release the followers if the leader can't finish normally
+		defer g.coalescer.finish(coalesceKey, flight, false, 0, nil, nil)
+	}
+
 	proxy := httputil.NewSingleHostReverseProxy(destURL)
 
 	// Configure TLS if CertPEM is available
 	if route.TLSClientCert != "" {
 		tlsConfig, err := configureTLS(route.TLSClientCert, route.TLSClientKey, route.TLSCaCert, route.TLSSkipCertVerify)
 		if err != nil {
-			http.Error(w, "Gateway Error: failed to configure TLS", http.StatusInternalServerError)
-			return
+			g.serveError(w, r, route, requestID, requestIP, startTime, true, "failed to configure TLS")
+			return requestID
 		}
 		proxy.Transport = &http.Transport{TLSClientConfig: tlsConfig}
 	}
+	proxy.Transport = g.traceTransport(proxy.Transport)
+
+	// Revalidate stale cached responses with the origin instead of refetching them
+	clientIfNoneMatch := r.Header.Get("If-None-Match")
+	revalidating := cached != nil && cached.etag() != ""
+	if revalidating {
+		r.Header.Set("If-None-Match", cached.etag())
+	}
+
+	cacheHit := false
+	var toCache *cachedResponse
+	if cacheKey != "" {
+		proxy.ModifyResponse = func(resp *http.Response) error {
+			now := time.Now()
+
+			if revalidating && resp.StatusCode == http.StatusNotModified {
+				refreshed := revalidatedCachedResponse(cached, resp.Header, now, route.CacheTTL)
+				g.setCachedResponse(cacheKey, refreshed)
+				replaceResponseWithCached(resp, clientIfNoneMatch, refreshed, now)
+				cacheHit = true
+				return nil
+			}
+
+			if r.Method != http.MethodGet || !varyCoveredBy(resp.Header, route.CacheVaryHeaders) {
+				return nil
+			}
+
+			freshness, ok := responseFreshness(resp.StatusCode, resp.Header, now, route.CacheTTL)
+			if !ok {
+				return nil
+			}
+
+			toCache = &cachedResponse{
+				StatusCode: resp.StatusCode,
+				Header:     resp.Header.Clone(),
+				StoredAt:   now,
+				InitialAge: responseInitialAge(resp.Header),
+				Freshness:  freshness,
+			}
+			return nil
+		}
+	}
+
+	// Answer origin failures with a problem instead of the bare default 502
+	// and keep the failure for the response log
+	errorKind := ""
+	var upstreamErr *UpstreamError
+	gatewayPath := r.URL.Path
+	proxy.ErrorHandler = func(rw http.ResponseWriter, _ *http.Request, err error) {
+		upstreamErr = &UpstreamError{
+			Class:   classifyUpstreamError(r.Context(), err),
+			Message: err.Error(),
+		}
+
+		if hit != nil {
+			hit.upstreamErrorClass = upstreamErr.Class
+		}
+
+		var status int
+		errorKind, status = upstreamErrorResponse(upstreamErr.Class)
+		writeProblem(rw, route, newProblem(requestID, gatewayPath, status, errorKind, ""))
+	}
+
+	trace := newUpstreamTrace()
+	if route.ServerTiming {
+		modifyResponse := proxy.ModifyResponse
+		proxy.ModifyResponse = func(resp *http.Response) error {
+			if modifyResponse != nil {
+				if err := modifyResponse(resp); err != nil {
+					return err
+				}
+			}
+			resp.Header.Add("Server-Timing", trace.timings(time.Now()).serverTiming())
+			return nil
+		}
+	}
 
 	customWriter := newResponseWriter(w)
 	r.URL.Path = gatewayToOriginPath(r.URL.Path, route.Endpoint)
 	r.Host = destURL.Host
-	proxy.ServeHTTP(customWriter, r)
+	proxy.ServeHTTP(customWriter, r.WithContext(trace.withContext(r.Context())))
+	upstreamTimings := trace.timings(time.Now())
+
+	if toCache != nil && customWriter.getStatusCode() == toCache.StatusCode {
+		toCache.Body = bytes.Clone(customWriter.getBody())
+		contentLength := toCache.Header.Get("Content-Length")
+		if contentLength == "" || contentLength == strconv.Itoa(len(toCache.Body)) {
+			g.setCachedResponse(cacheKey, toCache)
+		}
+	}
+
+	var responseFindings []ContractFinding
+	if route.validatesContract() && route.ContractResponses {
+		responseFindings = route.ContractValidator.ValidateResponse(
+			r,
+			originPath,
+			customWriter.getStatusCode(),
+			w.Header(),
+			customWriter.getBody(),
+		)
+	}
+
+	followers := 0
+	if flight != nil {
+		followers = g.coalescer.finish(
+			coalesceKey,
+			flight,
+			true,
+			customWriter.getStatusCode(),
+			w.Header().Clone(),
+			bytes.Clone(customWriter.getBody()),
+		)
+	}
+
+	g.logStorer.StoreResponseLog(ResponseLog{
+		RouteID:          route.ID,
+		Timestamp:        time.Now(),
+		Duration:         time.Since(startTime),
+		RequestID:        requestID,
+		StatusCode:       customWriter.getStatusCode(),
+		ResponseHeaders:  cloneHeaderMap(w.Header()),
+		ResponseBody:     bytes.NewReader(customWriter.getBody()),
+		CacheHit:         cacheHit,
+		Followers:        followers,
+		ContractFindings: responseFindings,
+		ClientEncoding:   clientEncoding(w),
+		ErrorKind:        errorKind,
+		UpstreamError:    upstreamErr,
+		UpstreamTimings:  &upstreamTimings,
+	})
+
+	return requestID
+}
+
+// ipBlockResponse returns the status code and message of the requests
+// blocked by the IP filters of a route.
+func ipBlockResponse(route Route) (int, string) {
+	status := route.IPBlockStatus
+	if status < 400 || status > 599 {
+		status = http.StatusForbidden
+	}
+	message := route.IPBlockMessage
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return status, message
+}
+
+// serveBodyTooLarge rejects a request whose body exceeds the route limit and
+// logs it along with the attempted body size.
+func (g *Gateway) serveBodyTooLarge(
+	w http.ResponseWriter,
+	r *http.Request,
+	route Route,
+	requestID string,
+	requestIP string,
+	startTime time.Time,
+	maxBodyBytes int64,
+) {
+	status := http.StatusRequestEntityTooLarge
+	g.serveBlocked(
+		w,
+		r,
+		route,
+		requestID,
+		requestIP,
+		startTime,
+		blockReasonBodyTooLarge,
+		status,
+		http.StatusText(status),
+		attemptedBodySize(r.ContentLength, maxBodyBytes),
+	)
+}
+
+// serveBlocked rejects a request that didn't pass the route access checks or
+// limits and logs it along with the reason it was blocked. The body of a
+// blocked request is never stored, only its size.
+func (g *Gateway) serveBlocked(
+	w http.ResponseWriter,
+	r *http.Request,
+	route Route,
+	requestID string,
+	requestIP string,
+	startTime time.Time,
+	blockReason string,
+	status int,
+	message string,
+	bodySize int64,
+) {
+	requestGatewayURL, requestOriginURL := getRequestURL(r, route)
+	g.logStorer.StoreRequestLog(RequestLog{
+		RouteID:           route.ID,
+		Timestamp:         startTime,
+		RequestID:         requestID,
+		RequestIP:         requestIP,
+		RequestMethod:     r.Method,
+		RequestGatewayURL: requestGatewayURL,
+		RequestOriginURL:  requestOriginURL,
+		RequestHeaders:    cloneHeaderMap(r.Header),
+		RequestBody:       bytes.NewReader(nil),
+		RequestBodySize:   bodySize,
+		BlockReason:       blockReason,
+		TraceID:           requestTraceID(r),
+	})
+
+	customWriter := newResponseWriter(w)
+	writeProblem(customWriter, route, newProblem(requestID, r.URL.Path, status, ErrorKindRejected, message))
 
 	g.logStorer.StoreResponseLog(ResponseLog{
 		RouteID:         route.ID,
 		Timestamp:       time.Now(),
 		Duration:        time.Since(startTime),
 		RequestID:       requestID,
+		StatusCode:      customWriter.getStatusCode(),
 		ResponseHeaders: cloneHeaderMap(w.Header()),
 		ResponseBody:    bytes.NewReader(customWriter.getBody()),
+		ErrorKind:       ErrorKindRejected,
 	})
 }
@@ -4,37 +4,64 @@ import (
 	"errors"
 	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 )
 
-// getRequestIP extracts the client's IP address from an HTTP request.
-func getRequestIP(r *http.Request) (string, error) {
-	// Check X-Forwarded-For header
-	ips := r.Header.Get("X-Forwarded-For")
-	if ips != "" {
-		splitIps := strings.Split(ips, ",")
-		// Take the first IP (original client IP)
-		clientIP := strings.TrimSpace(splitIps[0])
-		netIP := net.ParseIP(clientIP)
-		if netIP != nil {
-			return netIP.String(), nil
-		}
+// WithTrustedProxies sets the proxies whose X-Forwarded-For header is trusted
+// to find the client IP. Without trusted proxies the client IP is always the
+// address of the peer.
+func WithTrustedProxies(trustedProxies []netip.Prefix) Option {
+	return func(g *Gateway) {
+		g.trustedProxies = trustedProxies
 	}
+}
 
-	// Fallback to RemoteAddr if no valid IP is found in X-Forwarded-For
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+// getRequestIP extracts the client's IP address from an HTTP request.
+//
+// The X-Forwarded-For header is only used when the peer is a trusted proxy,
+// the client IP is then the right-most address of the header that is not a
+// trusted proxy, since the entries on its left can be set by anyone.
+func getRequestIP(r *http.Request, trustedProxies []netip.Prefix) (string, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
 		return "", err
 	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return "", errors.New("IP not found")
+	}
+	peer = peer.Unmap()
 
-	netIP := net.ParseIP(ip)
-	if netIP != nil {
-		// Normalize loopback address for consistency
-		if netIP.IsLoopback() {
-			return "127.0.0.1", nil
+	clientIP := peer
+	if isTrustedProxy(peer, trustedProxies) {
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				// the hops on the left of an invalid one can't be trusted
+				break
+			}
+			clientIP = hop.Unmap()
+			if !isTrustedProxy(clientIP, trustedProxies) {
+				break
+			}
 		}
-		return netIP.String(), nil
 	}
 
-	return "", errors.New("IP not found")
+	// Normalize loopback address for consistency
+	if clientIP.IsLoopback() {
+		return "127.0.0.1", nil
+	}
+	return clientIP.String(), nil
+}
+
+// isTrustedProxy reports whether addr is in one of the trusted proxy ranges.
+func isTrustedProxy(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
 }
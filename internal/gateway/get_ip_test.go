@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRequestIP(t *testing.T) {
+	trustedProxies := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+	}
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		forwardedFor   []string
+		trustedProxies []netip.Prefix
+		want           string
+		wantErr        bool
+	}{
+		{
+			name:       "peer address",
+			remoteAddr: "203.0.113.7:1234",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "loopback peer",
+			remoteAddr: "[::1]:1234",
+			want:       "127.0.0.1",
+		},
+		{
+			name:         "forwarded for ignored without trusted proxies",
+			remoteAddr:   "203.0.113.7:1234",
+			forwardedFor: []string{"10.0.0.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:           "forwarded for ignored from an untrusted peer",
+			remoteAddr:     "203.0.113.7:1234",
+			forwardedFor:   []string{"10.0.0.1"},
+			trustedProxies: trustedProxies,
+			want:           "203.0.113.7",
+		},
+		{
+			name:           "right-most untrusted hop",
+			remoteAddr:     "192.0.2.1:1234",
+			forwardedFor:   []string{"10.0.0.1, 198.51.100.4, 10.1.1.1"},
+			trustedProxies: trustedProxies,
+			want:           "198.51.100.4",
+		},
+		{
+			name:           "several headers",
+			remoteAddr:     "192.0.2.1:1234",
+			forwardedFor:   []string{"198.51.100.9", "198.51.100.4"},
+			trustedProxies: trustedProxies,
+			want:           "198.51.100.4",
+		},
+		{
+			name:           "every hop trusted",
+			remoteAddr:     "192.0.2.1:1234",
+			forwardedFor:   []string{"10.0.0.2, 10.0.0.1"},
+			trustedProxies: trustedProxies,
+			want:           "10.0.0.2",
+		},
+		{
+			name:           "invalid hop",
+			remoteAddr:     "192.0.2.1:1234",
+			forwardedFor:   []string{"198.51.100.4, garbage, 10.0.0.1"},
+			trustedProxies: trustedProxies,
+			want:           "10.0.0.1",
+		},
+		{
+			name:           "trusted peer without forwarded for",
+			remoteAddr:     "192.0.2.1:1234",
+			trustedProxies: trustedProxies,
+			want:           "192.0.2.1",
+		},
+		{
+			name:       "invalid remote address",
+			remoteAddr: "invalid",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			ip, err := getRequestIP(r, tt.trustedProxies)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ip)
+		})
+	}
+}
+
+func TestGatewaySpoofedForwardedForDoesNotBypassAllowlist(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer origin.Close()
+
+	routes := &fakeRouteProvider{routes: []Route{
+		{ID: "vpn", Endpoint: "/vpn", OriginURL: origin.URL, IPAllowList: mustParseIPAccessList("10.0.0.0/8")},
+	}}
+	logStorer := &fakeLogStorer{}
+	g := NewGateway(routes, logStorer)
+
+	r := httptest.NewRequest(http.MethodGet, "/vpn", nil)
+	r.RemoteAddr = "203.0.113.7:1234"
+	r.Header.Set("X-Forwarded-For", "10.0.0.1")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	require.Len(t, logStorer.requestLogs, 1)
+	assert.Equal(t, "203.0.113.7", logStorer.requestLogs[0].RequestIP)
+	assert.Equal(t, "ip 203.0.113.7 is not in route allowlist", logStorer.requestLogs[0].BlockReason)
+}
@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// HitRecorder records every request served by a route, whether it is stored
+// or not, for the traffic statistics.
+type HitRecorder interface {
+	// RecordHit records a request served by a route.
+	RecordHit(hit Hit)
+}
+
+// Hit is the outcome of a request served by a route.
+type Hit struct {
+	RouteID            string        // Identifier of the route that served the request
+	Timestamp          time.Time     // Timestamp when the request was received
+	StatusCode         int           // Status code sent to the client, 0 if none was sent
+	Duration           time.Duration // Time taken to serve the request
+	BytesIn            int64         // Bytes of the request body read by the gateway
+	BytesOut           int64         // Bytes of the response body sent to the client
+	UpstreamErrorClass string        // Class of the failure of the request to the origin, if any
+}
+
+// Metrics observes the requests served by the routes for the gateway
+// metrics.
+type Metrics interface {
+	// RequestStarted is called when a route starts serving a request.
+	RequestStarted(route Route)
+	// RequestFinished is called once the route served the request.
+	RequestFinished(route Route, hit Hit)
+}
+
+// WithHitRecorder sets the recorder of the requests served by the routes.
+// Replayed requests are not recorded.
+func WithHitRecorder(hitRecorder HitRecorder) Option {
+	return func(g *Gateway) {
+		g.hitRecorder = hitRecorder
+	}
+}
+
+// WithMetrics sets the metrics of the requests served by the routes.
+// Replayed requests are not observed.
+func WithMetrics(metrics Metrics) Option {
+	return func(g *Gateway) {
+		g.metrics = metrics
+	}
+}
+
+// hitWriter is an http.ResponseWriter that counts the status code and the
+// body bytes sent to the client.
+type hitWriter struct {
+	http.ResponseWriter
+	statusCode int
+	bytes      int64
+}
+
+func (w *hitWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 && isFinalStatus(statusCode) {
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *hitWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter so http.ResponseController
+// can reach optional interfaces such as http.Flusher
+func (w *hitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// hitBody is a request body that counts the bytes read from it.
+type hitBody struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (b *hitBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	return n, err
+}
+
+// hitTracker counts what a route serves to a request, for the hit
+// recorder and the metrics.
+type hitTracker struct {
+	route              Route
+	startTime          time.Time
+	writer             *hitWriter
+	body               *hitBody
+	upstreamErrorClass string
+}
+
+// trackHit wraps the response writer and the request body to count what the
+// route serves and reports the start of the request to the metrics. The
+// returned tracker records the hit once the request is served.
+func (g *Gateway) trackHit(
+	w http.ResponseWriter,
+	r *http.Request,
+	route Route,
+	startTime time.Time,
+) (http.ResponseWriter, *hitTracker) {
+	t := &hitTracker{
+		route:     route,
+		startTime: startTime,
+		writer:    &hitWriter{ResponseWriter: w},
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		t.body = &hitBody{ReadCloser: r.Body}
+		r.Body = t.body
+	}
+
+	if g.metrics != nil {
+		g.metrics.RequestStarted(route)
+	}
+	return t.writer, t
+}
+
+// recordHit records the hit of the request in the hit recorder and the
+// metrics.
+func (g *Gateway) recordHit(t *hitTracker) {
+	hit := Hit{
+		RouteID:            t.route.ID,
+		Timestamp:          t.startTime,
+		StatusCode:         t.writer.statusCode,
+		Duration:           time.Since(t.startTime),
+		BytesOut:           t.writer.bytes,
+		UpstreamErrorClass: t.upstreamErrorClass,
+	}
+	if t.body != nil {
+		hit.BytesIn = t.body.bytes
+	}
+
+	if g.hitRecorder != nil {
+		g.hitRecorder.RecordHit(hit)
+	}
+	if g.metrics != nil {
+		g.metrics.RequestFinished(t.route, hit)
+	}
+}
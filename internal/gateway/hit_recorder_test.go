@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewayRecordsHits(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer origin.Close()
+
+	recorder := &fakeHitRecorder{}
+	routes := &fakeRouteProvider{routes: []Route{
+		{ID: "proxy", Endpoint: "/proxy", OriginURL: origin.URL},
+		{ID: "blocked", Endpoint: "/blocked", OriginURL: origin.URL, IPDenyList: mustParseIPAccessList("0.0.0.0/0")},
+	}}
+	g := NewGateway(routes, &fakeLogStorer{}, WithHitRecorder(recorder))
+
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/proxy", strings.NewReader("ping")))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/proxy/missing", nil))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/blocked", nil))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	_, err := g.Replay(context.Background(), ReplayRequest{
+		RouteID:           "proxy",
+		RequestID:         "original",
+		Method:            http.MethodGet,
+		RequestGatewayURL: "http://gateway/proxy",
+		Header:            http.Header{},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, recorder.hits, 3, "requests without a route and replays are not recorded")
+
+	assert.Equal(t, "proxy", recorder.hits[0].RouteID)
+	assert.Equal(t, http.StatusOK, recorder.hits[0].StatusCode)
+	assert.Equal(t, int64(4), recorder.hits[0].BytesIn)
+	assert.Equal(t, int64(5), recorder.hits[0].BytesOut)
+	assert.Positive(t, recorder.hits[0].Duration)
+	assert.WithinDuration(t, time.Now(), recorder.hits[0].Timestamp, time.Second)
+
+	assert.Equal(t, http.StatusNotFound, recorder.hits[1].StatusCode)
+	assert.Equal(t, int64(0), recorder.hits[1].BytesIn)
+
+	assert.Equal(t, "blocked", recorder.hits[2].RouteID)
+	assert.Equal(t, http.StatusForbidden, recorder.hits[2].StatusCode)
+}
+
+func TestGatewayMetrics(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer origin.Close()
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	metrics := &fakeMetrics{}
+	routes := &fakeRouteProvider{routes: []Route{
+		{ID: "proxy", Endpoint: "/proxy", OriginURL: origin.URL},
+		{ID: "down", Endpoint: "/down", OriginURL: unreachable.URL},
+	}}
+	g := NewGateway(routes, &fakeLogStorer{}, WithMetrics(metrics))
+
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/proxy", nil))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/down", nil))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	assert.Equal(t, []string{"proxy", "down"}, metrics.started)
+	assert.Equal(t, 0, metrics.inFlight)
+	require.Len(t, metrics.finished, 2)
+
+	assert.Equal(t, http.StatusOK, metrics.finished[0].StatusCode)
+	assert.Empty(t, metrics.finished[0].UpstreamErrorClass)
+
+	assert.Equal(t, http.StatusBadGateway, metrics.finished[1].StatusCode)
+	assert.Equal(t, UpstreamErrorConnectionRefused, metrics.finished[1].UpstreamErrorClass)
+}
+
+func TestHitWriterStatusCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		writeFn    func(w *hitWriter)
+		wantStatus int
+	}{
+		{
+			name: "implicit status on write",
+			writeFn: func(w *hitWriter) {
+				_, _ = w.Write([]byte("ok"))
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "informational status before the final one",
+			writeFn: func(w *hitWriter) {
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "switching protocols is final",
+			writeFn: func(w *hitWriter) {
+				w.WriteHeader(http.StatusSwitchingProtocols)
+			},
+			wantStatus: http.StatusSwitchingProtocols,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &hitWriter{ResponseWriter: httptest.NewRecorder()}
+
+			tt.writeFn(writer)
+
+			assert.Equal(t, tt.wantStatus, writer.statusCode)
+		})
+	}
+}
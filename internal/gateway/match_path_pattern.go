@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"strings"
+
+	"github.com/uforg/ufogateway/internal/util/strutil"
+)
+
+// matchPathPattern matches a path against a pattern such as /users/{id}
+// and returns the values of the named segments.
+//
+// Segments are compared one by one, a segment between braces matches any
+// value. Leading and trailing slashes are ignored. It returns false if the
+// path doesn't match the pattern.
+func matchPathPattern(pattern string, path string) (map[string]string, bool) {
+	params := map[string]string{}
+
+	patternSegments := splitPathSegments(pattern)
+	pathSegments := splitPathSegments(path)
+	if len(patternSegments) != len(pathSegments) {
+		return params, false
+	}
+
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = pathSegments[i]
+			continue
+		}
+
+		if segment != pathSegments[i] {
+			return map[string]string{}, false
+		}
+	}
+
+	return params, true
+}
+
+// splitPathSegments splits a path into its segments ignoring leading and
+// trailing slashes.
+func splitPathSegments(path string) []string {
+	path = strutil.RemoveAllLeadingSlashes(strutil.RemoveAllTrailingSlashes(path))
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPathPattern(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    string
+		path       string
+		wantParams map[string]string
+		wantMatch  bool
+	}{
+		{
+			name:       "empty pattern and path",
+			pattern:    "",
+			path:       "",
+			wantParams: map[string]string{},
+			wantMatch:  true,
+		},
+		{
+			name:       "static match",
+			pattern:    "/users",
+			path:       "users/",
+			wantParams: map[string]string{},
+			wantMatch:  true,
+		},
+		{
+			name:       "single param",
+			pattern:    "/users/{id}",
+			path:       "/users/42",
+			wantParams: map[string]string{"id": "42"},
+			wantMatch:  true,
+		},
+		{
+			name:       "multiple params",
+			pattern:    "{user}/posts/{post}",
+			path:       "7/posts/abc",
+			wantParams: map[string]string{"user": "7", "post": "abc"},
+			wantMatch:  true,
+		},
+		{
+			name:       "static segment mismatch",
+			pattern:    "/users/{id}",
+			path:       "/posts/42",
+			wantParams: map[string]string{},
+			wantMatch:  false,
+		},
+		{
+			name:       "different segment count",
+			pattern:    "/users/{id}",
+			path:       "/users/42/posts",
+			wantParams: map[string]string{},
+			wantMatch:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, match := matchPathPattern(tt.pattern, tt.path)
+			assert.Equal(t, tt.wantMatch, match)
+			assert.Equal(t, tt.wantParams, params)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/uforg/ufogateway/internal/util/strutil"
+)
+
+const (
+	// mirrorTimeout is the maximum time a mirrored request can take.
+	mirrorTimeout = 30 * time.Second
+	// maxConcurrentMirrors is the maximum number of mirrored requests in
+	// flight, requests are not mirrored while all the slots are taken.
+	maxConcurrentMirrors = 100
+	// maxMirrorResponseBytes is the maximum size of the shadow response body
+	// read by the gateway, the rest of the body is discarded.
+	maxMirrorResponseBytes = 1 << 20
+)
+
+// hopByHopHeaders are the headers that only apply to a single connection
+// and must not be forwarded.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// shouldMirror decides if a request must be mirrored given the percentage
+// of requests to mirror, from 0 to 100.
+func shouldMirror(percent float64) bool {
+	if percent <= 0 {
+		return false
+	}
+	if percent >= 100 {
+		return true
+	}
+	return rand.Float64()*100 < percent
+}
+
+// buildMirrorURL returns the URL of the shadow origin for a request with the
+// given path relative to the route endpoint and raw query.
+func buildMirrorURL(mirrorURL string, originPath string, rawQuery string) (string, error) {
+	u, err := url.Parse(mirrorURL)
+	if err != nil {
+		return "", err
+	}
+
+	if originPath != "" {
+		u.Path = strutil.RemoveAllTrailingSlashes(u.Path) + "/" + strutil.RemoveAllLeadingSlashes(originPath)
+	}
+	if rawQuery != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&" + rawQuery
+		} else {
+			u.RawQuery = rawQuery
+		}
+	}
+
+	return u.String(), nil
+}
+
+// startMirror runs mirror in its own goroutine if one of the mirror slots is
+// free and reports whether it did. Mirrors are dropped rather than queued so
+// a slow shadow origin never holds up the gateway.
+func (g *Gateway) startMirror(mirror func()) bool {
+	select {
+	case g.mirrorSlots <- struct{}{}:
+	default:
+		return false
+	}
+
+	go func() {
+		defer func() { <-g.mirrorSlots }()
+		mirror()
+	}()
+	return true
+}
+
+// mirrorRequest sends a copy of a request to the shadow origin of the route
+// and logs its response. The response is never sent to the client.
+//
+// It is meant to run in its own goroutine and doesn't depend on the client
+// request context, so the mirror completes even if the client is gone. Only
+// the first maxMirrorResponseBytes of the shadow response body are kept.
+func (g *Gateway) mirrorRequest(
+	route Route,
+	requestID string,
+	method string,
+	originPath string,
+	rawQuery string,
+	header http.Header,
+	body []byte,
+) {
+	startTime := time.Now()
+	mirrorLog := MirrorLog{
+		RouteID:   route.ID,
+		RequestID: requestID,
+		Timestamp: startTime,
+	}
+
+	resp, err := func() (*http.Response, error) {
+		mirrorURL, err := buildMirrorURL(route.MirrorURL, originPath, rawQuery)
+		if err != nil {
+			return nil, err
+		}
+		mirrorLog.MirrorURL = mirrorURL
+
+		ctx, cancel := context.WithTimeout(context.Background(), mirrorTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, method, mirrorURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header = header.Clone()
+		for _, name := range hopByHopHeaders {
+			req.Header.Del(name)
+		}
+
+		resp, err := g.mirrorClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxMirrorResponseBytes))
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+		return resp, nil
+	}()
+
+	mirrorLog.Duration = time.Since(startTime)
+	if err != nil {
+		mirrorLog.Error = err.Error()
+		mirrorLog.ResponseBody = bytes.NewReader(nil)
+	} else {
+		mirrorLog.StatusCode = resp.StatusCode
+		mirrorLog.ResponseHeaders = cloneHeaderMap(resp.Header)
+		mirrorLog.ResponseBody = resp.Body
+	}
+
+	g.logStorer.StoreMirrorLog(mirrorLog)
+}
@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldMirror(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		assert.False(t, shouldMirror(0))
+		assert.False(t, shouldMirror(-5))
+		assert.True(t, shouldMirror(100))
+		assert.True(t, shouldMirror(150))
+	}
+}
+
+func TestBuildMirrorURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		mirrorURL  string
+		originPath string
+		rawQuery   string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:      "base URL only",
+			mirrorURL: "http://shadow:8080",
+			want:      "http://shadow:8080",
+		},
+		{
+			name:       "path and query",
+			mirrorURL:  "http://shadow:8080/v2/",
+			originPath: "users/42",
+			rawQuery:   "expand=true",
+			want:       "http://shadow:8080/v2/users/42?expand=true",
+		},
+		{
+			name:      "merge queries",
+			mirrorURL: "http://shadow:8080?shadow=1",
+			rawQuery:  "a=b",
+			want:      "http://shadow:8080?shadow=1&a=b",
+		},
+		{
+			name:      "invalid URL",
+			mirrorURL: "://bad",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildMirrorURL(tt.mirrorURL, tt.originPath, tt.rawQuery)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMirrorRequest(t *testing.T) {
+	var gotMethod, gotPath, gotBody, gotHeader, gotConnection string
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotBody = r.Method, r.URL.RequestURI(), string(body)
+		gotHeader, gotConnection = r.Header.Get("X-Test"), r.Header.Get("Proxy-Connection")
+		w.Header().Set("X-Shadow", "yes")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("shadow response"))
+	}))
+	defer shadow.Close()
+
+	t.Run("successful mirror", func(t *testing.T) {
+		storer := &fakeLogStorer{}
+		g := NewGateway(nil, storer)
+
+		header := http.Header{"X-Test": {"1"}, "Proxy-Connection": {"keep-alive"}}
+		route := Route{ID: "route1", MirrorURL: shadow.URL}
+		g.mirrorRequest(route, "req1", http.MethodPost, "items", "q=1", header, []byte("payload"))
+
+		assert.Equal(t, http.MethodPost, gotMethod)
+		assert.Equal(t, "/items?q=1", gotPath)
+		assert.Equal(t, "payload", gotBody)
+		assert.Equal(t, "1", gotHeader)
+		assert.Empty(t, gotConnection)
+
+		require.Len(t, storer.mirrorLogs, 1)
+		mirrorLog := storer.mirrorLogs[0]
+		assert.Equal(t, "route1", mirrorLog.RouteID)
+		assert.Equal(t, "req1", mirrorLog.RequestID)
+		assert.Equal(t, shadow.URL+"/items?q=1", mirrorLog.MirrorURL)
+		assert.Equal(t, http.StatusAccepted, mirrorLog.StatusCode)
+		assert.Equal(t, []string{"yes"}, mirrorLog.ResponseHeaders["X-Shadow"])
+		assert.Empty(t, mirrorLog.Error)
+
+		body, _ := io.ReadAll(mirrorLog.ResponseBody)
+		assert.Equal(t, "shadow response", string(body))
+	})
+
+	t.Run("unreachable shadow", func(t *testing.T) {
+		storer := &fakeLogStorer{}
+		g := NewGateway(nil, storer)
+
+		route := Route{ID: "route1", MirrorURL: "http://127.0.0.1:1"}
+		g.mirrorRequest(route, "req2", http.MethodGet, "", "", http.Header{}, nil)
+
+		require.Len(t, storer.mirrorLogs, 1)
+		assert.Equal(t, 0, storer.mirrorLogs[0].StatusCode)
+		assert.NotEmpty(t, storer.mirrorLogs[0].Error)
+	})
+}
+
+func TestMirrorRequestLimitsResponseBody(t *testing.T) {
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("a"), maxMirrorResponseBytes+100))
+	}))
+	defer shadow.Close()
+
+	storer := &fakeLogStorer{}
+	g := NewGateway(nil, storer)
+	g.mirrorRequest(Route{ID: "route1", MirrorURL: shadow.URL}, "req1", http.MethodGet, "", "", http.Header{}, nil)
+
+	require.Len(t, storer.mirrorLogs, 1)
+	body, _ := io.ReadAll(storer.mirrorLogs[0].ResponseBody)
+	assert.Len(t, body, maxMirrorResponseBytes)
+}
+
+func TestStartMirrorDropsWhenFull(t *testing.T) {
+	g := NewGateway(nil, &fakeLogStorer{})
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(maxConcurrentMirrors)
+	for i := 0; i < maxConcurrentMirrors; i++ {
+		started := g.startMirror(func() {
+			defer wg.Done()
+			<-release
+		})
+		require.True(t, started)
+	}
+
+	assert.False(t, g.startMirror(func() { t.Error("mirror started while all the slots were taken") }))
+
+	close(release)
+	wg.Wait()
+
+	done := make(chan struct{})
+	assert.Eventually(t, func() bool {
+		return g.startMirror(func() { close(done) })
+	}, time.Second, 10*time.Millisecond)
+	<-done
+}
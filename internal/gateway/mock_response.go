@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// mockTemplateData is the data available to the templates of mock responses.
+type mockTemplateData struct {
+	RequestID string            // is the unique identifier of the request
+	Method    string            // is the HTTP method of the request
+	Path      string            // is the path of the request relative to the route endpoint
+	Params    map[string]string // are the named segments matched by the route path pattern
+	Query     url.Values        // are the query values of the request
+	Headers   http.Header       // are the headers of the request
+	Body      string            // is the body of the request
+}
+
+// mockResponse is a rendered mock response ready to be written to the client.
+type mockResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// renderMockResponse renders the status, headers and body configured in a
+// mock route using the given request data.
+//
+// The body and the header values are Go templates, besides the data fields
+// they can use the param, query and header functions to read single values
+// and the json function to encode any value as JSON.
+func renderMockResponse(route Route, data mockTemplateData) (mockResponse, error) {
+	funcs := template.FuncMap{
+		"param":  func(name string) string { return data.Params[name] },
+		"query":  func(name string) string { return data.Query.Get(name) },
+		"header": func(name string) string { return data.Headers.Get(name) },
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+
+	render := func(name string, text string) (string, error) {
+		if !strings.Contains(text, "{{") {
+			return text, nil
+		}
+
+		tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return "", err
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	header := http.Header{}
+	for name, values := range route.MockHeaders {
+		for _, value := range values {
+			rendered, err := render("header "+name, value)
+			if err != nil {
+				return mockResponse{}, err
+			}
+			header.Add(name, rendered)
+		}
+	}
+
+	body, err := render("body", route.MockBody)
+	if err != nil {
+		return mockResponse{}, err
+	}
+
+	statusCode := route.MockStatus
+	if statusCode < 100 || statusCode > 599 {
+		statusCode = http.StatusOK
+	}
+
+	return mockResponse{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       []byte(body),
+	}, nil
+}
+
+// serveMock answers a request to a mock route with its configured response
+// after the configured latency, without contacting any origin.
+func (g *Gateway) serveMock(
+	w http.ResponseWriter,
+	r *http.Request,
+	route Route,
+	requestID string,
+	startTime time.Time,
+	reqBody []byte,
+) {
+	path := gatewayToOriginPath(r.URL.Path, route.Endpoint)
+	params, _ := matchPathPattern(route.MockPathPattern, path)
+
+	resp, err := renderMockResponse(route, mockTemplateData{
+		RequestID: requestID,
+		Method:    r.Method,
+		Path:      path,
+		Params:    params,
+		Query:     r.URL.Query(),
+		Headers:   r.Header,
+		Body:      string(reqBody),
+	})
+
+	customWriter := newResponseWriter(w)
+	errorKind := ""
+	if err != nil {
+		errorKind = ErrorKindInternal
+		problem := newProblem(requestID, r.URL.Path, http.StatusInternalServerError, errorKind, "failed to render mock response")
+		writeProblem(customWriter, route, problem)
+	} else {
+		if route.MockLatency > 0 {
+			select {
+			case <-time.After(route.MockLatency):
+			case <-r.Context().Done():
+			}
+		}
+
+		for k, vv := range resp.Header {
+			w.Header()[k] = vv
+		}
+
+		// Responses to HEAD requests and some status codes can't have a body
+		bodyAllowed := resp.StatusCode >= 200 &&
+			resp.StatusCode != http.StatusNoContent &&
+			resp.StatusCode != http.StatusNotModified
+		if bodyAllowed {
+			w.Header().Set("Content-Length", strconv.Itoa(len(resp.Body)))
+		}
+		customWriter.WriteHeader(resp.StatusCode)
+		if bodyAllowed && r.Method != http.MethodHead {
+			_, _ = customWriter.Write(resp.Body)
+		}
+	}
+
+	g.logStorer.StoreResponseLog(ResponseLog{
+		RouteID:         route.ID,
+		Timestamp:       time.Now(),
+		Duration:        time.Since(startTime),
+		RequestID:       requestID,
+		StatusCode:      customWriter.getStatusCode(),
+		ResponseHeaders: cloneHeaderMap(w.Header()),
+		ResponseBody:    bytes.NewReader(customWriter.getBody()),
+		ClientEncoding:  clientEncoding(w),
+		ErrorKind:       errorKind,
+	})
+}
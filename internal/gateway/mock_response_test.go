@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMockResponse(t *testing.T) {
+	data := mockTemplateData{
+		RequestID: "req123",
+		Method:    http.MethodGet,
+		Path:      "users/42",
+		Params:    map[string]string{"id": "42"},
+		Query:     url.Values{"lang": {"es"}},
+		Headers:   http.Header{"X-Tenant": {"acme"}},
+		Body:      `{"name":"Jane"}`,
+	}
+
+	tests := []struct {
+		name       string
+		route      Route
+		wantStatus int
+		wantHeader http.Header
+		wantBody   string
+		wantErr    bool
+	}{
+		{
+			name:       "static response with default status",
+			route:      Route{MockBody: `{"ok":true}`},
+			wantStatus: http.StatusOK,
+			wantHeader: http.Header{},
+			wantBody:   `{"ok":true}`,
+		},
+		{
+			name: "templated body and headers",
+			route: Route{
+				MockStatus: http.StatusCreated,
+				MockHeaders: map[string][]string{
+					"Content-Type": {"application/json"},
+					"X-Request":    {"{{ .RequestID }}"},
+				},
+				MockBody: `{"id":{{ param "id" | json }},"lang":"{{ query "lang" }}","tenant":"{{ header "x-tenant" }}","method":"{{ .Method }}"}`,
+			},
+			wantStatus: http.StatusCreated,
+			wantHeader: http.Header{
+				"Content-Type": {"application/json"},
+				"X-Request":    {"req123"},
+			},
+			wantBody: `{"id":"42","lang":"es","tenant":"acme","method":"GET"}`,
+		},
+		{
+			name:       "invalid status falls back to the default",
+			route:      Route{MockStatus: 42},
+			wantStatus: http.StatusOK,
+			wantHeader: http.Header{},
+		},
+		{
+			name:       "echo request body",
+			route:      Route{MockBody: `{"received":{{ .Body }}}`},
+			wantStatus: http.StatusOK,
+			wantHeader: http.Header{},
+			wantBody:   `{"received":{"name":"Jane"}}`,
+		},
+		{
+			name:       "missing values render empty",
+			route:      Route{MockBody: `[{{ param "missing" }}{{ query "missing" }}]`},
+			wantStatus: http.StatusOK,
+			wantHeader: http.Header{},
+			wantBody:   `[]`,
+		},
+		{
+			name:    "invalid template",
+			route:   Route{MockBody: `{{ .Broken `},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := renderMockResponse(tt.route, data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantHeader, resp.Header)
+			assert.Equal(t, tt.wantBody, string(resp.Body))
+		})
+	}
+}
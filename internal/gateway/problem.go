@@ -0,0 +1,187 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// Error kinds of the responses the gateway answers by itself.
+const (
+	ErrorKindNoRoute         = "no_route"            // no route matches the request path
+	ErrorKindRejected        = "rejected_by_policy"  // the request was blocked by the route policies
+	ErrorKindUpstreamDial    = "upstream_dial_error" // the origin could not be reached
+	ErrorKindUpstreamTLS     = "upstream_tls_error"  // the TLS connection with the origin failed
+	ErrorKindUpstreamTimeout = "upstream_timeout"    // the origin did not respond in time
+	ErrorKindUpstream        = "upstream_error"      // the origin request failed for another reason
+	ErrorKindClientCancelled = "client_cancelled"    // the client went away before the origin responded
+	ErrorKindInternal        = "gateway_error"       // the gateway failed to handle the request
+)
+
+// problemContentType is the media type of RFC 9457 problem details.
+const problemContentType = "application/problem+json"
+
+// errorKindTitles are the short summaries of the error kinds.
+var errorKindTitles = map[string]string{
+	ErrorKindNoRoute:         "No route matches the request",
+	ErrorKindRejected:        "Request rejected by the gateway",
+	ErrorKindUpstreamDial:    "Origin unreachable",
+	ErrorKindUpstreamTLS:     "Origin TLS connection failed",
+	ErrorKindUpstreamTimeout: "Origin timed out",
+	ErrorKindUpstream:        "Origin request failed",
+	ErrorKindClientCancelled: "Client closed the request",
+	ErrorKindInternal:        "Gateway error",
+}
+
+// Problem is an RFC 9457 problem details object describing an error answered
+// by the gateway, extended with the request ID and the error kind.
+type Problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	RequestID string         `json:"request_id"`
+	Code      string         `json:"code"`
+	Extra     map[string]any `json:"-"`
+}
+
+// newProblem returns the problem of an error kind answered with status,
+// instance is the gateway path of the request.
+func newProblem(requestID string, instance string, status int, kind string, detail string) Problem {
+	title, ok := errorKindTitles[kind]
+	if !ok {
+		title = http.StatusText(status)
+	}
+
+	return Problem{
+		Type:      "urn:ufogateway:error:" + kind,
+		Title:     title,
+		Status:    status,
+		Detail:    detail,
+		Instance:  instance,
+		RequestID: requestID,
+		Code:      kind,
+	}
+}
+
+// MarshalJSON encodes the problem members along with its extension members.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	members, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extra) == 0 {
+		return members, err
+	}
+
+	merged := map[string]any{}
+	if err := json.Unmarshal(members, &merged); err != nil {
+		return nil, err
+	}
+	for key, value := range p.Extra {
+		if _, reserved := merged[key]; !reserved {
+			merged[key] = value
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// renderProblem returns the content type and body of a problem, rendered
+// with the route error template when it has one.
+//
+// The template is a Go template with the problem as data, it can use the
+// json function to encode any value as JSON, so {{json .}} renders the
+// default body. Templates that fail fall back to the default body.
+func renderProblem(route Route, problem Problem) (string, []byte) {
+	body, _ := json.Marshal(problem)
+	if route.ErrorTemplate == "" {
+		return problemContentType, body
+	}
+
+	funcs := template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+	tmpl, err := template.New("error").Funcs(funcs).Option("missingkey=zero").Parse(route.ErrorTemplate)
+	if err != nil {
+		return problemContentType, body
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, problem); err != nil {
+		return problemContentType, body
+	}
+
+	contentType := route.ErrorContentType
+	if contentType == "" {
+		contentType = problemContentType
+	}
+	return contentType, buf.Bytes()
+}
+
+// writeProblem answers the request with a problem, replacing any header
+// already set for the response.
+func writeProblem(w http.ResponseWriter, route Route, problem Problem) {
+	contentType, body := renderProblem(route, problem)
+
+	header := w.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	_, _ = w.Write(body)
+}
+
+// serveError answers a request the gateway failed to handle with a problem
+// and logs it. The request is logged too unless it already was. Requests
+// without a route can't be logged.
+func (g *Gateway) serveError(
+	w http.ResponseWriter,
+	r *http.Request,
+	route Route,
+	requestID string,
+	requestIP string,
+	startTime time.Time,
+	logged bool,
+	detail string,
+) {
+	customWriter := newResponseWriter(w)
+	writeProblem(customWriter, route, newProblem(requestID, r.URL.Path, http.StatusInternalServerError, ErrorKindInternal, detail))
+	if route.ID == "" || g.logStorer == nil {
+		return
+	}
+
+	if !logged {
+		requestGatewayURL, requestOriginURL := getRequestURL(r, route)
+		g.logStorer.StoreRequestLog(RequestLog{
+			RouteID:           route.ID,
+			Timestamp:         startTime,
+			RequestID:         requestID,
+			RequestIP:         requestIP,
+			RequestMethod:     r.Method,
+			RequestGatewayURL: requestGatewayURL,
+			RequestOriginURL:  requestOriginURL,
+			RequestHeaders:    cloneHeaderMap(r.Header),
+			RequestBody:       bytes.NewReader(nil),
+			TraceID:           requestTraceID(r),
+		})
+	}
+
+	g.logStorer.StoreResponseLog(ResponseLog{
+		RouteID:         route.ID,
+		Timestamp:       time.Now(),
+		Duration:        time.Since(startTime),
+		RequestID:       requestID,
+		StatusCode:      customWriter.getStatusCode(),
+		ResponseHeaders: cloneHeaderMap(w.Header()),
+		ResponseBody:    bytes.NewReader(customWriter.getBody()),
+		ErrorKind:       ErrorKindInternal,
+	})
+}
@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemMarshalJSON(t *testing.T) {
+	problem := newProblem("req1", "/api/users", http.StatusBadRequest, ErrorKindRejected, "denied")
+	problem.Extra = map[string]any{"findings": []string{"a"}, "code": "ignored"}
+
+	body, err := json.Marshal(problem)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "urn:ufogateway:error:rejected_by_policy",
+		"title": "Request rejected by the gateway",
+		"status": 400,
+		"detail": "denied",
+		"instance": "/api/users",
+		"request_id": "req1",
+		"code": "rejected_by_policy",
+		"findings": ["a"]
+	}`, string(body))
+}
+
+func TestRenderProblem(t *testing.T) {
+	problem := newProblem("req1", "/api", http.StatusBadGateway, ErrorKindUpstreamDial, "")
+	defaultBody, err := json.Marshal(problem)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		route           Route
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "default",
+			route:           Route{},
+			wantContentType: problemContentType,
+			wantBody:        string(defaultBody),
+		},
+		{
+			name:            "template",
+			route:           Route{ErrorTemplate: `{"error":{{json .Code}},"id":"{{.RequestID}}"}`, ErrorContentType: "application/json"},
+			wantContentType: "application/json",
+			wantBody:        `{"error":"upstream_dial_error","id":"req1"}`,
+		},
+		{
+			name:            "template with the default content type",
+			route:           Route{ErrorTemplate: `{{.Status}} {{.Title}}`},
+			wantContentType: problemContentType,
+			wantBody:        "502 Origin unreachable",
+		},
+		{
+			name:            "invalid template",
+			route:           Route{ErrorTemplate: `{{.Status`, ErrorContentType: "text/plain"},
+			wantContentType: problemContentType,
+			wantBody:        string(defaultBody),
+		},
+		{
+			name:            "failing template",
+			route:           Route{ErrorTemplate: `{{.Missing}}`, ErrorContentType: "text/plain"},
+			wantContentType: problemContentType,
+			wantBody:        string(defaultBody),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, body := renderProblem(tt.route, problem)
+			assert.Equal(t, tt.wantContentType, contentType)
+			assert.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+func TestGatewayProblems(t *testing.T) {
+	// a listener that is closed right away gives an address that refuses connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedURL := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	storer := &fakeLogStorer{}
+	routes := &fakeRouteProvider{routes: []Route{
+		{ID: "down", Endpoint: "/down", OriginURL: closedURL},
+		{ID: "blocked", Endpoint: "/blocked", OriginURL: closedURL, IPDenyList: mustParseIPAccessList("0.0.0.0/0"), IPBlockMessage: "go away"},
+		{ID: "broken", Endpoint: "/broken", OriginURL: "://bad"},
+	}}
+	g := NewGateway(routes, storer)
+
+	serve := func(path string) (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		body := map[string]any{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, problemContentType, rec.Header().Get("Content-Type"))
+		return rec, body
+	}
+
+	t.Run("no route", func(t *testing.T) {
+		rec, body := serve("/missing")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, ErrorKindNoRoute, body["code"])
+		assert.Equal(t, "/missing", body["instance"])
+		assert.NotEmpty(t, body["request_id"])
+	})
+
+	t.Run("origin down", func(t *testing.T) {
+		rec, body := serve("/down/users")
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+		assert.Equal(t, ErrorKindUpstreamDial, body["code"])
+		assert.Equal(t, "/down/users", body["instance"])
+
+		response := storer.responseLogs[len(storer.responseLogs)-1]
+		assert.Equal(t, ErrorKindUpstreamDial, response.ErrorKind)
+		assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+		assert.Equal(t, body["request_id"], response.RequestID)
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		rec, body := serve("/blocked")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, ErrorKindRejected, body["code"])
+		assert.Equal(t, "go away", body["detail"])
+		assert.Equal(t, ErrorKindRejected, storer.responseLogs[len(storer.responseLogs)-1].ErrorKind)
+	})
+
+	t.Run("gateway error", func(t *testing.T) {
+		rec, body := serve("/broken/users")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, ErrorKindInternal, body["code"])
+
+		request := storer.requestLogs[len(storer.requestLogs)-1]
+		assert.Equal(t, "broken", request.RouteID)
+		assert.Equal(t, body["request_id"], request.RequestID)
+		assert.Equal(t, http.MethodGet, request.RequestMethod)
+
+		response := storer.responseLogs[len(storer.responseLogs)-1]
+		assert.Equal(t, body["request_id"], response.RequestID)
+		assert.Equal(t, ErrorKindInternal, response.ErrorKind)
+		assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	})
+}
@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ReplayRequest represents a stored request to be sent again through the gateway.
+type ReplayRequest struct {
+	RouteID           string      // Identifier of the route that handled the original request
+	RequestID         string      // Unique identifier of the original request
+	BatchID           string      // Identifier of the batch of replays (optional)
+	Method            string      // HTTP method of the original request
+	RequestGatewayURL string      // URL of the original request to the gateway
+	TargetURL         string      // Origin URL that replaces the route origin (optional)
+	Header            http.Header // Headers to send with the request
+	Body              []byte      // Body to send with the request
+}
+
+// ReplayResult represents the outcome of a replayed request.
+type ReplayResult struct {
+	RequestID  string        // Unique identifier assigned to the replayed request
+	StatusCode int           // Status code of the response
+	Duration   time.Duration // Time taken to process the replayed request
+}
+
+// replayInfo links a replayed request to the request it replays.
+type replayInfo struct {
+	originalID string // is the ID of the replayed request
+	batch      string // is the ID of the replay batch
+}
+
+// originalRequestID returns the ID of the replayed request, it is safe to
+// call on a nil replayInfo.
+func (ri *replayInfo) originalRequestID() string {
+	if ri == nil {
+		return ""
+	}
+	return ri.originalID
+}
+
+// batchID returns the ID of the replay batch, it is safe to call on a nil
+// replayInfo.
+func (ri *replayInfo) batchID() string {
+	if ri == nil {
+		return ""
+	}
+	return ri.batch
+}
+
+// discardResponseWriter is an http.ResponseWriter that discards everything
+// written to it.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+// Replay sends a stored request again through the route that handled it, or
+// through the same route pointed to TargetURL when it is set.
+//
+// The replayed request is logged as a new request linked to the original one
+// and skips the route access checks, response cache, coalescing and mirroring.
+func (g *Gateway) Replay(ctx context.Context, replayReq ReplayRequest) (ReplayResult, error) {
+	if g.routeProvider == nil || g.logStorer == nil {
+		return ReplayResult{}, errors.New("gateway is not configured")
+	}
+
+	routes, err := g.routeProvider.Routes()
+	if err != nil {
+		return ReplayResult{}, err
+	}
+
+	var route Route
+	found := false
+	for _, r := range routes {
+		if r.ID == replayReq.RouteID {
+			route, found = r, true
+			break
+		}
+	}
+	if !found {
+		return ReplayResult{}, errors.New("route not found or not active")
+	}
+
+	if replayReq.TargetURL != "" {
+		route.Type = RouteTypeProxy
+		route.OriginURL = replayReq.TargetURL
+	}
+
+	r, err := http.NewRequestWithContext(
+		ctx,
+		replayReq.Method,
+		replayReq.RequestGatewayURL,
+		bytes.NewReader(replayReq.Body),
+	)
+	if err != nil {
+		return ReplayResult{}, err
+	}
+	if replayReq.Header != nil {
+		r.Header = replayReq.Header.Clone()
+	}
+
+	startTime := time.Now()
+	customWriter := newResponseWriter(&discardResponseWriter{header: http.Header{}})
+	requestID := g.serveRoute(customWriter, r, route, "", &replayInfo{
+		originalID: replayReq.RequestID,
+		batch:      replayReq.BatchID,
+	})
+
+	return ReplayResult{
+		RequestID:  requestID,
+		StatusCode: customWriter.getStatusCode(),
+		Duration:   time.Since(startTime),
+	}, nil
+}
@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	newOrigin := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("X-Origin", name)
+			w.Header().Set("X-Path", r.URL.RequestURI())
+			w.Header().Set("X-Token", r.Header.Get("X-Token"))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		}))
+	}
+	origin := newOrigin("primary")
+	defer origin.Close()
+	staging := newOrigin("staging")
+	defer staging.Close()
+
+	routes := &fakeRouteProvider{routes: []Route{
+		{ID: "route1", Endpoint: "/api", OriginURL: origin.URL, CacheEnabled: true},
+	}}
+
+	t.Run("replay to the route origin", func(t *testing.T) {
+		storer := &fakeLogStorer{}
+		g := NewGateway(routes, storer)
+
+		result, err := g.Replay(context.Background(), ReplayRequest{
+			RouteID:           "route1",
+			RequestID:         "original1",
+			BatchID:           "batch1",
+			Method:            http.MethodPost,
+			RequestGatewayURL: "http://gateway/api/users?page=2",
+			Header:            http.Header{"X-Token": {"abc"}},
+			Body:              []byte("payload"),
+		})
+		require.NoError(t, err)
+
+		assert.NotEmpty(t, result.RequestID)
+		assert.Equal(t, http.StatusCreated, result.StatusCode)
+
+		require.Len(t, storer.requestLogs, 1)
+		assert.Equal(t, "original1", storer.requestLogs[0].ReplayOf)
+		assert.Equal(t, "batch1", storer.requestLogs[0].ReplayBatch)
+		assert.Equal(t, result.RequestID, storer.requestLogs[0].RequestID)
+
+		require.Len(t, storer.responseLogs, 1)
+		respLog := storer.responseLogs[0]
+		assert.Equal(t, []string{"primary"}, respLog.ResponseHeaders["X-Origin"])
+		assert.Equal(t, []string{"/users?page=2"}, respLog.ResponseHeaders["X-Path"])
+		assert.Equal(t, []string{"abc"}, respLog.ResponseHeaders["X-Token"])
+		body, _ := io.ReadAll(respLog.ResponseBody)
+		assert.Equal(t, "payload", string(body))
+	})
+
+	t.Run("replay to a target URL", func(t *testing.T) {
+		storer := &fakeLogStorer{}
+		g := NewGateway(routes, storer)
+
+		result, err := g.Replay(context.Background(), ReplayRequest{
+			RouteID:           "route1",
+			RequestID:         "original1",
+			Method:            http.MethodGet,
+			RequestGatewayURL: "http://gateway/api/users",
+			TargetURL:         staging.URL,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, result.StatusCode)
+		require.Len(t, storer.responseLogs, 1)
+		assert.Equal(t, []string{"staging"}, storer.responseLogs[0].ResponseHeaders["X-Origin"])
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		g := NewGateway(routes, &fakeLogStorer{})
+
+		_, err := g.Replay(context.Background(), ReplayRequest{
+			RouteID:           "missing",
+			Method:            http.MethodGet,
+			RequestGatewayURL: "http://gateway/api",
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// coalescedRequestHeaders are the request headers that always take part in
+// the coalescing key, so requests are only grouped with others that would
+// get exactly the same response.
+var coalescedRequestHeaders = []string{
+	"Accept-Encoding",
+	"Authorization",
+	"Cookie",
+	"If-Modified-Since",
+	"If-None-Match",
+	"Range",
+}
+
+// inflightRequest is an upstream request shared by identical concurrent
+// requests. The first request (the leader) fetches the response from the
+// origin and the rest (the followers) wait for it and reuse it.
+type inflightRequest struct {
+	leaderID    string        // is the request ID of the leader
+	varyHeaders []string      // are the request headers that took part in the key
+	done        chan struct{} // is closed when the leader finishes
+	once        sync.Once     // guards the closing of done
+	ok          bool          // is true if the leader got a complete response
+	statusCode  int           // is the status code of the shared response
+	header      http.Header   // is the header of the shared response
+	body        []byte        // is the body of the shared response
+	followers   int           // is the number of requests that joined the leader
+}
+
+// requestCoalescer groups identical concurrent requests so only one of them
+// reaches the origin at a time.
+type requestCoalescer struct {
+	mu       sync.Mutex
+	inflight map[string]*inflightRequest
+}
+
+// newRequestCoalescer creates a new requestCoalescer.
+func newRequestCoalescer() *requestCoalescer {
+	return &requestCoalescer{
+		inflight: make(map[string]*inflightRequest),
+	}
+}
+
+// join returns the in-flight request for the given key and true if the
+// caller is the leader and must fetch the response from the origin. The key
+// must be built from the varyHeaders of the request.
+func (c *requestCoalescer) join(key string, requestID string, varyHeaders []string) (*inflightRequest, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if flight, found := c.inflight[key]; found {
+		flight.followers++
+		return flight, false
+	}
+
+	flight := &inflightRequest{
+		leaderID:    requestID,
+		varyHeaders: varyHeaders,
+		done:        make(chan struct{}),
+	}
+	c.inflight[key] = flight
+	return flight, true
+}
+
+// finish shares the response of the leader with its followers and returns
+// the number of followers. If ok is false, or the response can't be shared,
+// the followers must fetch the response by themselves. It is safe to call
+// finish more than once, only the first call has effect.
+func (c *requestCoalescer) finish(
+	key string,
+	flight *inflightRequest,
+	ok bool,
+	statusCode int,
+	header http.Header,
+	body []byte,
+) int {
+	flight.once.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.inflight[key] == flight {
+			delete(c.inflight, key)
+		}
+
+		flight.ok = ok && isShareableResponse(header, flight.varyHeaders)
+		flight.statusCode = statusCode
+		flight.header = header
+		flight.body = body
+		close(flight.done)
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return flight.followers
+}
+
+// isShareableResponse reports whether a response can be served to other
+// clients than the one that requested it. Like the response cache, it
+// refuses responses that set cookies, are private or no-store, or vary on
+// request headers that are not part of the key.
+func isShareableResponse(header http.Header, varyHeaders []string) bool {
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+
+	cc := parseCacheControl(header.Values("Cache-Control"))
+	if cc.has("no-store") || cc.has("private") {
+		return false
+	}
+
+	return varyCoveredBy(header, varyHeaders)
+}
+
+// serveCoalesced waits for the leader of the in-flight request and writes
+// its response to the client.
+//
+// It returns false if the leader couldn't get a complete response, in which
+// case the caller must fetch the response from the origin by itself.
+func (g *Gateway) serveCoalesced(
+	w http.ResponseWriter,
+	r *http.Request,
+	route Route,
+	requestID string,
+	startTime time.Time,
+	flight *inflightRequest,
+) bool {
+	select {
+	case <-flight.done:
+	case <-r.Context().Done():
+		g.logStorer.StoreResponseLog(ResponseLog{
+			RouteID:       route.ID,
+			Timestamp:     time.Now(),
+			Duration:      time.Since(startTime),
+			RequestID:     requestID,
+			ResponseBody:  bytes.NewReader(nil),
+			CoalescedWith: flight.leaderID,
+		})
+		return true
+	}
+
+	if !flight.ok || flight.statusCode == 0 {
+		return false
+	}
+
+	customWriter := newResponseWriter(w)
+	for k, vv := range flight.header {
+		w.Header()[k] = append([]string(nil), vv...)
+	}
+	customWriter.WriteHeader(flight.statusCode)
+	_, _ = customWriter.Write(flight.body)
+
+	g.logStorer.StoreResponseLog(ResponseLog{
+		RouteID:         route.ID,
+		Timestamp:       time.Now(),
+		Duration:        time.Since(startTime),
+		RequestID:       requestID,
+		StatusCode:      customWriter.getStatusCode(),
+		ResponseHeaders: cloneHeaderMap(w.Header()),
+		ResponseBody:    bytes.NewReader(customWriter.getBody()),
+		CoalescedWith:   flight.leaderID,
+		ClientEncoding:  clientEncoding(w),
+	})
+	return true
+}
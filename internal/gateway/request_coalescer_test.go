@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestCoalescer(t *testing.T) {
+	t.Run("followers share the leader response", func(t *testing.T) {
+		c := newRequestCoalescer()
+
+		leader, isLeader := c.join("key", "leader-id", nil)
+		assert.True(t, isLeader)
+
+		const followers = 10
+		var wg sync.WaitGroup
+		results := make(chan *inflightRequest, followers)
+		for i := 0; i < followers; i++ {
+			flight, isLeader := c.join("key", "follower-id", nil)
+			assert.False(t, isLeader)
+			assert.Equal(t, "leader-id", flight.leaderID)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-flight.done
+				results <- flight
+			}()
+		}
+
+		count := c.finish("key", leader, true, http.StatusOK, http.Header{"X-Test": {"1"}}, []byte("body"))
+		wg.Wait()
+		close(results)
+
+		assert.Equal(t, followers, count)
+		for flight := range results {
+			assert.True(t, flight.ok)
+			assert.Equal(t, http.StatusOK, flight.statusCode)
+			assert.Equal(t, "1", flight.header.Get("X-Test"))
+			assert.Equal(t, []byte("body"), flight.body)
+		}
+	})
+
+	t.Run("a new leader is elected after finishing", func(t *testing.T) {
+		c := newRequestCoalescer()
+
+		first, isLeader := c.join("key", "first", nil)
+		assert.True(t, isLeader)
+		c.finish("key", first, true, http.StatusOK, nil, nil)
+
+		second, isLeader := c.join("key", "second", nil)
+		assert.True(t, isLeader)
+		assert.NotSame(t, first, second)
+	})
+
+	t.Run("different keys do not coalesce", func(t *testing.T) {
+		c := newRequestCoalescer()
+
+		_, isLeaderA := c.join("a", "1", nil)
+		_, isLeaderB := c.join("b", "2", nil)
+		assert.True(t, isLeaderA)
+		assert.True(t, isLeaderB)
+	})
+
+	t.Run("finish is idempotent", func(t *testing.T) {
+		c := newRequestCoalescer()
+
+		flight, _ := c.join("key", "leader", nil)
+		c.finish("key", flight, true, http.StatusCreated, nil, []byte("first"))
+		c.finish("key", flight, false, http.StatusBadGateway, nil, []byte("second"))
+
+		assert.True(t, flight.ok)
+		assert.Equal(t, http.StatusCreated, flight.statusCode)
+		assert.Equal(t, []byte("first"), flight.body)
+	})
+
+	t.Run("responses that can't be shared release the followers", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			header http.Header
+		}{
+			{name: "set cookie", header: http.Header{"Set-Cookie": {"session=abc"}}},
+			{name: "private", header: http.Header{"Cache-Control": {"private, max-age=60"}}},
+			{name: "no-store", header: http.Header{"Cache-Control": {"no-store"}}},
+			{name: "vary on a header outside the key", header: http.Header{"Vary": {"Accept-Language"}}},
+			{name: "vary on everything", header: http.Header{"Vary": {"*"}}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := newRequestCoalescer()
+
+				leader, _ := c.join("key", "leader", []string{"Accept-Encoding"})
+				follower, _ := c.join("key", "follower", []string{"Accept-Encoding"})
+				c.finish("key", leader, true, http.StatusOK, tt.header, []byte("body"))
+
+				<-follower.done
+				assert.False(t, follower.ok)
+			})
+		}
+	})
+
+	t.Run("responses varying on headers of the key are shared", func(t *testing.T) {
+		c := newRequestCoalescer()
+
+		leader, _ := c.join("key", "leader", []string{"Accept-Encoding"})
+		follower, _ := c.join("key", "follower", []string{"Accept-Encoding"})
+		c.finish("key", leader, true, http.StatusOK, http.Header{"Vary": {"accept-encoding"}}, []byte("body"))
+
+		<-follower.done
+		assert.True(t, follower.ok)
+	})
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"net/http"
+)
+
+// Block reasons of the requests rejected by the request limits.
+const (
+	blockReasonBodyTooLarge    = "request body too large"
+	blockReasonHeadersTooLarge = "request headers too large"
+	blockReasonTooManyHeaders  = "too many request headers"
+)
+
+// RequestLimits are the limits on the size of incoming requests. A zero
+// value means no limit.
+type RequestLimits struct {
+	MaxBodyBytes   int64 // is the maximum size in bytes of the request body
+	MaxHeaderBytes int   // is the maximum size in bytes of the request headers
+	MaxHeaderCount int   // is the maximum number of request header lines
+}
+
+// routeRequestLimits returns the request limits of a route, each limit the
+// route doesn't set falls back to the gateway wide one.
+func (g *Gateway) routeRequestLimits(route Route) RequestLimits {
+	limits := g.requestLimits
+	if route.MaxBodyBytes > 0 {
+		limits.MaxBodyBytes = route.MaxBodyBytes
+	}
+	if route.MaxHeaderBytes > 0 {
+		limits.MaxHeaderBytes = route.MaxHeaderBytes
+	}
+	if route.MaxHeaderCount > 0 {
+		limits.MaxHeaderCount = route.MaxHeaderCount
+	}
+	return limits
+}
+
+// checkHeaderLimits returns the reason why the headers exceed the limits or
+// an empty string if they don't.
+func checkHeaderLimits(header http.Header, limits RequestLimits) string {
+	size, count := headerSize(header)
+	if limits.MaxHeaderCount > 0 && count > limits.MaxHeaderCount {
+		return blockReasonTooManyHeaders
+	}
+	if limits.MaxHeaderBytes > 0 && size > limits.MaxHeaderBytes {
+		return blockReasonHeadersTooLarge
+	}
+	return ""
+}
+
+// headerSize returns the size in bytes and the number of lines of the
+// headers, each value counted as a "Name: value\r\n" line like HTTP/1.1
+// sends it.
+func headerSize(header http.Header) (int, int) {
+	size, count := 0, 0
+	for name, values := range header {
+		for _, value := range values {
+			size += len(name) + len(value) + 4
+			count++
+		}
+	}
+	return size, count
+}
+
+// attemptedBodySize returns the size of a request body that exceeded the
+// limit. It is the declared Content-Length when there is one, otherwise the
+// body was only read up to one byte past the limit.
+func attemptedBodySize(contentLength int64, maxBodyBytes int64) int64 {
+	if contentLength > maxBodyBytes {
+		return contentLength
+	}
+	return maxBodyBytes + 1
+}
@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteRequestLimits(t *testing.T) {
+	g := NewGateway(nil, nil, WithRequestLimits(RequestLimits{
+		MaxBodyBytes:   1024,
+		MaxHeaderBytes: 512,
+		MaxHeaderCount: 10,
+	}))
+
+	tests := []struct {
+		name  string
+		route Route
+		want  RequestLimits
+	}{
+		{
+			name:  "gateway limits",
+			route: Route{},
+			want:  RequestLimits{MaxBodyBytes: 1024, MaxHeaderBytes: 512, MaxHeaderCount: 10},
+		},
+		{
+			name:  "route overrides",
+			route: Route{MaxBodyBytes: 4096, MaxHeaderCount: 5},
+			want:  RequestLimits{MaxBodyBytes: 4096, MaxHeaderBytes: 512, MaxHeaderCount: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, g.routeRequestLimits(tt.route))
+		})
+	}
+}
+
+func TestCheckHeaderLimits(t *testing.T) {
+	header := http.Header{
+		"Accept":    {"application/json"}, // 26 bytes
+		"X-Tracing": {"a", "b"},           // 14 bytes each
+	}
+
+	tests := []struct {
+		name   string
+		limits RequestLimits
+		want   string
+	}{
+		{name: "no limits", limits: RequestLimits{}, want: ""},
+		{name: "within limits", limits: RequestLimits{MaxHeaderBytes: 54, MaxHeaderCount: 3}, want: ""},
+		{name: "too large", limits: RequestLimits{MaxHeaderBytes: 53}, want: blockReasonHeadersTooLarge},
+		{name: "too many", limits: RequestLimits{MaxHeaderCount: 2}, want: blockReasonTooManyHeaders},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, checkHeaderLimits(header, tt.limits))
+		})
+	}
+}
+
+func TestAttemptedBodySize(t *testing.T) {
+	assert.Equal(t, int64(2048), attemptedBodySize(2048, 1024))
+	assert.Equal(t, int64(1025), attemptedBodySize(-1, 1024))
+	assert.Equal(t, int64(1025), attemptedBodySize(512, 1024))
+}
+
+func TestGatewayRequestLimits(t *testing.T) {
+	originHits := 0
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originHits++
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer origin.Close()
+
+	newGateway := func() (*Gateway, *fakeLogStorer) {
+		storer := &fakeLogStorer{}
+		routes := &fakeRouteProvider{routes: []Route{
+			{ID: "small", Endpoint: "/small", OriginURL: origin.URL, MaxBodyBytes: 4},
+			{ID: "default", Endpoint: "/default", OriginURL: origin.URL},
+		}}
+		return NewGateway(routes, storer, WithRequestLimits(RequestLimits{MaxBodyBytes: 8, MaxHeaderCount: 2})), storer
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		body           io.Reader
+		header         http.Header
+		wantStatus     int
+		wantOriginHits int
+		wantReason     string
+		wantSize       int64
+	}{
+		{
+			name:           "within the route limit",
+			path:           "/small",
+			body:           strings.NewReader("1234"),
+			wantStatus:     http.StatusOK,
+			wantOriginHits: 1,
+			wantSize:       4,
+		},
+		{
+			name:       "declared length over the route limit",
+			path:       "/small",
+			body:       strings.NewReader("12345"),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantReason: blockReasonBodyTooLarge,
+			wantSize:   5,
+		},
+		{
+			name:       "unknown length over the route limit",
+			path:       "/small",
+			body:       io.MultiReader(strings.NewReader("123"), strings.NewReader("456789")),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantReason: blockReasonBodyTooLarge,
+			wantSize:   5,
+		},
+		{
+			name:           "within the gateway limit",
+			path:           "/default",
+			body:           strings.NewReader("12345678"),
+			wantStatus:     http.StatusOK,
+			wantOriginHits: 1,
+			wantSize:       8,
+		},
+		{
+			name:       "over the gateway limit",
+			path:       "/default",
+			body:       strings.NewReader("123456789"),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantReason: blockReasonBodyTooLarge,
+			wantSize:   9,
+		},
+		{
+			name:       "too many headers",
+			path:       "/default",
+			header:     http.Header{"A": {"1"}, "B": {"2", "3"}},
+			wantStatus: http.StatusRequestHeaderFieldsTooLarge,
+			wantReason: blockReasonTooManyHeaders,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originHits = 0
+			g, storer := newGateway()
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, tt.body)
+			if _, ok := tt.body.(*strings.Reader); !ok {
+				req.ContentLength = -1
+			}
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			rec := httptest.NewRecorder()
+			g.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantOriginHits, originHits)
+			require.Len(t, storer.requestLogs, 1)
+			assert.Equal(t, tt.wantReason, storer.requestLogs[0].BlockReason)
+			assert.Equal(t, tt.wantSize, storer.requestLogs[0].RequestBodySize)
+			require.Len(t, storer.responseLogs, 1)
+			assert.Equal(t, tt.wantStatus, storer.responseLogs[0].StatusCode)
+		})
+	}
+}
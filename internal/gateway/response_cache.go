@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// cacheStaleRetention is how long a stale response with an ETag is kept in
+// the cache so it can be revalidated with the origin instead of refetched.
+const cacheStaleRetention = 10 * time.Minute
+
+// cachedResponse is a response stored in the gateway response cache.
+type cachedResponse struct {
+	StatusCode int           // is the status code of the response
+	Header     http.Header   // is the header of the response as sent by the origin
+	Body       []byte        // is the full body of the response
+	StoredAt   time.Time     // is the time the response was stored or revalidated
+	InitialAge time.Duration // is the age reported by the origin when stored
+	Freshness  time.Duration // is the freshness lifetime of the response, compared to its age
+}
+
+// etag returns the entity tag of the cached response.
+func (c *cachedResponse) etag() string {
+	return c.Header.Get("ETag")
+}
+
+// age returns the current age of the cached response.
+func (c *cachedResponse) age(now time.Time) time.Duration {
+	return c.InitialAge + now.Sub(c.StoredAt)
+}
+
+// isFresh returns true if the response can be served without revalidation,
+// that is while its current age is below its freshness lifetime (RFC 9111
+// section 4.2).
+func (c *cachedResponse) isFresh(now time.Time) bool {
+	return c.age(now) < c.Freshness
+}
+
+// ttl returns for how long the response should be kept in the cache since it
+// was stored.
+func (c *cachedResponse) ttl() time.Duration {
+	remaining := max(c.Freshness-c.InitialAge, 0)
+	if c.etag() != "" {
+		return remaining + cacheStaleRetention
+	}
+	return remaining
+}
+
+// size returns the approximate size in bytes of the cached response.
+func (c *cachedResponse) size() int64 {
+	size := int64(len(c.Body))
+	for k, vv := range c.Header {
+		size += int64(len(k))
+		for _, v := range vv {
+			size += int64(len(v))
+		}
+	}
+	return size
+}
+
+// getCachedResponse returns the cached response for the given key, if any.
+func (g *Gateway) getCachedResponse(key string) (*cachedResponse, bool) {
+	if g.responseCache == nil {
+		return nil, false
+	}
+
+	value, found := g.responseCache.Get(key)
+	if !found {
+		return nil, false
+	}
+
+	cached, ok := value.(*cachedResponse)
+	return cached, ok
+}
+
+// setCachedResponse stores the response in the cache under the given key.
+func (g *Gateway) setCachedResponse(key string, cached *cachedResponse) {
+	if g.responseCache == nil {
+		return
+	}
+	g.responseCache.Set(key, cached, cached.size(), cached.ttl())
+}
+
+// writeCachedResponse writes a cached response to the client adding the Age
+// header, or a 304 Not Modified response if the client already has it.
+func writeCachedResponse(w http.ResponseWriter, r *http.Request, cached *cachedResponse, now time.Time) {
+	header := w.Header()
+	for k, vv := range cached.Header {
+		header[k] = append([]string(nil), vv...)
+	}
+	header.Set("Age", strconv.FormatInt(int64(cached.age(now)/time.Second), 10))
+
+	if etagMatches(r.Header.Get("If-None-Match"), cached.etag()) {
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(cached.Body)))
+	w.WriteHeader(cached.StatusCode)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(cached.Body)
+	}
+}
+
+// replaceResponseWithCached replaces an origin response with a revalidated
+// cached response, answering with 304 Not Modified if the client already
+// has it.
+func replaceResponseWithCached(resp *http.Response, clientIfNoneMatch string, cached *cachedResponse, now time.Time) {
+	header := cached.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(cached.age(now)/time.Second), 10))
+	resp.Header = header
+
+	if etagMatches(clientIfNoneMatch, cached.etag()) {
+		resp.StatusCode = http.StatusNotModified
+		resp.Status = "304 " + http.StatusText(http.StatusNotModified)
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = 0
+		resp.Body = http.NoBody
+		return
+	}
+
+	resp.StatusCode = cached.StatusCode
+	resp.Status = strconv.Itoa(cached.StatusCode) + " " + http.StatusText(cached.StatusCode)
+	resp.ContentLength = int64(len(cached.Body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(cached.Body)))
+	resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+}
+
+// revalidatedCachedResponse returns a copy of the cached response refreshed
+// with the headers of a 304 Not Modified response from the origin.
+func revalidatedCachedResponse(cached *cachedResponse, notModifiedHeader http.Header, now time.Time, defaultTTL time.Duration) *cachedResponse {
+	header := cached.Header.Clone()
+	for _, name := range []string{"Cache-Control", "Date", "ETag", "Expires", "Last-Modified", "Vary"} {
+		if values := notModifiedHeader.Values(name); len(values) > 0 {
+			header[name] = append([]string(nil), values...)
+		}
+	}
+
+	freshness, _ := responseFreshness(cached.StatusCode, header, now, defaultTTL)
+	return &cachedResponse{
+		StatusCode: cached.StatusCode,
+		Header:     header,
+		Body:       cached.Body,
+		StoredAt:   now,
+		InitialAge: responseInitialAge(notModifiedHeader),
+		Freshness:  freshness,
+	}
+}
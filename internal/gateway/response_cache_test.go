@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedResponseFreshness(t *testing.T) {
+	now := time.Now()
+	cached := &cachedResponse{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		StoredAt:   now.Add(-30 * time.Second),
+		InitialAge: 10 * time.Second,
+		Freshness:  60 * time.Second,
+	}
+
+	assert.Equal(t, 40*time.Second, cached.age(now))
+	assert.True(t, cached.isFresh(now))
+	assert.True(t, cached.isFresh(now.Add(19*time.Second)))
+	assert.False(t, cached.isFresh(now.Add(20*time.Second)), "the age reported by the origin counts against the freshness lifetime")
+	assert.Equal(t, 50*time.Second, cached.ttl())
+
+	cached.Header.Set("ETag", `"v1"`)
+	assert.Equal(t, 50*time.Second+cacheStaleRetention, cached.ttl())
+}
+
+func TestCachedResponseStaleOnArrival(t *testing.T) {
+	now := time.Now()
+	cached := &cachedResponse{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		StoredAt:   now,
+		InitialAge: 120 * time.Second,
+		Freshness:  60 * time.Second,
+	}
+
+	assert.False(t, cached.isFresh(now))
+	assert.Equal(t, time.Duration(0), cached.ttl())
+
+	cached.Header.Set("ETag", `"v1"`)
+	assert.Equal(t, cacheStaleRetention, cached.ttl())
+}
+
+func TestWriteCachedResponse(t *testing.T) {
+	now := time.Now()
+	cached := &cachedResponse{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type": {"application/json"},
+			"Etag":         {`"v1"`},
+		},
+		Body:      []byte(`{"ok":true}`),
+		StoredAt:  now.Add(-5 * time.Second),
+		Freshness: time.Minute,
+	}
+
+	t.Run("GET", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		writeCachedResponse(rec, httptest.NewRequest(http.MethodGet, "/", nil), cached, now)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "5", rec.Header().Get("Age"))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"ok":true}`, rec.Body.String())
+	})
+
+	t.Run("HEAD", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		writeCachedResponse(rec, httptest.NewRequest(http.MethodHead, "/", nil), cached, now)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "11", rec.Header().Get("Content-Length"))
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("matching If-None-Match", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-None-Match", `"v1"`)
+		rec := httptest.NewRecorder()
+		writeCachedResponse(rec, r, cached, now)
+
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+	})
+}
+
+func TestRevalidatedCachedResponse(t *testing.T) {
+	now := time.Now()
+	cached := &cachedResponse{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Cache-Control": {"max-age=10"},
+			"Etag":          {`"v1"`},
+			"X-Custom":      {"kept"},
+		},
+		Body:      []byte("body"),
+		StoredAt:  now.Add(-time.Minute),
+		Freshness: 10 * time.Second,
+	}
+
+	refreshed := revalidatedCachedResponse(cached, http.Header{"Cache-Control": {"max-age=120"}}, now, 0)
+
+	assert.Equal(t, now, refreshed.StoredAt)
+	assert.Equal(t, 120*time.Second, refreshed.Freshness)
+	assert.True(t, refreshed.isFresh(now))
+	assert.Equal(t, "kept", refreshed.Header.Get("X-Custom"))
+	assert.Equal(t, []byte("body"), refreshed.Body)
+	assert.Equal(t, 10*time.Second, cached.Freshness, "original entry must not be modified")
+}
+
+func TestReplaceResponseWithCached(t *testing.T) {
+	now := time.Now()
+	cached := &cachedResponse{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": {`"v1"`}},
+		Body:       []byte("cached body"),
+		StoredAt:   now,
+		Freshness:  time.Minute,
+	}
+
+	t.Run("client without validator gets the cached body", func(t *testing.T) {
+		resp := &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: http.NoBody}
+		replaceResponseWithCached(resp, "", cached, now)
+
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "cached body", string(body))
+		assert.Equal(t, "0", resp.Header.Get("Age"))
+	})
+
+	t.Run("client with matching validator gets 304", func(t *testing.T) {
+		resp := &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: http.NoBody}
+		replaceResponseWithCached(resp, `"v1"`, cached, now)
+
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+		assert.Equal(t, http.NoBody, resp.Body)
+	})
+}
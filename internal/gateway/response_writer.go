@@ -6,9 +6,11 @@ import (
 )
 
 // responseWriter is a custom http.ResponseWriter that captures the response body
+// and status code
 type responseWriter struct {
 	http.ResponseWriter
-	body *bytes.Buffer
+	body       *bytes.Buffer
+	statusCode int
 }
 
 // newResponseWriter creates a new responseWriter
@@ -19,13 +21,44 @@ func newResponseWriter(w http.ResponseWriter) *responseWriter {
 	}
 }
 
+// WriteHeader captures the status code while writing it to the underlying
+// ResponseWriter, informational responses are passed through without being
+// captured since the final status comes after them
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 && isFinalStatus(statusCode) {
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
 // Write captures the response body while writing it to the underlying ResponseWriter
 func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
 	w.body.Write(b)
 	return w.ResponseWriter.Write(b)
 }
 
+// Unwrap returns the underlying ResponseWriter so http.ResponseController
+// can reach optional interfaces such as http.Flusher
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // getBody returns the captured response body
 func (w *responseWriter) getBody() []byte {
 	return w.body.Bytes()
 }
+
+// getStatusCode returns the captured status code, it is 0 if nothing
+// was written yet
+func (w *responseWriter) getStatusCode() int {
+	return w.statusCode
+}
+
+// isFinalStatus reports whether a status code ends the response headers, which
+// are all but the informational ones except 101 Switching Protocols
+func isFinalStatus(statusCode int) bool {
+	return statusCode >= 200 || statusCode == http.StatusSwitchingProtocols
+}
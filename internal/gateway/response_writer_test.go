@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
@@ -45,3 +46,74 @@ func TestResponseWriter(t *testing.T) {
 		})
 	}
 }
+
+func TestResponseWriterStatusCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		writeFn    func(w *responseWriter)
+		wantStatus int
+	}{
+		{
+			name:       "nothing written",
+			writeFn:    func(w *responseWriter) {},
+			wantStatus: 0,
+		},
+		{
+			name: "implicit status on write",
+			writeFn: func(w *responseWriter) {
+				_, _ = w.Write([]byte("ok"))
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "explicit status",
+			writeFn: func(w *responseWriter) {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte("forbidden"))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "first status wins",
+			writeFn: func(w *responseWriter) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "informational status before the final one",
+			writeFn: func(w *responseWriter) {
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusCreated)
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "informational status before an implicit one",
+			writeFn: func(w *responseWriter) {
+				w.WriteHeader(http.StatusContinue)
+				_, _ = w.Write([]byte("ok"))
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "switching protocols is final",
+			writeFn: func(w *responseWriter) {
+				w.WriteHeader(http.StatusSwitchingProtocols)
+			},
+			wantStatus: http.StatusSwitchingProtocols,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writer := newResponseWriter(rec)
+
+			tt.writeFn(writer)
+
+			assert.Equal(t, tt.wantStatus, writer.getStatusCode())
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracePropagator propagates the W3C traceparent and tracestate headers.
+var tracePropagator = propagation.TraceContext{}
+
+// WithTracerProvider enables tracing with the given provider. The gateway
+// creates a server span for each request and a client span for each request
+// to an origin, which receives the trace context in the traceparent and
+// tracestate headers. Incoming trace context is continued.
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(g *Gateway) {
+		g.tracerProvider = tracerProvider
+	}
+}
+
+// traceHandler wraps handler to create the server span of each request when
+// tracing is enabled.
+func (g *Gateway) traceHandler(handler http.Handler) http.Handler {
+	if g.tracerProvider == nil {
+		return handler
+	}
+
+	return otelhttp.NewHandler(
+		handler,
+		"gateway",
+		otelhttp.WithTracerProvider(g.tracerProvider),
+		otelhttp.WithPropagators(tracePropagator),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}),
+	)
+}
+
+// traceTransport wraps transport, nil for the default one, to create the
+// client span of each request to an origin and send it the trace context
+// when tracing is enabled.
+func (g *Gateway) traceTransport(transport http.RoundTripper) http.RoundTripper {
+	if g.tracerProvider == nil {
+		return transport
+	}
+
+	return otelhttp.NewTransport(
+		transport,
+		otelhttp.WithTracerProvider(g.tracerProvider),
+		otelhttp.WithPropagators(tracePropagator),
+	)
+}
+
+// annotateServerSpan names the server span of a request after the route
+// that serves it.
+func annotateServerSpan(r *http.Request, route Route, requestID string) {
+	span := trace.SpanFromContext(r.Context())
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetName(r.Method + " " + route.Endpoint)
+	span.SetAttributes(
+		attribute.String("http.route", route.Endpoint),
+		attribute.String("ufogateway.route.id", route.ID),
+		attribute.String("ufogateway.request.id", requestID),
+	)
+}
+
+// requestTraceID returns the ID of the trace of a request, empty when the
+// request is not traced.
+func requestTraceID(r *http.Request) string {
+	spanContext := trace.SpanContextFromContext(r.Context())
+	if !spanContext.IsValid() || !spanContext.IsSampled() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestGatewayTracing(t *testing.T) {
+	const incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	tests := []struct {
+		name        string
+		traced      bool
+		traceparent string
+		tracestate  string
+	}{
+		{name: "not traced"},
+		{name: "new trace", traced: true},
+		{
+			name:        "incoming trace",
+			traced:      true,
+			traceparent: "00-" + incomingTraceID + "-00f067aa0ba902b7-01",
+			tracestate:  "vendor=value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var originHeader http.Header
+			origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				originHeader = r.Header.Clone()
+			}))
+			defer origin.Close()
+
+			exporter := tracetest.NewInMemoryExporter()
+			logStorer := &fakeLogStorer{}
+			routes := &fakeRouteProvider{routes: []Route{{ID: "r1", Endpoint: "/api", OriginURL: origin.URL}}}
+
+			var opts []Option
+			if tt.traced {
+				tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+				defer func() { _ = tracerProvider.Shutdown(context.Background()) }()
+				opts = append(opts, WithTracerProvider(tracerProvider))
+			}
+			g := NewGateway(routes, logStorer, opts...)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+			if tt.traceparent != "" {
+				r.Header.Set("traceparent", tt.traceparent)
+				r.Header.Set("tracestate", tt.tracestate)
+			}
+			g.ServeHTTP(httptest.NewRecorder(), r)
+
+			require.Len(t, logStorer.requestLogs, 1)
+			if !tt.traced {
+				assert.Empty(t, originHeader.Get("traceparent"))
+				assert.Empty(t, logStorer.requestLogs[0].TraceID)
+				return
+			}
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 2)
+			client, server := spans[0], spans[1]
+
+			assert.Equal(t, "GET /api", server.Name)
+			assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+			assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+			assert.Equal(t, server.SpanContext.SpanID(), client.Parent.SpanID())
+
+			traceID := server.SpanContext.TraceID().String()
+			if tt.traceparent != "" {
+				assert.Equal(t, incomingTraceID, traceID)
+				assert.Equal(t, tt.tracestate, originHeader.Get("tracestate"))
+			}
+			assert.Equal(t, traceID, client.SpanContext.TraceID().String())
+			assert.Equal(t, "00-"+traceID+"-"+client.SpanContext.SpanID().String()+"-01", originHeader.Get("traceparent"))
+			assert.Equal(t, traceID, logStorer.requestLogs[0].TraceID)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// Classes of the errors of requests to the origin.
+const (
+	UpstreamErrorDNS               = "dns"                // the origin host name could not be resolved
+	UpstreamErrorConnectionRefused = "connection_refused" // the origin refused the connection
+	UpstreamErrorConnectionReset   = "connection_reset"   // the origin closed the connection abruptly
+	UpstreamErrorTLSVerification   = "tls_verification"   // the origin certificate could not be verified
+	UpstreamErrorTLS               = "tls"                // the TLS handshake with the origin failed otherwise
+	UpstreamErrorTimeout           = "timeout"            // the origin did not respond in time
+	UpstreamErrorClientCancelled   = "client_cancelled"   // the client went away before the origin responded
+	UpstreamErrorOther             = "other"              // any other failure
+)
+
+// StatusClientClosedRequest is the non standard status code logged for the
+// requests whose client went away before the origin responded.
+const StatusClientClosedRequest = 499
+
+// UpstreamError describes why a request to the origin failed.
+type UpstreamError struct {
+	Class   string `json:"class"`   // is one of the UpstreamError classes
+	Message string `json:"message"` // is the error returned by the transport
+}
+
+// classifyUpstreamError returns the class of an error of the request to the
+// origin, ctx is the context of the client request.
+func classifyUpstreamError(ctx context.Context, err error) string {
+	var (
+		netErr      net.Error
+		dnsErr      *net.DNSError
+		certErr     *tls.CertificateVerificationError
+		unknownAuth x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidCert x509.CertificateInvalidError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+	)
+
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled) || errors.Is(err, context.Canceled):
+		return UpstreamErrorClientCancelled
+	case errors.As(err, &dnsErr):
+		return UpstreamErrorDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return UpstreamErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return UpstreamErrorConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return UpstreamErrorConnectionReset
+	case errors.As(err, &certErr),
+		errors.As(err, &unknownAuth),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &invalidCert):
+		return UpstreamErrorTLSVerification
+	case errors.As(err, &recordErr), errors.As(err, &alertErr):
+		return UpstreamErrorTLS
+	default:
+		return UpstreamErrorOther
+	}
+}
+
+// upstreamErrorResponse returns the error kind and the status code the
+// gateway answers an origin failure of the given class with.
+func upstreamErrorResponse(class string) (string, int) {
+	switch class {
+	case UpstreamErrorClientCancelled:
+		return ErrorKindClientCancelled, StatusClientClosedRequest
+	case UpstreamErrorTimeout:
+		return ErrorKindUpstreamTimeout, http.StatusGatewayTimeout
+	case UpstreamErrorDNS, UpstreamErrorConnectionRefused:
+		return ErrorKindUpstreamDial, http.StatusBadGateway
+	case UpstreamErrorTLSVerification, UpstreamErrorTLS:
+		return ErrorKindUpstreamTLS, http.StatusBadGateway
+	default:
+		return ErrorKindUpstream, http.StatusBadGateway
+	}
+}
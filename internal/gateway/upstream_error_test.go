@@ -0,0 +1,180 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyUpstreamError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{
+			name: "client cancelled",
+			ctx:  cancelled,
+			err:  errors.New("read: connection reset"),
+			want: UpstreamErrorClientCancelled,
+		},
+		{
+			name: "dns",
+			err:  fmt.Errorf("dial tcp: %w", &net.DNSError{Err: "no such host", Name: "origin.invalid", IsNotFound: true}),
+			want: UpstreamErrorDNS,
+		},
+		{
+			name: "connection refused",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			want: UpstreamErrorConnectionRefused,
+		},
+		{
+			name: "connection reset",
+			err:  &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)},
+			want: UpstreamErrorConnectionReset,
+		},
+		{
+			name: "deadline",
+			err:  fmt.Errorf("round trip: %w", context.DeadlineExceeded),
+			want: UpstreamErrorTimeout,
+		},
+		{
+			name: "unknown authority",
+			err:  &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}},
+			want: UpstreamErrorTLSVerification,
+		},
+		{
+			name: "hostname mismatch",
+			err:  x509.HostnameError{Host: "origin"},
+			want: UpstreamErrorTLSVerification,
+		},
+		{
+			name: "tls alert",
+			err:  &net.OpError{Op: "remote error", Err: tls.AlertError(40)},
+			want: UpstreamErrorTLS,
+		},
+		{
+			name: "other",
+			err:  errors.New("unexpected EOF"),
+			want: UpstreamErrorOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			assert.Equal(t, tt.want, classifyUpstreamError(ctx, tt.err))
+		})
+	}
+}
+
+func TestUpstreamErrorResponse(t *testing.T) {
+	tests := []struct {
+		class      string
+		wantKind   string
+		wantStatus int
+	}{
+		{class: UpstreamErrorDNS, wantKind: ErrorKindUpstreamDial, wantStatus: http.StatusBadGateway},
+		{class: UpstreamErrorConnectionRefused, wantKind: ErrorKindUpstreamDial, wantStatus: http.StatusBadGateway},
+		{class: UpstreamErrorConnectionReset, wantKind: ErrorKindUpstream, wantStatus: http.StatusBadGateway},
+		{class: UpstreamErrorTLSVerification, wantKind: ErrorKindUpstreamTLS, wantStatus: http.StatusBadGateway},
+		{class: UpstreamErrorTLS, wantKind: ErrorKindUpstreamTLS, wantStatus: http.StatusBadGateway},
+		{class: UpstreamErrorTimeout, wantKind: ErrorKindUpstreamTimeout, wantStatus: http.StatusGatewayTimeout},
+		{class: UpstreamErrorClientCancelled, wantKind: ErrorKindClientCancelled, wantStatus: StatusClientClosedRequest},
+		{class: UpstreamErrorOther, wantKind: ErrorKindUpstream, wantStatus: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.class, func(t *testing.T) {
+			kind, status := upstreamErrorResponse(tt.class)
+			assert.Equal(t, tt.wantKind, kind)
+			assert.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
+
+func TestGatewayUpstreamErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedURL := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	tlsOrigin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsOrigin.Close()
+
+	tests := []struct {
+		name       string
+		originURL  string
+		cancel     bool
+		wantStatus int
+		wantKind   string
+		wantClass  string
+	}{
+		{
+			name:       "connection refused",
+			originURL:  closedURL,
+			wantStatus: http.StatusBadGateway,
+			wantKind:   ErrorKindUpstreamDial,
+			wantClass:  UpstreamErrorConnectionRefused,
+		},
+		{
+			name:       "untrusted certificate",
+			originURL:  tlsOrigin.URL,
+			wantStatus: http.StatusBadGateway,
+			wantKind:   ErrorKindUpstreamTLS,
+			wantClass:  UpstreamErrorTLSVerification,
+		},
+		{
+			name:       "client cancelled",
+			originURL:  closedURL,
+			cancel:     true,
+			wantStatus: StatusClientClosedRequest,
+			wantKind:   ErrorKindClientCancelled,
+			wantClass:  UpstreamErrorClientCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storer := &fakeLogStorer{}
+			routes := &fakeRouteProvider{routes: []Route{{ID: "route1", Endpoint: "/api", OriginURL: tt.originURL}}}
+			g := NewGateway(routes, storer)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+
+			rec := httptest.NewRecorder()
+			g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil).WithContext(ctx))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			require.Len(t, storer.responseLogs, 1)
+			response := storer.responseLogs[0]
+			assert.Equal(t, tt.wantStatus, response.StatusCode)
+			assert.Equal(t, tt.wantKind, response.ErrorKind)
+			require.NotNil(t, response.UpstreamError)
+			assert.Equal(t, tt.wantClass, response.UpstreamError.Class)
+			assert.NotEmpty(t, response.UpstreamError.Message)
+		})
+	}
+}
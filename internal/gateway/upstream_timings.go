@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UpstreamTimings is the breakdown of the time spent in a request to the
+// origin. Phases that didn't happen, like DNS on a reused connection, are
+// zero.
+type UpstreamTimings struct {
+	DNS        time.Duration // is the time spent resolving the origin host
+	Connect    time.Duration // is the time spent opening the TCP connection
+	TLS        time.Duration // is the time spent in the TLS handshake
+	Wait       time.Duration // is the time from the request written until the first response byte
+	Transfer   time.Duration // is the time from the first response byte until the body was copied
+	Total      time.Duration // is the whole time of the request to the origin
+	ConnReused bool          // is whether the connection was reused from a previous request
+	ConnIdle   time.Duration // is how long a reused connection was idle before
+}
+
+// upstreamTrace records the httptrace events of a request to the origin.
+// The events can fire from different goroutines.
+type upstreamTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	reused       bool
+	idle         time.Duration
+}
+
+func newUpstreamTrace() *upstreamTrace {
+	return &upstreamTrace{start: time.Now()}
+}
+
+// withContext returns ctx with the trace attached to it.
+func (t *upstreamTrace) withContext(ctx context.Context) context.Context {
+	record := func(at *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*at = time.Now()
+	}
+	// recordFirst keeps the first time of events that can happen more than
+	// once, like connecting to each address of the host
+	recordFirst := func(at *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if at.IsZero() {
+			*at = time.Now()
+		}
+	}
+
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { recordFirst(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { record(&t.dnsDone) },
+		ConnectStart:      func(string, string) { recordFirst(&t.connectStart) },
+		ConnectDone:       func(string, string, error) { record(&t.connectDone) },
+		TLSHandshakeStart: func() { recordFirst(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { record(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = time.Now()
+			t.reused = info.Reused
+			t.idle = info.IdleTime
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { record(&t.wroteRequest) },
+		GotFirstResponseByte: func() { record(&t.firstByte) },
+	})
+}
+
+// timings returns the phase timings with end as the time the response body
+// was copied, or the time until now for a response still being read.
+func (t *upstreamTrace) timings(end time.Time) UpstreamTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	waitStart := t.wroteRequest
+	if waitStart.IsZero() {
+		waitStart = t.gotConn
+	}
+
+	return UpstreamTimings{
+		DNS:        between(t.dnsStart, t.dnsDone),
+		Connect:    between(t.connectStart, t.connectDone),
+		TLS:        between(t.tlsStart, t.tlsDone),
+		Wait:       between(waitStart, t.firstByte),
+		Transfer:   between(t.firstByte, end),
+		Total:      between(t.start, end),
+		ConnReused: t.reused,
+		ConnIdle:   t.idle,
+	}
+}
+
+// between returns the time from start to end, or zero if either is unknown.
+func between(start time.Time, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// serverTiming formats the timings known when the response headers arrive
+// as a Server-Timing header value, the transfer is still to come then.
+func (t UpstreamTimings) serverTiming() string {
+	metrics := []struct {
+		name string
+		dur  time.Duration
+	}{
+		{"dns", t.DNS},
+		{"connect", t.Connect},
+		{"tls", t.TLS},
+		{"wait", t.Wait},
+		{"upstream", t.Total},
+	}
+
+	entries := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		if metric.dur == 0 && metric.name != "upstream" {
+			continue
+		}
+		entries = append(entries, fmt.Sprintf("%s;dur=%.3f", metric.name, float64(metric.dur)/float64(time.Millisecond)))
+	}
+	return strings.Join(entries, ", ")
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamTraceTimings(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	t.Run("new connection", func(t *testing.T) {
+		trace := &upstreamTrace{
+			start:        start,
+			dnsStart:     at(1),
+			dnsDone:      at(3),
+			connectStart: at(3),
+			connectDone:  at(7),
+			tlsStart:     at(7),
+			tlsDone:      at(15),
+			gotConn:      at(15),
+			wroteRequest: at(16),
+			firstByte:    at(40),
+		}
+
+		assert.Equal(t, UpstreamTimings{
+			DNS:      2 * time.Millisecond,
+			Connect:  4 * time.Millisecond,
+			TLS:      8 * time.Millisecond,
+			Wait:     24 * time.Millisecond,
+			Transfer: 10 * time.Millisecond,
+			Total:    50 * time.Millisecond,
+		}, trace.timings(at(50)))
+	})
+
+	t.Run("reused connection", func(t *testing.T) {
+		trace := &upstreamTrace{
+			start:     start,
+			gotConn:   at(1),
+			firstByte: at(5),
+			reused:    true,
+			idle:      time.Second,
+		}
+
+		assert.Equal(t, UpstreamTimings{
+			Wait:       4 * time.Millisecond,
+			Transfer:   1 * time.Millisecond,
+			Total:      6 * time.Millisecond,
+			ConnReused: true,
+			ConnIdle:   time.Second,
+		}, trace.timings(at(6)))
+	})
+
+	t.Run("failed before a response", func(t *testing.T) {
+		trace := &upstreamTrace{start: start, dnsStart: at(1)}
+		assert.Equal(t, UpstreamTimings{Total: 2 * time.Millisecond}, trace.timings(at(2)))
+	})
+}
+
+func TestUpstreamTimingsServerTiming(t *testing.T) {
+	timings := UpstreamTimings{
+		DNS:      1500 * time.Microsecond,
+		Wait:     20 * time.Millisecond,
+		Transfer: time.Second,
+		Total:    25 * time.Millisecond,
+	}
+	assert.Equal(t, "dns;dur=1.500, wait;dur=20.000, upstream;dur=25.000", timings.serverTiming())
+	assert.Equal(t, "upstream;dur=0.000", UpstreamTimings{}.serverTiming())
+}
+
+func TestGatewayUpstreamTimings(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer origin.Close()
+
+	storer := &fakeLogStorer{}
+	routes := &fakeRouteProvider{routes: []Route{
+		{ID: "timed", Endpoint: "/timed", OriginURL: origin.URL, ServerTiming: true},
+		{ID: "plain", Endpoint: "/plain", OriginURL: origin.URL},
+	}}
+	g := NewGateway(routes, storer)
+
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/timed", nil))
+
+	serverTiming := rec.Header().Get("Server-Timing")
+	assert.Contains(t, serverTiming, "connect;dur=")
+	assert.Contains(t, serverTiming, "wait;dur=")
+	assert.Contains(t, serverTiming, "upstream;dur=")
+
+	require.Len(t, storer.responseLogs, 1)
+	timings := storer.responseLogs[0].UpstreamTimings
+	require.NotNil(t, timings)
+	assert.GreaterOrEqual(t, timings.Wait, 5*time.Millisecond)
+	assert.GreaterOrEqual(t, timings.Total, timings.Wait)
+	assert.False(t, timings.ConnReused)
+
+	rec = httptest.NewRecorder()
+	g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/plain", nil))
+
+	assert.Empty(t, rec.Header().Get("Server-Timing"))
+	require.Len(t, storer.responseLogs, 2)
+	require.NotNil(t, storer.responseLogs[1].UpstreamTimings)
+	assert.True(t, storer.responseLogs[1].UpstreamTimings.ConnReused)
+	assert.Zero(t, storer.responseLogs[1].UpstreamTimings.Connect)
+}
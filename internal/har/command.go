@@ -0,0 +1,62 @@
+package har
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/uforg/ufogateway/internal/util/timeutil"
+)
+
+// NewCommand returns the "export-har" command that writes the selected stored
+// requests as a HAR file.
+func NewCommand(exporter *Exporter) *cobra.Command {
+	opts := Options{}
+	var from, to, output string
+
+	command := &cobra.Command{
+		Use:   "export-har",
+		Short: "Exports stored requests as a HAR 1.2 file",
+		Example: `  ufogateway export-har --route abc123def456ghi --output route.har
+  ufogateway export-har --from 2025-01-01 --to 2025-01-02 --filter "res_status >= 500"`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if opts.From, err = timeutil.ParseTime(from); err != nil {
+				return err
+			}
+			if opts.To, err = timeutil.ParseTime(to); err != nil {
+				return err
+			}
+
+			doc, err := exporter.Export(opts)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = os.Stdout
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(doc)
+		},
+	}
+
+	command.Flags().StringVar(&opts.RouteID, "route", "", "only export the requests of this route id")
+	command.Flags().StringVar(&from, "from", "", "only export the requests made at or after this time")
+	command.Flags().StringVar(&to, "to", "", "only export the requests made before this time")
+	command.Flags().StringVar(&opts.Filter, "filter", "", "a filter over the requests collection")
+	command.Flags().IntVar(&opts.Limit, "limit", DefaultLimit, "the maximum number of requests to export")
+	command.Flags().StringVarP(&output, "output", "o", "", "the file to write, defaults to stdout")
+
+	return command
+}
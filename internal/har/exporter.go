@@ -0,0 +1,83 @@
+package har
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/uforg/ufogateway/internal/db"
+)
+
+const (
+	// DefaultLimit is the number of requests exported when no limit is given.
+	DefaultLimit = 500
+	// MaxLimit is the maximum number of requests in a single export.
+	MaxLimit = 10000
+)
+
+// Options selects the stored requests to export, all the set criteria must
+// match.
+type Options struct {
+	RouteID string    // Only export the requests of this route
+	From    time.Time // Only export the requests made at or after this time
+	To      time.Time // Only export the requests made before this time
+	Filter  string    // PocketBase filter over the requests collection
+	Limit   int       // Maximum number of requests to export
+}
+
+type Exporter struct {
+	db *db.DB
+}
+
+func NewExporter(db *db.DB) *Exporter {
+	return &Exporter{
+		db: db,
+	}
+}
+
+// Export returns a HAR document with the stored requests selected by opts,
+// oldest first.
+func (ex *Exporter) Export(opts Options) (HAR, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		return HAR{}, errors.New("limit exceeds the maximum of 10000 requests")
+	}
+
+	filter, params := buildFilter(opts)
+	storedRequests, err := ex.db.FindStoredRequests(filter, limit, params)
+	if err != nil {
+		return HAR{}, err
+	}
+
+	return NewHAR(storedRequests), nil
+}
+
+// buildFilter joins the export criteria into a single PocketBase filter
+// expression and its parameters.
+func buildFilter(opts Options) (string, dbx.Params) {
+	conditions := []string{}
+	params := dbx.Params{}
+
+	if opts.RouteID != "" {
+		conditions = append(conditions, "route = {:route}")
+		params["route"] = opts.RouteID
+	}
+	if !opts.From.IsZero() {
+		conditions = append(conditions, "req_timestamp >= {:from}")
+		params["from"] = opts.From.UTC().Format(types.DefaultDateLayout)
+	}
+	if !opts.To.IsZero() {
+		conditions = append(conditions, "req_timestamp < {:to}")
+		params["to"] = opts.To.UTC().Format(types.DefaultDateLayout)
+	}
+	if filter := strings.TrimSpace(opts.Filter); filter != "" {
+		conditions = append(conditions, "("+filter+")")
+	}
+
+	return strings.Join(conditions, " && "), params
+}
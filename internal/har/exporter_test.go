@@ -0,0 +1,60 @@
+package har
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildFilter(t *testing.T) {
+	from := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	to := time.Date(2025, 1, 3, 0, 0, 0, 0, time.FixedZone("", 3600))
+
+	tests := []struct {
+		name       string
+		opts       Options
+		wantFilter string
+		wantParams dbx.Params
+	}{
+		{
+			name:       "no criteria",
+			opts:       Options{},
+			wantFilter: "",
+			wantParams: dbx.Params{},
+		},
+		{
+			name:       "route",
+			opts:       Options{RouteID: "abc"},
+			wantFilter: "route = {:route}",
+			wantParams: dbx.Params{"route": "abc"},
+		},
+		{
+			name:       "time range in utc",
+			opts:       Options{From: from, To: to},
+			wantFilter: "req_timestamp >= {:from} && req_timestamp < {:to}",
+			wantParams: dbx.Params{
+				"from": "2025-01-02 03:04:05.000Z",
+				"to":   "2025-01-02 23:00:00.000Z",
+			},
+		},
+		{
+			name:       "all criteria",
+			opts:       Options{RouteID: "abc", From: from, Filter: " res_status >= 500 || req_method = 'POST' "},
+			wantFilter: "route = {:route} && req_timestamp >= {:from} && (res_status >= 500 || req_method = 'POST')",
+			wantParams: dbx.Params{
+				"route": "abc",
+				"from":  "2025-01-02 03:04:05.000Z",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, params := buildFilter(tt.opts)
+			assert.Equal(t, tt.wantFilter, filter)
+			assert.Equal(t, tt.wantParams, params)
+		})
+	}
+}
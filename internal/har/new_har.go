@@ -0,0 +1,206 @@
+package har
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/uforg/ufogateway/internal/bodycodec"
+	"github.com/uforg/ufogateway/internal/db"
+)
+
+// NewHAR builds a HAR document with one entry per stored request.
+//
+// The timings come from the stored phases of the request to the origin. When
+// there are none, like for mocked responses, the total duration is reported as
+// the wait timing. Headers and bodies are only present when the route was
+// configured to store them, bodies that can't be read are left empty with a
+// comment.
+func NewHAR(storedRequests []db.StoredRequest) HAR {
+	entries := make([]Entry, 0, len(storedRequests))
+	for _, storedRequest := range storedRequests {
+		entries = append(entries, newEntry(storedRequest))
+	}
+
+	return HAR{
+		Log: Log{
+			Version: harVersion,
+			Creator: Creator{Name: creatorName, Version: creatorVersion},
+			Entries: entries,
+		},
+	}
+}
+
+func newEntry(sr db.StoredRequest) Entry {
+	durationMs := milliseconds(sr.ResDuration)
+	reqBody := sr.ReqBodyBytes()
+	resBody := sr.ResBodyBytes()
+
+	request := Request{
+		Method:      sr.ReqMethod,
+		URL:         sr.ReqGatewayURL,
+		HTTPVersion: httpVersion,
+		Cookies:     requestCookies(sr.ReqHeaders),
+		Headers:     nameValues(sr.ReqHeaders),
+		QueryString: queryString(sr.ReqGatewayURL),
+		HeadersSize: -1,
+		BodySize:    transferSize(sr.ReqBodyMeta, reqBody),
+	}
+	if len(reqBody) > 0 || sr.ReqBodyError != "" {
+		text, encoding := encodeBody(reqBody)
+		request.PostData = &PostData{
+			MimeType: http.Header(sr.ReqHeaders).Get("Content-Type"),
+			Text:     text,
+			Encoding: encoding,
+			Comment:  unreadableBodyComment(sr.ReqBodyError),
+		}
+	}
+
+	text, encoding := encodeBody(resBody)
+	response := Response{
+		Status:      sr.ResStatus,
+		StatusText:  http.StatusText(sr.ResStatus),
+		HTTPVersion: httpVersion,
+		Cookies:     responseCookies(sr.ResHeaders),
+		Headers:     nameValues(sr.ResHeaders),
+		Content: Content{
+			Size:     len(resBody),
+			MimeType: http.Header(sr.ResHeaders).Get("Content-Type"),
+			Text:     text,
+			Encoding: encoding,
+			Comment:  unreadableBodyComment(sr.ResBodyError),
+		},
+		RedirectURL: http.Header(sr.ResHeaders).Get("Location"),
+		HeadersSize: -1,
+		BodySize:    transferSize(sr.ResBodyMeta, resBody),
+	}
+	if sr.ResBodyMeta.Decoded {
+		response.Content.Compression = len(resBody) - sr.ResBodyMeta.Size
+	}
+
+	return Entry{
+		StartedDateTime: sr.ReqTimestamp.UTC().Format(time.RFC3339Nano),
+		Time:            durationMs,
+		Request:         request,
+		Response:        response,
+		Timings:         newTimings(sr.ResTimings, sr.ResDuration),
+		Comment:         sr.ID,
+	}
+}
+
+// newTimings maps the stored phases of the request to the origin to the HAR
+// timings. The HAR connect timing includes the TLS handshake, and phases that
+// didn't happen, like DNS on a reused connection, are -1. The time spent in
+// the gateway before and around the request to the origin is blocked, so the
+// timings add up to the duration of the entry.
+func newTimings(t *db.ResTimings, duration time.Duration) Timings {
+	if t == nil {
+		return Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: milliseconds(duration)}
+	}
+
+	optional := func(d time.Duration) float64 {
+		if d <= 0 {
+			return -1
+		}
+		return milliseconds(d)
+	}
+	blocked := max(duration-t.DNS-t.Connect-t.TLS-t.Wait-t.Transfer, 0)
+	return Timings{
+		Blocked: milliseconds(blocked),
+		DNS:     optional(t.DNS),
+		Connect: optional(t.Connect + t.TLS),
+		SSL:     optional(t.TLS),
+		Wait:    milliseconds(t.Wait),
+		Receive: milliseconds(t.Transfer),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// unreadableBodyComment explains why a stored body is missing from the entry.
+func unreadableBodyComment(bodyErr string) string {
+	if bodyErr == "" {
+		return ""
+	}
+	return "the stored body can't be read: " + bodyErr
+}
+
+// encodeBody returns the body as is when it is valid UTF-8 text, otherwise
+// it returns it base64 encoded along with the "base64" encoding.
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+// transferSize returns the size of a stored body as it was sent, bodies
+// stored before their metadata existed were stored as sent.
+func transferSize(meta bodycodec.Meta, body []byte) int {
+	if meta.Size == 0 {
+		return len(body)
+	}
+	return meta.Size
+}
+
+// nameValues returns the headers as HAR name/value pairs sorted by name.
+func nameValues(headers map[string][]string) []NameValue {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	pairs := []NameValue{}
+	for _, name := range names {
+		for _, value := range headers[name] {
+			pairs = append(pairs, NameValue{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+// queryString returns the query parameters of rawURL sorted by name.
+func queryString(rawURL string) []NameValue {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return []NameValue{}
+	}
+	return nameValues(u.Query())
+}
+
+func requestCookies(headers map[string][]string) []Cookie {
+	r := http.Request{Header: http.Header(headers)}
+
+	cookies := []Cookie{}
+	for _, c := range r.Cookies() {
+		cookies = append(cookies, Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies
+}
+
+func responseCookies(headers map[string][]string) []Cookie {
+	r := http.Response{Header: http.Header(headers)}
+
+	cookies := []Cookie{}
+	for _, c := range r.Cookies() {
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			cookie.Expires = c.Expires.UTC().Format(time.RFC3339)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
@@ -0,0 +1,198 @@
+package har
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uforg/ufogateway/internal/bodycodec"
+	"github.com/uforg/ufogateway/internal/db"
+)
+
+func TestNewHAR(t *testing.T) {
+	storedRequest := db.StoredRequest{
+		ID:            "req1",
+		ReqTimestamp:  time.Date(2025, 1, 2, 3, 4, 5, 600_000_000, time.UTC),
+		ReqMethod:     "POST",
+		ReqGatewayURL: "http://gateway/api/users?b=2&a=1",
+		ReqHeaders: map[string][]string{
+			"Content-Type": {"application/json"},
+			"Cookie":       {"session=abc"},
+		},
+		ReqBody:     `{"name":"ann"}`,
+		ResDuration: 12500 * time.Microsecond,
+		ResStatus:   201,
+		ResHeaders: map[string][]string{
+			"Content-Type": {"application/octet-stream"},
+			"Set-Cookie":   {"theme=dark; Path=/; HttpOnly"},
+		},
+		ResBody: string([]byte{0xff, 0x00, 0x01}),
+	}
+
+	got := NewHAR([]db.StoredRequest{storedRequest})
+
+	assert.Equal(t, "1.2", got.Log.Version)
+	assert.Equal(t, Creator{Name: "UFO Gateway", Version: "dev"}, got.Log.Creator)
+	assert.Len(t, got.Log.Entries, 1)
+
+	entry := got.Log.Entries[0]
+	assert.Equal(t, "2025-01-02T03:04:05.6Z", entry.StartedDateTime)
+	assert.Equal(t, 12.5, entry.Time)
+	assert.Equal(t, Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: 12.5}, entry.Timings)
+	assert.Equal(t, "req1", entry.Comment)
+
+	assert.Equal(t, Request{
+		Method:      "POST",
+		URL:         "http://gateway/api/users?b=2&a=1",
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []Cookie{{Name: "session", Value: "abc"}},
+		Headers: []NameValue{
+			{Name: "Content-Type", Value: "application/json"},
+			{Name: "Cookie", Value: "session=abc"},
+		},
+		QueryString: []NameValue{
+			{Name: "a", Value: "1"},
+			{Name: "b", Value: "2"},
+		},
+		PostData: &PostData{
+			MimeType: "application/json",
+			Text:     `{"name":"ann"}`,
+		},
+		HeadersSize: -1,
+		BodySize:    14,
+	}, entry.Request)
+
+	assert.Equal(t, Response{
+		Status:      201,
+		StatusText:  "Created",
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []Cookie{{Name: "theme", Value: "dark", Path: "/", HTTPOnly: true}},
+		Headers: []NameValue{
+			{Name: "Content-Type", Value: "application/octet-stream"},
+			{Name: "Set-Cookie", Value: "theme=dark; Path=/; HttpOnly"},
+		},
+		Content: Content{
+			Size:     3,
+			MimeType: "application/octet-stream",
+			Text:     "/wAB",
+			Encoding: "base64",
+		},
+		HeadersSize: -1,
+		BodySize:    3,
+	}, entry.Response)
+}
+
+func TestNewHARDecodedBodies(t *testing.T) {
+	storedRequest := db.StoredRequest{
+		ReqMethod:   "POST",
+		ReqHeaders:  map[string][]string{"Content-Encoding": {"gzip"}},
+		ReqBody:     "//4=",
+		ReqBodyMeta: bodycodec.Meta{Encoding: "gzip", Size: 22, Decoded: true, DecodedSize: 2, Base64: true},
+		ResStatus:   200,
+		ResHeaders: map[string][]string{
+			"Content-Encoding": {"br"},
+			"Content-Type":     {"application/json"},
+		},
+		ResBody:     `{"items":[1,2,3]}`,
+		ResBodyMeta: bodycodec.Meta{Encoding: "br", Size: 12, Decoded: true, DecodedSize: 17},
+	}
+
+	entry := NewHAR([]db.StoredRequest{storedRequest}).Log.Entries[0]
+
+	assert.Equal(t, 22, entry.Request.BodySize)
+	assert.Equal(t, &PostData{Text: "//4=", Encoding: "base64"}, entry.Request.PostData)
+
+	assert.Equal(t, 12, entry.Response.BodySize)
+	assert.Equal(t, Content{
+		Size:        17,
+		Compression: 5,
+		MimeType:    "application/json",
+		Text:        `{"items":[1,2,3]}`,
+	}, entry.Response.Content)
+}
+
+func TestNewHARUnreadableBodies(t *testing.T) {
+	storedRequest := db.StoredRequest{
+		ReqMethod:    "POST",
+		ReqHeaders:   map[string][]string{"Content-Type": {"application/json"}},
+		ReqBodyError: "file not found",
+		ResStatus:    200,
+		ResBodyError: "file not found",
+	}
+
+	entry := NewHAR([]db.StoredRequest{storedRequest}).Log.Entries[0]
+
+	assert.Equal(t, &PostData{
+		MimeType: "application/json",
+		Comment:  "the stored body can't be read: file not found",
+	}, entry.Request.PostData)
+	assert.Empty(t, entry.Response.Content.Text)
+	assert.Equal(t, "the stored body can't be read: file not found", entry.Response.Content.Comment)
+}
+
+func TestNewHARTimings(t *testing.T) {
+	tests := []struct {
+		name       string
+		resTimings *db.ResTimings
+		want       Timings
+	}{
+		{
+			name:       "without upstream timings",
+			resTimings: nil,
+			want:       Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: 20},
+		},
+		{
+			name: "new TLS connection",
+			resTimings: &db.ResTimings{
+				DNS:      1500 * time.Microsecond,
+				Connect:  2 * time.Millisecond,
+				TLS:      3 * time.Millisecond,
+				Wait:     10 * time.Millisecond,
+				Transfer: 2500 * time.Microsecond,
+				Total:    19 * time.Millisecond,
+			},
+			want: Timings{Blocked: 1, DNS: 1.5, Connect: 5, SSL: 3, Wait: 10, Receive: 2.5},
+		},
+		{
+			name: "reused connection",
+			resTimings: &db.ResTimings{
+				Wait:     10 * time.Millisecond,
+				Transfer: 1 * time.Millisecond,
+				Total:    11 * time.Millisecond,
+			},
+			want: Timings{Blocked: 9, DNS: -1, Connect: -1, SSL: -1, Wait: 10, Receive: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storedRequest := db.StoredRequest{
+				ResDuration: 20 * time.Millisecond,
+				ResTimings:  tt.resTimings,
+			}
+
+			entry := NewHAR([]db.StoredRequest{storedRequest}).Log.Entries[0]
+			assert.Equal(t, tt.want, entry.Timings)
+			assert.Equal(t, 20.0, entry.Time)
+
+			sum := 0.0
+			for _, timing := range []float64{
+				entry.Timings.Blocked,
+				entry.Timings.DNS,
+				entry.Timings.Connect,
+				entry.Timings.Send,
+				entry.Timings.Wait,
+				entry.Timings.Receive,
+			} {
+				sum += max(timing, 0)
+			}
+			assert.InDelta(t, entry.Time, sum, 1e-9, "the timings add up to the entry time")
+		})
+	}
+}
+
+func TestNewHAREmpty(t *testing.T) {
+	got := NewHAR(nil)
+	assert.NotNil(t, got.Log.Entries)
+	assert.Empty(t, got.Log.Entries)
+}
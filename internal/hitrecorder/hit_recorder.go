@@ -0,0 +1,119 @@
+package hitrecorder
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/gateway"
+	"github.com/uforg/ufogateway/internal/routestats"
+)
+
+// bucketKey identifies the minute bucket of a route.
+type bucketKey struct {
+	routeID string
+	start   time.Time
+}
+
+// HitRecorder aggregates every request served by the gateway in minute
+// buckets per route, which are added to the route_stats collection on Flush.
+type HitRecorder struct {
+	app *pocketbase.PocketBase
+	db  *db.DB
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*routestats.Bucket
+
+	flushMu sync.Mutex
+}
+
+func NewHitRecorder(
+	app *pocketbase.PocketBase,
+	db *db.DB,
+) *HitRecorder {
+	return &HitRecorder{
+		app:     app,
+		db:      db,
+		buckets: map[bucketKey]*routestats.Bucket{},
+	}
+}
+
+// RecordHit implements gateway.HitRecorder.
+func (hr *HitRecorder) RecordHit(hit gateway.Hit) {
+	key := bucketKey{
+		routeID: hit.RouteID,
+		start:   routestats.Truncate(hit.Timestamp, routestats.GranularityMinute),
+	}
+
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+
+	bucket, ok := hr.buckets[key]
+	if !ok {
+		newBucket := routestats.NewBucket()
+		bucket = &newBucket
+		hr.buckets[key] = bucket
+	}
+	bucket.Add(hit.StatusCode, hit.Duration, hit.BytesIn, hit.BytesOut)
+}
+
+// Flush adds the buckets recorded since the last flush to the stored ones.
+// When they can't be stored they are kept for the next flush.
+func (hr *HitRecorder) Flush() error {
+	hr.flushMu.Lock()
+	defer hr.flushMu.Unlock()
+
+	hr.mu.Lock()
+	buckets := hr.buckets
+	hr.buckets = map[bucketKey]*routestats.Bucket{}
+	hr.mu.Unlock()
+
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	stats := make([]db.RouteStat, 0, len(buckets))
+	for key, bucket := range buckets {
+		route, err := hr.db.GetRouteByIDCached(key.routeID)
+		if err != nil {
+			// the route was deleted since the hit
+			hr.app.Logger().Warn(
+				"dropping the stats of a missing route",
+				"fn", "Flush",
+				"route_id", key.routeID,
+				"error", err,
+			)
+			continue
+		}
+
+		stats = append(stats, db.RouteStat{
+			RouteID:     key.routeID,
+			ProjectID:   route.Project,
+			Granularity: routestats.GranularityMinute,
+			Start:       key.start,
+			Bucket:      *bucket,
+		})
+	}
+
+	if err := hr.db.AddRouteStats(stats); err != nil {
+		hr.restore(buckets)
+		return err
+	}
+	return nil
+}
+
+// restore merges buckets that failed to be stored back into the recorded
+// ones.
+func (hr *HitRecorder) restore(buckets map[bucketKey]*routestats.Bucket) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+
+	for key, bucket := range buckets {
+		if current, ok := hr.buckets[key]; ok {
+			current.Merge(*bucket)
+			continue
+		}
+		hr.buckets[key] = bucket
+	}
+}
@@ -0,0 +1,50 @@
+package hitrecorder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uforg/ufogateway/internal/gateway"
+	"github.com/uforg/ufogateway/internal/routestats"
+)
+
+func TestHitRecorderRecordHit(t *testing.T) {
+	hr := NewHitRecorder(nil, nil)
+	minute := time.Date(2025, 2, 3, 12, 35, 0, 0, time.UTC)
+
+	hr.RecordHit(gateway.Hit{RouteID: "a", Timestamp: minute.Add(time.Second), StatusCode: 200, Duration: time.Millisecond, BytesIn: 1, BytesOut: 2})
+	hr.RecordHit(gateway.Hit{RouteID: "a", Timestamp: minute.Add(59 * time.Second), StatusCode: 502, Duration: time.Second})
+	hr.RecordHit(gateway.Hit{RouteID: "a", Timestamp: minute.Add(time.Minute), StatusCode: 200})
+	hr.RecordHit(gateway.Hit{RouteID: "b", Timestamp: minute, StatusCode: 404})
+
+	require.Len(t, hr.buckets, 3)
+
+	bucket := hr.buckets[bucketKey{routeID: "a", start: minute}]
+	require.NotNil(t, bucket)
+	assert.Equal(t, int64(2), bucket.Requests)
+	assert.Equal(t, int64(1), bucket.Status2xx)
+	assert.Equal(t, int64(1), bucket.Status5xx)
+	assert.Equal(t, int64(1), bucket.BytesIn)
+	assert.Equal(t, int64(2), bucket.BytesOut)
+	assert.Equal(t, time.Second, bucket.LatencyMax)
+
+	assert.Equal(t, int64(1), hr.buckets[bucketKey{routeID: "b", start: minute}].Status4xx)
+}
+
+func TestHitRecorderRestore(t *testing.T) {
+	hr := NewHitRecorder(nil, nil)
+	minute := time.Date(2025, 2, 3, 12, 35, 0, 0, time.UTC)
+	key := bucketKey{routeID: "a", start: minute}
+
+	failed := routestats.NewBucket()
+	failed.Add(200, time.Millisecond, 0, 0)
+
+	hr.RecordHit(gateway.Hit{RouteID: "a", Timestamp: minute, StatusCode: 500})
+	hr.restore(map[bucketKey]*routestats.Bucket{key: &failed})
+
+	assert.Equal(t, int64(2), hr.buckets[key].Requests)
+	assert.Equal(t, int64(1), hr.buckets[key].Status2xx)
+	assert.Equal(t, int64(1), hr.buckets[key].Status5xx)
+}
@@ -0,0 +1,177 @@
+package logstorer
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/gateway"
+)
+
+// Sample modes of a route, an empty mode is the same as sampleModeAll.
+const (
+	sampleModeAll     = "all"
+	sampleModePercent = "percent"
+	sampleModeOneInN  = "one_in_n"
+)
+
+// sampler picks the hits of each route that are stored according to the
+// route sample mode.
+type sampler struct {
+	mu     sync.Mutex
+	counts map[string]uint64 // hits seen per route in the one_in_n mode
+}
+
+func newSampler() *sampler {
+	return &sampler{counts: map[string]uint64{}}
+}
+
+// sample reports whether a hit of the route is picked. In the one_in_n mode
+// the first hit and then one of every SampleOneIn hits are picked.
+func (s *sampler) sample(route db.Route) bool {
+	switch route.SampleMode {
+	case sampleModePercent:
+		if route.SamplePercent <= 0 {
+			return false
+		}
+		if route.SamplePercent >= 100 {
+			return true
+		}
+		return rand.Float64()*100 < route.SamplePercent
+
+	case sampleModeOneInN:
+		if route.SampleOneIn <= 1 {
+			return true
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		count := s.counts[route.ID]
+		s.counts[route.ID] = count + 1
+		return count%uint64(route.SampleOneIn) == 0
+
+	default:
+		return true
+	}
+}
+
+// hasCaptureConditions reports whether the route only stores the hits that
+// match a capture condition.
+func hasCaptureConditions(route db.Route) bool {
+	return route.CaptureMinStatus > 0 ||
+		route.CaptureMinDurationMs > 0 ||
+		strings.TrimSpace(route.CaptureHeader) != ""
+}
+
+// matchesCaptureConditions reports whether the hit matches any of the
+// capture conditions of the route.
+func matchesCaptureConditions(route db.Route, reqLog gateway.RequestLog, resLog gateway.ResponseLog) bool {
+	if route.CaptureMinStatus > 0 && resLog.StatusCode >= route.CaptureMinStatus {
+		return true
+	}
+
+	minDuration := time.Duration(route.CaptureMinDurationMs) * time.Millisecond
+	if minDuration > 0 && resLog.Duration >= minDuration {
+		return true
+	}
+
+	if strings.TrimSpace(route.CaptureHeader) != "" && matchesHeader(route.CaptureHeader, reqLog.RequestHeaders) {
+		return true
+	}
+
+	return false
+}
+
+// matchesHeader reports whether the headers match a "Name: value" condition,
+// which requires one of the header values to equal value, or a "Name"
+// condition, which requires the header to be present.
+func matchesHeader(condition string, headers map[string][]string) bool {
+	name, value, hasValue := strings.Cut(condition, ":")
+	name = strings.TrimSpace(name)
+	value = strings.TrimSpace(value)
+
+	values := http.Header(headers).Values(name)
+	if !hasValue {
+		return len(values) > 0
+	}
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// skippedRequestsSize is how many of the last requests left out of the
+// storage are remembered to leave their mirror entries out too.
+const skippedRequestsSize = 10_000
+
+// skippedRequests is a bounded set of the IDs of the last requests left out
+// of the storage, the oldest IDs are forgotten first.
+type skippedRequests struct {
+	mu   sync.Mutex
+	ids  map[string]struct{}
+	ring []string // the remembered IDs in the order they were added
+	next int      // is the position of the ring the next ID goes to
+}
+
+func newSkippedRequests(size int) *skippedRequests {
+	return &skippedRequests{
+		ids:  make(map[string]struct{}, size),
+		ring: make([]string, size),
+	}
+}
+
+func (s *skippedRequests) add(requestID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ids[requestID]; ok {
+		return
+	}
+	if oldest := s.ring[s.next]; oldest != "" {
+		delete(s.ids, oldest)
+	}
+	s.ring[s.next] = requestID
+	s.ids[requestID] = struct{}{}
+	s.next = (s.next + 1) % len(s.ring)
+}
+
+func (s *skippedRequests) contains(requestID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.ids[requestID]
+	return ok
+}
+
+// shouldCapture reports whether the entry is stored. A complete entry is
+// stored when it matches a capture condition of its route, or the route has
+// none, and it is picked by the route sampling.
+//
+// Mirror entries are stored unless their request was left out, the queue
+// writes them after their request. Replays and entries holding only the
+// request or only the response are always stored, so that no partial record
+// is left behind.
+func (ls *LogStorer) shouldCapture(entry *logEntry) bool {
+	if entry.mirror != nil {
+		return !ls.skipped.contains(entry.requestID())
+	}
+	if entry.request == nil || entry.response == nil {
+		return true
+	}
+	if entry.request.ReplayBatch != "" {
+		return true
+	}
+
+	route := entry.route
+	captured := (!hasCaptureConditions(route) || matchesCaptureConditions(route, *entry.request, *entry.response)) &&
+		ls.sampler.sample(route)
+	if !captured {
+		ls.skipped.add(entry.requestID())
+	}
+	return captured
+}
@@ -0,0 +1,242 @@
+package logstorer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/gateway"
+)
+
+func TestSamplerSample(t *testing.T) {
+	countPicked := func(s *sampler, route db.Route, hits int) int {
+		picked := 0
+		for range hits {
+			if s.sample(route) {
+				picked++
+			}
+		}
+		return picked
+	}
+
+	tests := []struct {
+		name  string
+		route db.Route
+		want  int
+	}{
+		{
+			name:  "no mode picks every hit",
+			route: db.Route{ID: "r"},
+			want:  10,
+		},
+		{
+			name:  "all picks every hit",
+			route: db.Route{ID: "r", SampleMode: sampleModeAll},
+			want:  10,
+		},
+		{
+			name:  "zero percent picks no hit",
+			route: db.Route{ID: "r", SampleMode: sampleModePercent, SamplePercent: 0},
+			want:  0,
+		},
+		{
+			name:  "hundred percent picks every hit",
+			route: db.Route{ID: "r", SampleMode: sampleModePercent, SamplePercent: 100},
+			want:  10,
+		},
+		{
+			name:  "one in three picks the first and every third hit",
+			route: db.Route{ID: "r", SampleMode: sampleModeOneInN, SampleOneIn: 3},
+			want:  4,
+		},
+		{
+			name:  "one in zero picks every hit",
+			route: db.Route{ID: "r", SampleMode: sampleModeOneInN, SampleOneIn: 0},
+			want:  10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, countPicked(newSampler(), tt.route, 10))
+		})
+	}
+
+	t.Run("one in n counts each route on its own", func(t *testing.T) {
+		s := newSampler()
+		a := db.Route{ID: "a", SampleMode: sampleModeOneInN, SampleOneIn: 2}
+		b := db.Route{ID: "b", SampleMode: sampleModeOneInN, SampleOneIn: 2}
+
+		assert.True(t, s.sample(a))
+		assert.True(t, s.sample(b))
+		assert.False(t, s.sample(a))
+		assert.False(t, s.sample(b))
+	})
+
+	t.Run("a percentage picks about that share of hits", func(t *testing.T) {
+		route := db.Route{ID: "r", SampleMode: sampleModePercent, SamplePercent: 25}
+		picked := countPicked(newSampler(), route, 10000)
+		assert.InDelta(t, 2500, picked, 300)
+	})
+}
+
+func TestMatchesCaptureConditions(t *testing.T) {
+	reqLog := gateway.RequestLog{
+		RequestHeaders: map[string][]string{"X-Debug": {"1"}, "Accept": {"text/html", "application/json"}},
+	}
+	resLog := gateway.ResponseLog{StatusCode: 404, Duration: 150 * time.Millisecond}
+
+	tests := []struct {
+		name  string
+		route db.Route
+		want  bool
+	}{
+		{
+			name:  "status at the minimum",
+			route: db.Route{CaptureMinStatus: 404},
+			want:  true,
+		},
+		{
+			name:  "status below the minimum",
+			route: db.Route{CaptureMinStatus: 500},
+			want:  false,
+		},
+		{
+			name:  "slower than the minimum duration",
+			route: db.Route{CaptureMinDurationMs: 100},
+			want:  true,
+		},
+		{
+			name:  "faster than the minimum duration",
+			route: db.Route{CaptureMinDurationMs: 200},
+			want:  false,
+		},
+		{
+			name:  "header present",
+			route: db.Route{CaptureHeader: "x-debug"},
+			want:  true,
+		},
+		{
+			name:  "header absent",
+			route: db.Route{CaptureHeader: "X-Trace"},
+			want:  false,
+		},
+		{
+			name:  "header with one of its values",
+			route: db.Route{CaptureHeader: "Accept: application/json"},
+			want:  true,
+		},
+		{
+			name:  "header with another value",
+			route: db.Route{CaptureHeader: "X-Debug: 0"},
+			want:  false,
+		},
+		{
+			name:  "any matching condition is enough",
+			route: db.Route{CaptureMinStatus: 500, CaptureMinDurationMs: 100},
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, hasCaptureConditions(tt.route))
+			assert.Equal(t, tt.want, matchesCaptureConditions(tt.route, reqLog, resLog))
+		})
+	}
+
+	t.Run("a route without conditions has none", func(t *testing.T) {
+		assert.False(t, hasCaptureConditions(db.Route{CaptureHeader: "  "}))
+	})
+}
+
+func TestShouldCapture(t *testing.T) {
+	route := db.Route{ID: "r", SampleMode: sampleModePercent, SamplePercent: 0}
+	ls := &LogStorer{sampler: newSampler(), skipped: newSkippedRequests(10)}
+
+	tests := []struct {
+		name  string
+		entry *logEntry
+		want  bool
+	}{
+		{
+			name: "complete entry sampled out",
+			entry: &logEntry{
+				route:    route,
+				request:  &gateway.RequestLog{},
+				response: &gateway.ResponseLog{},
+			},
+			want: false,
+		},
+		{
+			name: "replay",
+			entry: &logEntry{
+				route:    route,
+				request:  &gateway.RequestLog{ReplayBatch: "batch"},
+				response: &gateway.ResponseLog{},
+			},
+			want: true,
+		},
+		{
+			name:  "request only",
+			entry: &logEntry{route: route, request: &gateway.RequestLog{}},
+			want:  true,
+		},
+		{
+			name:  "response only",
+			entry: &logEntry{route: route, response: &gateway.ResponseLog{}},
+			want:  true,
+		},
+		{
+			name:  "mirror",
+			entry: &logEntry{route: route, mirror: &gateway.MirrorLog{RequestID: "mirrored"}},
+			want:  true,
+		},
+		{
+			name: "complete entry not matching a capture condition",
+			entry: &logEntry{
+				route:    db.Route{ID: "r", CaptureMinStatus: 500},
+				request:  &gateway.RequestLog{},
+				response: &gateway.ResponseLog{StatusCode: 200},
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ls.shouldCapture(tt.entry))
+		})
+	}
+}
+
+func TestShouldCaptureMirrorOfSkippedRequest(t *testing.T) {
+	route := db.Route{ID: "r", SampleMode: sampleModePercent, SamplePercent: 0}
+	ls := &LogStorer{sampler: newSampler(), skipped: newSkippedRequests(10)}
+
+	assert.False(t, ls.shouldCapture(&logEntry{
+		route:    route,
+		request:  &gateway.RequestLog{RequestID: "skipped"},
+		response: &gateway.ResponseLog{RequestID: "skipped"},
+	}))
+
+	assert.False(t, ls.shouldCapture(&logEntry{route: route, mirror: &gateway.MirrorLog{RequestID: "skipped"}}))
+	assert.True(t, ls.shouldCapture(&logEntry{route: route, mirror: &gateway.MirrorLog{RequestID: "other"}}))
+}
+
+func TestSkippedRequests(t *testing.T) {
+	skipped := newSkippedRequests(2)
+
+	skipped.add("a")
+	skipped.add("b")
+	skipped.add("a")
+	assert.True(t, skipped.contains("a"))
+	assert.True(t, skipped.contains("b"))
+
+	skipped.add("c")
+	assert.False(t, skipped.contains("a"), "the oldest ID is forgotten")
+	assert.True(t, skipped.contains("b"))
+	assert.True(t, skipped.contains("c"))
+	assert.False(t, skipped.contains("d"))
+}
@@ -0,0 +1,236 @@
+package logstorer
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/uforg/ufogateway/internal/bodycodec"
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/gateway"
+	"github.com/uforg/ufogateway/internal/redact"
+)
+
+// writeBatch builds the records of the captured entries and writes them in a
+// single transaction.
+func (ls *LogStorer) writeBatch(batch []*logEntry) {
+	requests := make([]db.RequestEntry, 0, len(batch))
+	mirrors := []db.MirrorResponseEntry{}
+
+	for _, entry := range batch {
+		if !ls.shouldCapture(entry) {
+			ls.sampledOut.Add(1)
+			continue
+		}
+		if entry.mirror != nil {
+			mirrors = append(mirrors, ls.mirrorResponseEntry(entry))
+			continue
+		}
+		requests = append(requests, ls.requestEntry(entry))
+	}
+
+	entries := len(requests) + len(mirrors)
+	if entries == 0 {
+		return
+	}
+
+	saved, err := ls.db.SaveLogEntries(requests, mirrors)
+	ls.written.Add(uint64(saved))
+	ls.failed.Add(uint64(entries - saved))
+	if err != nil {
+		ls.app.Logger().Error(
+			"failed to write log entries",
+			"fn", "writeBatch",
+			"entries", entries,
+			"failed", entries-saved,
+			"error", err,
+		)
+	}
+}
+
+// requestEntry builds the requests record of an entry, applying the storage
+// options and the redaction rules of its route.
+func (ls *LogStorer) requestEntry(entry *logEntry) db.RequestEntry {
+	route := entry.route
+	rules := ls.redactionRulesFor(route)
+	redactions := redact.Marker{}
+
+	requestEntry := db.RequestEntry{
+		ID:      entry.requestID(),
+		RouteID: route.ID,
+	}
+
+	if reqLog := entry.request; reqLog != nil {
+		requestEntry.HasRequest = true
+		requestEntry.ReqTimestamp = reqLog.Timestamp
+		requestEntry.ReqIP = reqLog.RequestIP
+		requestEntry.ReqMethod = reqLog.RequestMethod
+		requestEntry.ReqBodySize = reqLog.RequestBodySize
+		requestEntry.ReqBlockReason = reqLog.BlockReason
+		requestEntry.ReplayOf = reqLog.ReplayOf
+		requestEntry.ReplayBatch = reqLog.ReplayBatch
+		requestEntry.TraceID = reqLog.TraceID
+
+		var masked []string
+		requestEntry.ContractFindings, masked = maskContractFindings(reqLog.ContractFindings, rules)
+		redactions.Add("contract_findings", masked)
+		requestEntry.ReqGatewayURL, masked = rules.MaskURL(reqLog.RequestGatewayURL)
+		redactions.Add("req_gateway_url", masked)
+		requestEntry.ReqOriginURL, masked = rules.MaskURL(reqLog.RequestOriginURL)
+		redactions.Add("req_origin_url", masked)
+
+		if route.StoreReqHeaders {
+			requestEntry.ReqHeaders, masked = rules.MaskHeaders(reqLog.RequestHeaders)
+			redactions.Add("req_headers", masked)
+		}
+
+		if route.StoreReqBody {
+			requestEntry.ReqBody = entry.reqBody.body
+			redactions.Add("req_body", entry.reqBody.masked)
+		}
+	}
+
+	if resLog := entry.response; resLog != nil {
+		requestEntry.HasResponse = true
+		requestEntry.ResTimestamp = resLog.Timestamp
+		requestEntry.ResDuration = resLog.Duration
+		requestEntry.ResStatus = resLog.StatusCode
+		requestEntry.ResCacheHit = resLog.CacheHit
+		requestEntry.ResCoalescedWith = resLog.CoalescedWith
+		requestEntry.ResCoalescedFollowers = resLog.Followers
+		requestEntry.ResClientEncoding = resLog.ClientEncoding
+		requestEntry.ErrorKind = resLog.ErrorKind
+		if resLog.UpstreamTimings != nil {
+			requestEntry.ResTimings = upstreamTimingsToMap(*resLog.UpstreamTimings)
+		}
+
+		findings, masked := maskContractFindings(resLog.ContractFindings, rules)
+		requestEntry.ContractFindings = append(requestEntry.ContractFindings, findings...)
+		redactions.Add("contract_findings", masked)
+
+		if resLog.UpstreamError != nil {
+			upstreamError := *resLog.UpstreamError
+			upstreamError.Message, masked = rules.MaskMessage(upstreamError.Message)
+			requestEntry.UpstreamError = upstreamError
+			redactions.Add("upstream_error", masked)
+		}
+
+		if route.StoreResHeaders {
+			requestEntry.ResHeaders, masked = rules.MaskHeaders(resLog.ResponseHeaders)
+			redactions.Add("res_headers", masked)
+		}
+
+		if route.StoreResBody {
+			requestEntry.ResBody = entry.resBody.body
+			redactions.Add("res_body", entry.resBody.masked)
+		}
+	}
+
+	requestEntry.Redactions = redactions
+	return requestEntry
+}
+
+// mirrorResponseEntry builds the mirror_responses record of a mirror log,
+// applying the storage options and the redaction rules of its route.
+func (ls *LogStorer) mirrorResponseEntry(entry *logEntry) db.MirrorResponseEntry {
+	route, mirrorLog := entry.route, *entry.mirror
+	rules := ls.redactionRulesFor(route)
+	redactions := redact.Marker{}
+
+	mirrorEntry := db.MirrorResponseEntry{
+		RouteID:     mirrorLog.RouteID,
+		RequestID:   mirrorLog.RequestID,
+		ResStatus:   mirrorLog.StatusCode,
+		ResDuration: mirrorLog.Duration,
+		ResHeaders:  map[string][]string{},
+	}
+
+	var masked []string
+	mirrorEntry.MirrorURL, masked = rules.MaskURL(mirrorLog.MirrorURL)
+	redactions.Add("mirror_url", masked)
+	mirrorEntry.Error, masked = rules.MaskMessage(mirrorLog.Error)
+	redactions.Add("error", masked)
+
+	if route.StoreResHeaders {
+		mirrorEntry.ResHeaders, masked = rules.MaskHeaders(mirrorLog.ResponseHeaders)
+		redactions.Add("res_headers", masked)
+	}
+
+	if route.StoreResBody {
+		mirrorEntry.ResBody = entry.resBody.body
+		redactions.Add("res_body", entry.resBody.masked)
+	}
+
+	mirrorEntry.Redactions = redactions
+	return mirrorEntry
+}
+
+// body reads a logged body and prepares it for storage, it returns nil when
+// the body can't be read or is larger than maxBytes.
+func (ls *LogStorer) body(
+	reader io.Reader,
+	headers map[string][]string,
+	maxBytes int,
+	rules redact.Rules,
+	requestID string,
+) (*db.Body, []string) {
+	bodyBytes, err := io.ReadAll(reader)
+	if err != nil {
+		ls.app.Logger().Error(
+			"failed to read body",
+			"fn", "body",
+			"request_id", requestID,
+			"error", err,
+		)
+		return nil, nil
+	}
+
+	data, meta, err := bodycodec.ForStorage(
+		bodyBytes,
+		http.Header(headers).Get("Content-Encoding"),
+		http.Header(headers).Get("Content-Type"),
+		maxBytes,
+	)
+	if errors.Is(err, bodycodec.ErrTooLarge) {
+		return nil, nil
+	}
+
+	data, masked := rules.MaskBody(data, meta.ContentType)
+	return &db.Body{Data: data, Meta: meta}, masked
+}
+
+// maskContractFindings converts the findings to the type accepted by
+// db.RequestEntry, masking their messages since they can quote the invalid
+// value. The whole message is masked when it is about a masked header, field
+// or JSON path.
+func maskContractFindings(findings []gateway.ContractFinding, rules redact.Rules) ([]any, []string) {
+	items := make([]any, 0, len(findings))
+	masked := []string{}
+	for _, finding := range findings {
+		if part, ok := rules.MaskedLocation(finding.Location); ok {
+			finding.Message = redact.Mask
+			masked = append(masked, part)
+		} else {
+			var parts []string
+			finding.Message, parts = rules.MaskMessage(finding.Message)
+			masked = append(masked, parts...)
+		}
+		items = append(items, finding)
+	}
+	return items, masked
+}
+
+// upstreamTimingsToMap converts the timings to the stored JSON object, with
+// the durations in microseconds.
+func upstreamTimingsToMap(timings gateway.UpstreamTimings) map[string]any {
+	return map[string]any{
+		"dns_us":       timings.DNS.Microseconds(),
+		"connect_us":   timings.Connect.Microseconds(),
+		"tls_us":       timings.TLS.Microseconds(),
+		"wait_us":      timings.Wait.Microseconds(),
+		"transfer_us":  timings.Transfer.Microseconds(),
+		"total_us":     timings.Total.Microseconds(),
+		"conn_reused":  timings.ConnReused,
+		"conn_idle_us": timings.ConnIdle.Microseconds(),
+	}
+}
@@ -0,0 +1,28 @@
+package logstorer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uforg/ufogateway/internal/gateway"
+	"github.com/uforg/ufogateway/internal/redact"
+)
+
+func TestMaskContractFindings(t *testing.T) {
+	findings := []gateway.ContractFinding{
+		{Kind: "request", Location: "query.limit", Message: "number must be at most 100"},
+		{Kind: "request", Location: "query.api_key", Message: "value sk_123: string is too short"},
+		{Kind: "request", Location: "body/user/password", Message: `value "hunter2" doesn't match the pattern`},
+		{Kind: "request", Location: "body/card", Message: "value 4111111111111111 is not an integer"},
+	}
+
+	got, masked := maskContractFindings(findings, redact.DefaultRules())
+
+	assert.Equal(t, []any{
+		gateway.ContractFinding{Kind: "request", Location: "query.limit", Message: "number must be at most 100"},
+		gateway.ContractFinding{Kind: "request", Location: "query.api_key", Message: "[REDACTED]"},
+		gateway.ContractFinding{Kind: "request", Location: "body/user/password", Message: "[REDACTED]"},
+		gateway.ContractFinding{Kind: "request", Location: "body/card", Message: "value [REDACTED] is not an integer"},
+	}, got)
+	assert.Equal(t, []string{"api_key", "$.user.password", "pattern:card_number"}, masked)
+}
@@ -0,0 +1,355 @@
+package logstorer
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/gateway"
+)
+
+// pendingTimeout is how long a request waits for its response before it is
+// written on its own, the response then updates the written record.
+const pendingTimeout = 30 * time.Second
+
+// logEntry is everything logged for a request, or for a mirrored request.
+// The bodies of the logs are dropped once they are prepared for storage.
+type logEntry struct {
+	route    db.Route
+	request  *gateway.RequestLog
+	response *gateway.ResponseLog
+	mirror   *gateway.MirrorLog
+	reqBody  preparedBody // is the request body to store
+	resBody  preparedBody // is the response body to store, or the mirror one
+	queuedAt time.Time
+	size     int         // is the size of the bodies counted in the queue
+	mirrors  []*logEntry // are the mirror entries that arrived while the request waited for its response
+}
+
+// preparedBody is a body ready for storage along with what was masked in it.
+type preparedBody struct {
+	body   *db.Body
+	masked []string
+}
+
+func (b preparedBody) size() int {
+	if b.body == nil {
+		return 0
+	}
+	return len(b.body.Data)
+}
+
+// requestID returns the ID of the request the entry belongs to.
+func (e *logEntry) requestID() string {
+	switch {
+	case e.request != nil:
+		return e.request.RequestID
+	case e.response != nil:
+		return e.response.RequestID
+	default:
+		return e.mirror.RequestID
+	}
+}
+
+// queueItem is a log entry waiting in the queue, or a flush marker when
+// flushed is set.
+type queueItem struct {
+	entry   *logEntry
+	flushed chan struct{}
+}
+
+// logQueue is a bounded queue of log entries. A single goroutine pairs the
+// request and response entries of each request into one entry and groups the
+// entries in batches, which are handed to the writer goroutines.
+//
+// The entries of a request always go to the same writer, so a response or
+// mirror written after its request is never written before it.
+//
+// The queue is also bounded by the size of the bodies of its entries, which
+// are counted until they are written. When the bodies fill the queue the
+// requests waiting for their response are written without waiting for it.
+type logQueue struct {
+	config Config
+	write  func(batch []*logEntry)
+
+	items   chan queueItem
+	batches []chan []*logEntry // are the batches of each writer
+
+	closedMu sync.RWMutex
+	closed   bool
+	workers  sync.WaitGroup
+
+	writingMu   sync.Mutex
+	writingCond *sync.Cond
+	writing     int // batches handed to the writers and not written yet
+
+	bytesMu   sync.Mutex
+	bytesCond *sync.Cond
+	bytes     int // size of the bodies of the entries not written yet
+
+	enqueued atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+func newLogQueue(config Config, write func(batch []*logEntry)) *logQueue {
+	q := &logQueue{
+		config:  config,
+		write:   write,
+		items:   make(chan queueItem, config.QueueSize),
+		batches: make([]chan []*logEntry, config.Workers),
+	}
+	q.writingCond = sync.NewCond(&q.writingMu)
+	q.bytesCond = sync.NewCond(&q.bytesMu)
+
+	q.workers.Add(config.Workers)
+	for i := range q.batches {
+		q.batches[i] = make(chan []*logEntry, 1)
+		go q.runWriter(q.batches[i])
+	}
+	go q.runAssembler()
+
+	return q
+}
+
+// enqueue adds the entry to the queue. When the queue is full it waits for
+// room, or drops the entry if the queue is configured to drop on full. An
+// entry enqueued after the queue is closed is dropped.
+func (q *logQueue) enqueue(entry *logEntry) {
+	q.closedMu.RLock()
+	defer q.closedMu.RUnlock()
+
+	if q.closed {
+		q.dropped.Add(1)
+		return
+	}
+
+	entry.queuedAt = time.Now()
+	entry.size = entry.reqBody.size() + entry.resBody.size()
+	item := queueItem{entry: entry}
+
+	if !q.reserveBytes(entry.size) {
+		q.dropped.Add(1)
+		return
+	}
+
+	if !q.config.DropOnFull {
+		q.items <- item
+		q.enqueued.Add(1)
+		return
+	}
+
+	select {
+	case q.items <- item:
+		q.enqueued.Add(1)
+	default:
+		q.releaseBytes(entry.size)
+		q.dropped.Add(1)
+	}
+}
+
+// reserveBytes counts size bytes of bodies in the queue. When they don't fit
+// it waits for room, or returns false if the queue is configured to drop on
+// full. An entry larger than the whole queue is let in when the queue holds
+// no bodies, so it doesn't wait forever.
+func (q *logQueue) reserveBytes(size int) bool {
+	q.bytesMu.Lock()
+	defer q.bytesMu.Unlock()
+
+	for q.bytes > 0 && q.bytes+size > q.config.QueueMaxBytes {
+		if q.config.DropOnFull {
+			return false
+		}
+		q.bytesCond.Wait()
+	}
+	q.bytes += size
+	return true
+}
+
+// releaseBytes stops counting size bytes of bodies in the queue.
+func (q *logQueue) releaseBytes(size int) {
+	if size == 0 {
+		return
+	}
+	q.bytesMu.Lock()
+	q.bytes -= size
+	q.bytesCond.Broadcast()
+	q.bytesMu.Unlock()
+}
+
+// bytesFull reports whether the bodies fill the queue.
+func (q *logQueue) bytesFull() bool {
+	q.bytesMu.Lock()
+	defer q.bytesMu.Unlock()
+	return q.bytes >= q.config.QueueMaxBytes
+}
+
+// flush waits until every entry enqueued before the call is written,
+// including requests still waiting for their response.
+func (q *logQueue) flush() {
+	flushed := make(chan struct{})
+
+	q.closedMu.RLock()
+	if q.closed {
+		q.closedMu.RUnlock()
+		return
+	}
+	q.items <- queueItem{flushed: flushed}
+	q.closedMu.RUnlock()
+
+	<-flushed
+
+	q.writingMu.Lock()
+	for q.writing > 0 {
+		q.writingCond.Wait()
+	}
+	q.writingMu.Unlock()
+}
+
+// close writes every queued entry and stops the goroutines of the queue.
+// It is safe to call more than once.
+func (q *logQueue) close() {
+	q.closedMu.Lock()
+	if q.closed {
+		q.closedMu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.items)
+	q.closedMu.Unlock()
+
+	q.workers.Wait()
+}
+
+// queued returns the number of entries waiting in the queue.
+func (q *logQueue) queued() int {
+	return len(q.items)
+}
+
+// queuedBytes returns the size of the bodies of the entries not written yet.
+func (q *logQueue) queuedBytes() int {
+	q.bytesMu.Lock()
+	defer q.bytesMu.Unlock()
+	return q.bytes
+}
+
+func (q *logQueue) runAssembler() {
+	ticker := time.NewTicker(q.config.FlushInterval)
+	defer ticker.Stop()
+
+	pending := map[string]*logEntry{}
+	batches := make([][]*logEntry, len(q.batches))
+
+	dispatchTo := func(writer int) {
+		if len(batches[writer]) == 0 {
+			return
+		}
+		q.writingMu.Lock()
+		q.writing++
+		q.writingMu.Unlock()
+
+		q.batches[writer] <- batches[writer]
+		batches[writer] = nil
+	}
+	dispatch := func() {
+		for writer := range batches {
+			dispatchTo(writer)
+		}
+	}
+	// add moves the entry to the batch of its writer, followed by its mirror
+	// entries so that they are written once the entry is captured or not
+	add := func(entry *logEntry) {
+		writer := q.writerFor(entry.requestID())
+		batches[writer] = append(batches[writer], entry)
+		batches[writer] = append(batches[writer], entry.mirrors...)
+		entry.mirrors = nil
+		if len(batches[writer]) >= q.config.BatchSize {
+			dispatchTo(writer)
+		}
+	}
+	// addPending moves the requests waiting for their response since before
+	// the given time to the batch, all of them when the time is zero
+	addPending := func(queuedBefore time.Time) {
+		for id, entry := range pending {
+			if queuedBefore.IsZero() || entry.queuedAt.Before(queuedBefore) {
+				delete(pending, id)
+				add(entry)
+			}
+		}
+	}
+
+	for {
+		select {
+		case item, ok := <-q.items:
+			if !ok {
+				addPending(time.Time{})
+				dispatch()
+				for _, writerBatches := range q.batches {
+					close(writerBatches)
+				}
+				return
+			}
+
+			entry := item.entry
+			switch {
+			case item.flushed != nil:
+				addPending(time.Time{})
+				dispatch()
+				close(item.flushed)
+			case entry.request != nil:
+				pending[entry.requestID()] = entry
+			case entry.response != nil:
+				if request, ok := pending[entry.requestID()]; ok {
+					delete(pending, entry.requestID())
+					request.response = entry.response
+					request.resBody = entry.resBody
+					request.size += entry.size
+					add(request)
+				} else {
+					add(entry)
+				}
+			default:
+				if request, ok := pending[entry.requestID()]; ok {
+					request.mirrors = append(request.mirrors, entry)
+				} else {
+					add(entry)
+				}
+			}
+
+		case now := <-ticker.C:
+			if q.bytesFull() {
+				addPending(time.Time{})
+			} else {
+				addPending(now.Add(-pendingTimeout))
+			}
+			dispatch()
+		}
+	}
+}
+
+// writerFor returns the writer of the entries of a request.
+func (q *logQueue) writerFor(requestID string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(requestID))
+	return int(h.Sum32() % uint32(len(q.batches)))
+}
+
+func (q *logQueue) runWriter(batches <-chan []*logEntry) {
+	defer q.workers.Done()
+
+	for batch := range batches {
+		q.write(batch)
+
+		size := 0
+		for _, entry := range batch {
+			size += entry.size
+		}
+		q.releaseBytes(size)
+
+		q.writingMu.Lock()
+		q.writing--
+		q.writingCond.Broadcast()
+		q.writingMu.Unlock()
+	}
+}
@@ -0,0 +1,295 @@
+package logstorer
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/gateway"
+)
+
+// batchRecorder records the batches written by a logQueue.
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]*logEntry
+	block   chan struct{} // when set, writes wait until it is closed
+}
+
+func (r *batchRecorder) write(batch []*logEntry) {
+	if r.block != nil {
+		<-r.block
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, batch)
+}
+
+func (r *batchRecorder) entries() []*logEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := []*logEntry{}
+	for _, batch := range r.batches {
+		entries = append(entries, batch...)
+	}
+	return entries
+}
+
+// entriesByRequestID groups the written entries by request, keeping the order
+// in which they were written.
+func entriesByRequestID(entries []*logEntry) map[string][]*logEntry {
+	grouped := map[string][]*logEntry{}
+	for _, entry := range entries {
+		grouped[entry.requestID()] = append(grouped[entry.requestID()], entry)
+	}
+	return grouped
+}
+
+func mirrorEntryWithBody(id string, size int) *logEntry {
+	return &logEntry{
+		mirror:  &gateway.MirrorLog{RequestID: id},
+		resBody: preparedBody{body: &db.Body{Data: make([]byte, size)}},
+	}
+}
+
+func requestEntryFor(id string) *logEntry {
+	return &logEntry{request: &gateway.RequestLog{RequestID: id}}
+}
+
+func responseEntryFor(id string) *logEntry {
+	return &logEntry{response: &gateway.ResponseLog{RequestID: id}}
+}
+
+func TestLogQueue(t *testing.T) {
+	config := Config{
+		QueueSize:     10,
+		QueueMaxBytes: 1 << 20,
+		BatchSize:     100,
+		FlushInterval: time.Hour,
+		Workers:       2,
+	}
+
+	t.Run("pairs the request and response of a request", func(t *testing.T) {
+		recorder := &batchRecorder{}
+		q := newLogQueue(config, recorder.write)
+		defer q.close()
+
+		q.enqueue(requestEntryFor("a"))
+		q.enqueue(requestEntryFor("b"))
+		q.enqueue(responseEntryFor("a"))
+		q.enqueue(&logEntry{mirror: &gateway.MirrorLog{RequestID: "a"}})
+		q.flush()
+
+		entries := entriesByRequestID(recorder.entries())
+		require.Len(t, entries["a"], 2)
+		assert.NotNil(t, entries["a"][0].request)
+		assert.NotNil(t, entries["a"][0].response)
+		assert.NotNil(t, entries["a"][1].mirror)
+		require.Len(t, entries["b"], 1)
+		assert.Nil(t, entries["b"][0].response)
+	})
+
+	t.Run("writes the mirror entries of a request after its response", func(t *testing.T) {
+		recorder := &batchRecorder{}
+		q := newLogQueue(config, recorder.write)
+		defer q.close()
+
+		q.enqueue(requestEntryFor("a"))
+		q.enqueue(&logEntry{mirror: &gateway.MirrorLog{RequestID: "a"}})
+		q.enqueue(&logEntry{mirror: &gateway.MirrorLog{RequestID: "b"}})
+		q.enqueue(responseEntryFor("a"))
+		q.flush()
+
+		entries := entriesByRequestID(recorder.entries())
+		require.Len(t, entries["a"], 2)
+		assert.NotNil(t, entries["a"][0].response)
+		assert.NotNil(t, entries["a"][1].mirror)
+		assert.Len(t, entries["b"], 1)
+	})
+
+	t.Run("writes the entries of a request in order", func(t *testing.T) {
+		recorder := &batchRecorder{}
+		orderConfig := config
+		orderConfig.BatchSize = 1
+		orderConfig.Workers = 4
+		q := newLogQueue(orderConfig, recorder.write)
+		defer q.close()
+
+		ids := []string{}
+		for i := range 50 {
+			id := fmt.Sprintf("req%d", i)
+			ids = append(ids, id)
+			q.enqueue(&logEntry{mirror: &gateway.MirrorLog{RequestID: id}})
+			q.enqueue(responseEntryFor(id))
+		}
+		q.flush()
+
+		entries := entriesByRequestID(recorder.entries())
+		for _, id := range ids {
+			require.Len(t, entries[id], 2)
+			assert.NotNil(t, entries[id][0].mirror, id)
+			assert.NotNil(t, entries[id][1].response, id)
+		}
+	})
+
+	t.Run("writes a response whose request was already written on its own", func(t *testing.T) {
+		recorder := &batchRecorder{}
+		q := newLogQueue(config, recorder.write)
+		defer q.close()
+
+		q.enqueue(requestEntryFor("a"))
+		q.flush()
+		q.enqueue(responseEntryFor("a"))
+		q.flush()
+
+		entries := recorder.entries()
+		require.Len(t, entries, 2)
+		assert.Nil(t, entries[0].response)
+		assert.Nil(t, entries[1].request)
+		assert.NotNil(t, entries[1].response)
+	})
+
+	t.Run("writes full batches", func(t *testing.T) {
+		recorder := &batchRecorder{}
+		batchConfig := config
+		batchConfig.BatchSize = 2
+		q := newLogQueue(batchConfig, recorder.write)
+		defer q.close()
+
+		for _, id := range []string{"a", "b", "c"} {
+			q.enqueue(requestEntryFor(id))
+			q.enqueue(responseEntryFor(id))
+		}
+		q.flush()
+
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		require.Len(t, recorder.batches, 2)
+		assert.ElementsMatch(t, []int{2, 1}, []int{len(recorder.batches[0]), len(recorder.batches[1])})
+	})
+
+	t.Run("writes partial batches every flush interval", func(t *testing.T) {
+		recorder := &batchRecorder{}
+		intervalConfig := config
+		intervalConfig.FlushInterval = 10 * time.Millisecond
+		q := newLogQueue(intervalConfig, recorder.write)
+		defer q.close()
+
+		q.enqueue(requestEntryFor("a"))
+		q.enqueue(responseEntryFor("a"))
+
+		assert.Eventually(t, func() bool {
+			return len(recorder.entries()) == 1
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("drops entries when full", func(t *testing.T) {
+		recorder := &batchRecorder{block: make(chan struct{})}
+		dropConfig := Config{
+			QueueSize:     1,
+			QueueMaxBytes: 1 << 20,
+			BatchSize:     1,
+			FlushInterval: time.Hour,
+			Workers:       1,
+			DropOnFull:    true,
+		}
+		q := newLogQueue(dropConfig, recorder.write)
+
+		for range 20 {
+			q.enqueue(&logEntry{mirror: &gateway.MirrorLog{}})
+		}
+
+		assert.Positive(t, q.dropped.Load())
+		assert.Equal(t, uint64(20), q.enqueued.Load()+q.dropped.Load())
+
+		close(recorder.block)
+		q.close()
+		assert.Len(t, recorder.entries(), int(q.enqueued.Load()))
+	})
+
+	t.Run("drops entries when the bodies fill the queue", func(t *testing.T) {
+		recorder := &batchRecorder{block: make(chan struct{})}
+		bytesConfig := config
+		bytesConfig.QueueMaxBytes = 10
+		bytesConfig.DropOnFull = true
+		q := newLogQueue(bytesConfig, recorder.write)
+
+		q.enqueue(mirrorEntryWithBody("a", 6))
+		q.enqueue(mirrorEntryWithBody("b", 6))
+		q.enqueue(mirrorEntryWithBody("c", 4))
+
+		assert.Equal(t, uint64(1), q.dropped.Load())
+		assert.Equal(t, 10, q.queuedBytes())
+
+		close(recorder.block)
+		q.close()
+		assert.Len(t, recorder.entries(), 2)
+		assert.Equal(t, 0, q.queuedBytes())
+	})
+
+	t.Run("waits for room for the bodies", func(t *testing.T) {
+		recorder := &batchRecorder{}
+		bytesConfig := config
+		bytesConfig.QueueMaxBytes = 10
+		bytesConfig.BatchSize = 1
+		q := newLogQueue(bytesConfig, recorder.write)
+		defer q.close()
+
+		for _, id := range []string{"a", "b", "c", "d"} {
+			q.enqueue(mirrorEntryWithBody(id, 6))
+		}
+		q.flush()
+
+		assert.Len(t, recorder.entries(), 4)
+		assert.Zero(t, q.dropped.Load())
+		assert.Equal(t, 0, q.queuedBytes())
+	})
+
+	t.Run("writes pending requests when the bodies fill the queue", func(t *testing.T) {
+		recorder := &batchRecorder{}
+		bytesConfig := config
+		bytesConfig.QueueMaxBytes = 10
+		bytesConfig.FlushInterval = 10 * time.Millisecond
+		q := newLogQueue(bytesConfig, recorder.write)
+		defer q.close()
+
+		entry := requestEntryFor("a")
+		entry.reqBody = preparedBody{body: &db.Body{Data: make([]byte, 10)}}
+		q.enqueue(entry)
+
+		assert.Eventually(t, func() bool {
+			return len(recorder.entries()) == 1
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("close writes the queued entries and drops later ones", func(t *testing.T) {
+		recorder := &batchRecorder{}
+		q := newLogQueue(config, recorder.write)
+
+		q.enqueue(requestEntryFor("a"))
+		q.close()
+		q.close()
+		q.enqueue(requestEntryFor("b"))
+		q.flush()
+
+		entries := recorder.entries()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "a", entries[0].requestID())
+		assert.Equal(t, uint64(1), q.dropped.Load())
+	})
+}
+
+func TestConfigWithDefaults(t *testing.T) {
+	config := Config{BatchSize: 5, DropOnFull: true}.withDefaults()
+
+	defaults := DefaultConfig()
+	assert.Equal(t, defaults.QueueSize, config.QueueSize)
+	assert.Equal(t, defaults.QueueMaxBytes, config.QueueMaxBytes)
+	assert.Equal(t, 5, config.BatchSize)
+	assert.Equal(t, defaults.FlushInterval, config.FlushInterval)
+	assert.Equal(t, defaults.Workers, config.Workers)
+	assert.True(t, config.DropOnFull)
+}
@@ -2,180 +2,248 @@ package logstorer
 
 import (
 	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/uforg/ufogateway/internal/db"
 	"github.com/uforg/ufogateway/internal/gateway"
 )
 
+// Config configures the queue between the gateway and the database.
+type Config struct {
+	QueueSize     int           // Maximum number of logs waiting to be written
+	QueueMaxBytes int           // Maximum size of the bodies of the logs waiting to be written
+	BatchSize     int           // Maximum number of records written in one transaction
+	FlushInterval time.Duration // Maximum time a log waits for its batch to fill up
+	Workers       int           // Number of goroutines writing batches
+	DropOnFull    bool          // Whether to drop logs when the queue is full instead of waiting for room
+}
+
+// DefaultConfig returns the configuration used for the zero values of a
+// Config.
+func DefaultConfig() Config {
+	return Config{
+		QueueSize:     10000,
+		QueueMaxBytes: 64 << 20,
+		BatchSize:     200,
+		FlushInterval: time.Second,
+		Workers:       2,
+	}
+}
+
+// withDefaults returns the config with the default value for every field
+// that is not set.
+func (c Config) withDefaults() Config {
+	defaults := DefaultConfig()
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaults.QueueSize
+	}
+	if c.QueueMaxBytes <= 0 {
+		c.QueueMaxBytes = defaults.QueueMaxBytes
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaults.BatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaults.FlushInterval
+	}
+	if c.Workers <= 0 {
+		c.Workers = defaults.Workers
+	}
+	return c
+}
+
+// Stats are the counters of the logs that went through the LogStorer.
+type Stats struct {
+	Enqueued    uint64 `json:"enqueued"`     // Logs added to the queue
+	Dropped     uint64 `json:"dropped"`      // Logs dropped because the queue was full or closed
+	Written     uint64 `json:"written"`      // Records written to the database
+	Failed      uint64 `json:"failed"`       // Records that failed to be written
+	SampledOut  uint64 `json:"sampled_out"`  // Requests not written because of the route sampling or capture conditions
+	Queued      int    `json:"queued"`       // Logs currently waiting in the queue
+	QueuedBytes int    `json:"queued_bytes"` // Size of the bodies of the logs waiting to be written
+}
+
+// LogStorer stores the gateway logs in the database.
+//
+// The bodies the routes store are prepared, decoded and redacted before the
+// logs are added to an in-memory queue, and the rest of the bodies and the
+// headers the routes don't store are dropped. Worker goroutines pair the
+// request and response logs of each request, build the complete record and
+// write the records in batches, one transaction per batch.
 type LogStorer struct {
-	app *pocketbase.PocketBase
-	db  *db.DB
+	app    *pocketbase.PocketBase
+	db     *db.DB
+	config *Config
+
+	queueOnce sync.Once
+	queue     *logQueue
+
+	sampler    *sampler
+	skipped    *skippedRequests
+	written    atomic.Uint64
+	failed     atomic.Uint64
+	sampledOut atomic.Uint64
+
+	redactionRulesMu sync.Mutex
+	redactionRules   map[string]projectRedactionRules
 }
 
+// NewLogStorer returns a LogStorer configured by config, which is read when
+// the LogStorer is first used so it can be filled in by command flags.
 func NewLogStorer(
 	app *pocketbase.PocketBase,
 	db *db.DB,
+	config *Config,
 ) *LogStorer {
 	return &LogStorer{
-		app: app,
-		db:  db,
+		app:            app,
+		db:             db,
+		config:         config,
+		sampler:        newSampler(),
+		skipped:        newSkippedRequests(skippedRequestsSize),
+		redactionRules: map[string]projectRedactionRules{},
 	}
 }
 
+// getQueue returns the queue of the LogStorer, starting it on the first call.
+func (ls *LogStorer) getQueue() *logQueue {
+	ls.queueOnce.Do(func() {
+		ls.queue = newLogQueue(ls.config.withDefaults(), ls.writeBatch)
+	})
+	return ls.queue
+}
+
 func (ls *LogStorer) StoreRequestLog(reqLog gateway.RequestLog) {
-	route, err := ls.db.GetRouteByIDCached(reqLog.RouteID)
-	if err != nil {
-		ls.app.Logger().Error(
-			"failed to get route by id",
-			"id", reqLog.RouteID,
-			"fn", "StoreRequestLog",
-			"error", err,
-		)
+	route, ok := ls.storedRoute(reqLog.RouteID, "StoreRequestLog")
+	if !ok {
 		return
 	}
 
-	if !route.Active || !route.StoreHits {
+	entry := &logEntry{route: route, request: &reqLog}
+	if route.StoreReqBody {
+		entry.reqBody = ls.prepareBody(
+			route,
+			reqLog.RequestBody,
+			reqLog.RequestHeaders,
+			route.StoreReqBodyMaxBytes,
+			reqLog.RequestID,
+		)
+	}
+	reqLog.RequestBody = nil
+	// The request headers are kept for the capture header condition
+	if !route.StoreReqHeaders && strings.TrimSpace(route.CaptureHeader) == "" {
+		reqLog.RequestHeaders = nil
+	}
+
+	ls.getQueue().enqueue(entry)
+}
+
+func (ls *LogStorer) StoreResponseLog(respLog gateway.ResponseLog) {
+	route, ok := ls.storedRoute(respLog.RouteID, "StoreResponseLog")
+	if !ok {
 		return
 	}
 
-	err = ls.db.CreateRequest(
-		reqLog.RequestID,
-		reqLog.RouteID,
-		reqLog.Timestamp,
-		reqLog.RequestIP,
-		reqLog.RequestMethod,
-		reqLog.RequestGatewayURL,
-		reqLog.RequestOriginURL,
-	)
-	if err != nil {
-		ls.app.Logger().Error(
-			"failed to create request",
-			"fn", "StoreRequestLog",
-			"route_id", reqLog.RouteID,
-			"request_id", reqLog.RequestID,
-			"error", err,
+	entry := &logEntry{route: route, response: &respLog}
+	if route.StoreResBody {
+		entry.resBody = ls.prepareBody(
+			route,
+			respLog.ResponseBody,
+			respLog.ResponseHeaders,
+			route.StoreResBodyMaxBytes,
+			respLog.RequestID,
 		)
-		return
 	}
+	respLog.ResponseBody = nil
+	if !route.StoreResHeaders {
+		respLog.ResponseHeaders = nil
+	}
+
+	ls.getQueue().enqueue(entry)
+}
 
-	if route.StoreReqHeaders {
-		err = ls.db.StoreRequestReqHeaders(reqLog.RequestID, reqLog.RequestHeaders)
-		if err != nil {
-			ls.app.Logger().Error(
-				"failed to store request request headers",
-				"fn", "StoreRequestLog",
-				"route_id", reqLog.RouteID,
-				"request_id", reqLog.RequestID,
-				"error", err,
-			)
-		}
+func (ls *LogStorer) StoreMirrorLog(mirrorLog gateway.MirrorLog) {
+	route, ok := ls.storedRoute(mirrorLog.RouteID, "StoreMirrorLog")
+	if !ok {
+		return
 	}
 
-	if route.StoreReqBody {
-		func() {
-			bodyBytes, err := io.ReadAll(reqLog.RequestBody)
-			if err != nil {
-				ls.app.Logger().Error(
-					"failed to read request body",
-					"fn", "StoreRequestLog",
-					"route_id", reqLog.RouteID,
-					"request_id", reqLog.RequestID,
-					"error", err,
-				)
-				return
-			}
-
-			if route.StoreReqBodyMaxBytes > 0 && len(bodyBytes) > route.StoreReqBodyMaxBytes {
-				return
-			}
-
-			err = ls.db.StoreRequestReqBody(reqLog.RequestID, string(bodyBytes))
-			if err != nil {
-				ls.app.Logger().Error(
-					"failed to store request request body",
-					"fn", "StoreRequestLog",
-					"route_id", reqLog.RouteID,
-					"request_id", reqLog.RequestID,
-					"error", err,
-				)
-			}
-		}()
+	entry := &logEntry{route: route, mirror: &mirrorLog}
+	if route.StoreResBody {
+		entry.resBody = ls.prepareBody(
+			route,
+			mirrorLog.ResponseBody,
+			mirrorLog.ResponseHeaders,
+			route.StoreResBodyMaxBytes,
+			mirrorLog.RequestID,
+		)
+	}
+	mirrorLog.ResponseBody = nil
+	if !route.StoreResHeaders {
+		mirrorLog.ResponseHeaders = nil
 	}
+
+	ls.getQueue().enqueue(entry)
 }
 
-func (ls *LogStorer) StoreResponseLog(reqLog gateway.ResponseLog) {
-	route, err := ls.db.GetRouteByIDCached(reqLog.RouteID)
-	if err != nil {
-		ls.app.Logger().Error(
-			"failed to get route by id",
-			"id", reqLog.RouteID,
-			"fn", "StoreResponseLog",
-			"error", err,
-		)
-		return
+// prepareBody reads a logged body and prepares it for storage with the
+// redaction rules of the route.
+func (ls *LogStorer) prepareBody(
+	route db.Route,
+	reader io.Reader,
+	headers map[string][]string,
+	maxBytes int,
+	requestID string,
+) preparedBody {
+	if reader == nil {
+		return preparedBody{}
 	}
 
-	if !route.Active || !route.StoreHits {
-		return
+	body, masked := ls.body(reader, headers, maxBytes, ls.redactionRulesFor(route), requestID)
+	return preparedBody{body: body, masked: masked}
+}
+
+// Flush waits until every log stored before the call is written to the
+// database.
+func (ls *LogStorer) Flush() {
+	ls.getQueue().flush()
+}
+
+// Close writes the queued logs and stops the workers, logs stored afterwards
+// are dropped.
+func (ls *LogStorer) Close() {
+	ls.getQueue().close()
+}
+
+// Stats returns the current counters of the LogStorer.
+func (ls *LogStorer) Stats() Stats {
+	return Stats{
+		Enqueued:    ls.getQueue().enqueued.Load(),
+		Dropped:     ls.getQueue().dropped.Load(),
+		Written:     ls.written.Load(),
+		Failed:      ls.failed.Load(),
+		SampledOut:  ls.sampledOut.Load(),
+		Queued:      ls.getQueue().queued(),
+		QueuedBytes: ls.getQueue().queuedBytes(),
 	}
+}
 
-	err = ls.db.StoreRequestResTimestamp(
-		reqLog.RequestID,
-		reqLog.Timestamp,
-		reqLog.Duration,
-	)
+// storedRoute returns the route of a log if the route stores its hits.
+func (ls *LogStorer) storedRoute(routeID string, fn string) (db.Route, bool) {
+	route, err := ls.db.GetRouteByIDCached(routeID)
 	if err != nil {
 		ls.app.Logger().Error(
-			"failed to store request response timestamp",
-			"fn", "StoreResponseLog",
-			"route_id", reqLog.RouteID,
-			"request_id", reqLog.RequestID,
+			"failed to get route by id",
+			"id", routeID,
+			"fn", fn,
 			"error", err,
 		)
+		return db.Route{}, false
 	}
 
-	if route.StoreResHeaders {
-		err = ls.db.StoreRequestResHeaders(reqLog.RequestID, reqLog.ResponseHeaders)
-		if err != nil {
-			ls.app.Logger().Error(
-				"failed to store request response headers",
-				"fn", "StoreResponseLog",
-				"route_id", reqLog.RouteID,
-				"request_id", reqLog.RequestID,
-				"error", err,
-			)
-		}
-	}
-
-	if route.StoreResBody {
-		func() {
-			bodyBytes, err := io.ReadAll(reqLog.ResponseBody)
-			if err != nil {
-				ls.app.Logger().Error(
-					"failed to read response body",
-					"fn", "StoreResponseLog",
-					"route_id", reqLog.RouteID,
-					"request_id", reqLog.RequestID,
-					"error", err,
-				)
-				return
-			}
-
-			if route.StoreResBodyMaxBytes > 0 && len(bodyBytes) > route.StoreResBodyMaxBytes {
-				return
-			}
-
-			err = ls.db.StoreRequestResBody(reqLog.RequestID, string(bodyBytes))
-			if err != nil {
-				ls.app.Logger().Error(
-					"failed to store request response body",
-					"fn", "StoreResponseLog",
-					"route_id", reqLog.RouteID,
-					"request_id", reqLog.RequestID,
-					"error", err,
-				)
-			}
-		}()
-	}
+	return route, route.Active && route.StoreHits
 }
@@ -0,0 +1,59 @@
+package logstorer
+
+import (
+	"time"
+
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/redact"
+)
+
+// projectRedactionRules are the parsed redaction rules of a project as of
+// its last update.
+type projectRedactionRules struct {
+	updated time.Time
+	rules   redact.Rules
+}
+
+// redactionRulesFor returns the redaction rules of the route project, the
+// default ones plus its own unless it skips the defaults. The rules are
+// parsed again only when the project changes.
+func (ls *LogStorer) redactionRulesFor(route db.Route) redact.Rules {
+	project, err := ls.db.GetProjectByIDCached(route.Project)
+	if err != nil {
+		ls.app.Logger().Error(
+			"failed to get project by id, using the default redaction rules",
+			"id", route.Project,
+			"fn", "redactionRulesFor",
+			"error", err,
+		)
+		return redact.DefaultRules()
+	}
+
+	ls.redactionRulesMu.Lock()
+	defer ls.redactionRulesMu.Unlock()
+
+	if cached, ok := ls.redactionRules[project.ID]; ok && cached.updated.Equal(project.Updated) {
+		return cached.rules
+	}
+
+	rules, err := redact.ParseRules(
+		project.RedactHeaders,
+		project.RedactJSONPaths,
+		project.RedactFormFields,
+		project.RedactPatterns,
+	)
+	if err != nil {
+		ls.app.Logger().Error(
+			"invalid project redaction rules",
+			"project_id", project.ID,
+			"fn", "redactionRulesFor",
+			"error", err,
+		)
+	}
+	if !project.RedactSkipDefaults {
+		rules = redact.DefaultRules().Merge(rules)
+	}
+
+	ls.redactionRules[project.ID] = projectRedactionRules{updated: project.Updated, rules: rules}
+	return rules
+}
@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/uforg/ufogateway/internal/gateway"
+	"github.com/uforg/ufogateway/internal/logstorer"
+)
+
+const namespace = "ufogateway"
+
+// routeLabels are the labels of the per-route metrics. Routes are labeled
+// by their id and name, never by the request path, so the number of series
+// is bounded by the number of routes.
+var routeLabels = []string{"route", "route_name"}
+
+// bodySizeBuckets are the buckets in bytes of the body size histograms, from
+// 64 B to 16 MiB.
+var bodySizeBuckets = prometheus.ExponentialBuckets(64, 4, 10)
+
+// Cache is a cache whose number of items is exposed as a metric.
+type Cache interface {
+	// Len returns the number of items in the cache.
+	Len() int
+}
+
+// Metrics collects the Prometheus metrics of the gateway traffic and of the
+// gateway internals. It implements gateway.Metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	requestsInFlight  *prometheus.GaugeVec
+	upstreamErrors    *prometheus.CounterVec
+	requestBodyBytes  *prometheus.HistogramVec
+	responseBodyBytes *prometheus.HistogramVec
+	expiredDeleted    prometheus.Counter
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Requests served by the routes, by status code.",
+		}, append(routeLabels, "code")),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time taken by the routes to serve the requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, routeLabels),
+		requestsInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "requests_in_flight",
+			Help:      "Requests being served by the routes.",
+		}, routeLabels),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_errors_total",
+			Help:      "Failed requests to the origins, by class of failure.",
+		}, append(routeLabels, "class")),
+		requestBodyBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_body_bytes",
+			Help:      "Size of the request bodies read by the routes.",
+			Buckets:   bodySizeBuckets,
+		}, routeLabels),
+		responseBodyBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "response_body_bytes",
+			Help:      "Size of the response bodies sent by the routes.",
+			Buckets:   bodySizeBuckets,
+		}, routeLabels),
+		expiredDeleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "expired_requests_deleted_total",
+			Help:      "Stored requests deleted because they were past their retention.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.requestsInFlight,
+		m.upstreamErrors,
+		m.requestBodyBytes,
+		m.responseBodyBytes,
+		m.expiredDeleted,
+	)
+	return m
+}
+
+// RequestStarted implements gateway.Metrics.
+func (m *Metrics) RequestStarted(route gateway.Route) {
+	m.requestsInFlight.WithLabelValues(route.ID, route.Name).Inc()
+}
+
+// RequestFinished implements gateway.Metrics.
+func (m *Metrics) RequestFinished(route gateway.Route, hit gateway.Hit) {
+	m.requestsInFlight.WithLabelValues(route.ID, route.Name).Dec()
+
+	m.requests.WithLabelValues(route.ID, route.Name, strconv.Itoa(hit.StatusCode)).Inc()
+	m.requestDuration.WithLabelValues(route.ID, route.Name).Observe(hit.Duration.Seconds())
+	m.requestBodyBytes.WithLabelValues(route.ID, route.Name).Observe(float64(hit.BytesIn))
+	m.responseBodyBytes.WithLabelValues(route.ID, route.Name).Observe(float64(hit.BytesOut))
+	if hit.UpstreamErrorClass != "" {
+		m.upstreamErrors.WithLabelValues(route.ID, route.Name, hit.UpstreamErrorClass).Inc()
+	}
+}
+
+// AddExpiredRequestsDeleted counts qty stored requests deleted past their
+// retention.
+func (m *Metrics) AddExpiredRequestsDeleted(qty int64) {
+	m.expiredDeleted.Add(float64(qty))
+}
+
+// ObserveLogStorer exposes the queue and the counters of the log storer.
+func (m *Metrics) ObserveLogStorer(logStorer *logstorer.LogStorer) {
+	counters := []struct {
+		name  string
+		help  string
+		value func(logstorer.Stats) uint64
+	}{
+		{"log_entries_enqueued_total", "Request logs added to the log queue.", func(s logstorer.Stats) uint64 { return s.Enqueued }},
+		{"log_entries_dropped_total", "Request logs dropped because the log queue was full or closed.", func(s logstorer.Stats) uint64 { return s.Dropped }},
+		{"log_records_written_total", "Request records written to the database.", func(s logstorer.Stats) uint64 { return s.Written }},
+		{"log_records_failed_total", "Request records that failed to be written to the database.", func(s logstorer.Stats) uint64 { return s.Failed }},
+		{"log_records_sampled_out_total", "Requests not stored because of the route sampling or capture conditions.", func(s logstorer.Stats) uint64 { return s.SampledOut }},
+	}
+	for _, counter := range counters {
+		value := counter.value
+		m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      counter.name,
+			Help:      counter.help,
+		}, func() float64 {
+			return float64(value(logStorer.Stats()))
+		}))
+	}
+
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "log_queue_depth",
+		Help:      "Request logs waiting in the log queue to be written.",
+	}, func() float64 {
+		return float64(logStorer.Stats().Queued)
+	}))
+
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "log_queue_bytes",
+		Help:      "Size of the stored bodies of the request logs waiting to be written.",
+	}, func() float64 {
+		return float64(logStorer.Stats().QueuedBytes)
+	}))
+}
+
+// ObserveCache exposes the number of items of the cache named name.
+func (m *Metrics) ObserveCache(name string, cache Cache) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "cache_items",
+		Help:        "Items in the gateway caches.",
+		ConstLabels: prometheus.Labels{"cache": name},
+	}, func() float64 {
+		return float64(cache.Len())
+	}))
+}
+
+// Handler returns the handler of the metrics endpoint. When token is not
+// empty, scrapes must send it as a bearer token.
+func (m *Metrics) Handler(token string) http.Handler {
+	handler := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uforg/ufogateway/internal/cache"
+	"github.com/uforg/ufogateway/internal/gateway"
+)
+
+func TestMetricsRequests(t *testing.T) {
+	m := NewMetrics()
+	route := gateway.Route{ID: "r1", Name: "users"}
+
+	m.RequestStarted(route)
+	m.RequestStarted(route)
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requestsInFlight.WithLabelValues("r1", "users")))
+
+	m.RequestFinished(route, gateway.Hit{RouteID: "r1", StatusCode: 200, Duration: time.Millisecond, BytesIn: 10, BytesOut: 100})
+	m.RequestFinished(route, gateway.Hit{RouteID: "r1", StatusCode: 502, UpstreamErrorClass: gateway.UpstreamErrorTimeout})
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.requestsInFlight.WithLabelValues("r1", "users")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("r1", "users", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("r1", "users", "502")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.upstreamErrors.WithLabelValues("r1", "users", "timeout")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.upstreamErrors))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.requestDuration))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.requestBodyBytes))
+}
+
+func TestMetricsHandler(t *testing.T) {
+	m := NewMetrics()
+	m.AddExpiredRequestsDeleted(3)
+
+	cacheInstance := cache.NewCacheInstance()
+	cacheInstance.Set("a", 1, time.Minute)
+	m.ObserveCache("db", cacheInstance)
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "no token", wantStatus: http.StatusOK},
+		{name: "valid token", token: "secret", authorization: "Bearer secret", wantStatus: http.StatusOK},
+		{name: "missing token", token: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", authorization: "Bearer other", wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", token: "secret", authorization: "secret", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			m.Handler(tt.token).ServeHTTP(w, r)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			body, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), "ufogateway_expired_requests_deleted_total 3")
+			assert.Contains(t, string(body), `ufogateway_cache_items{cache="db"} 1`)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(19, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text4265535555",
+			"max": 0,
+			"min": 0,
+			"name": "ip_allow_list",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(20, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text3388765464",
+			"max": 0,
+			"min": 0,
+			"name": "ip_deny_list",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(21, []byte(`{
+			"hidden": false,
+			"id": "number1852962194",
+			"max": 599,
+			"min": 0,
+			"name": "ip_block_status",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(22, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text3165188040",
+			"max": 0,
+			"min": 0,
+			"name": "ip_block_message",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("text4265535555")
+
+		// remove field
+		collection.Fields.RemoveById("text3388765464")
+
+		// remove field
+		collection.Fields.RemoveById("number1852962194")
+
+		// remove field
+		collection.Fields.RemoveById("text3165188040")
+
+		return app.Save(collection)
+	})
+}
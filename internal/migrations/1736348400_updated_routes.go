@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(23, []byte(`{
+			"hidden": false,
+			"id": "bool1406870963",
+			"name": "cache_enabled",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "bool"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(24, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text928500085",
+			"max": 0,
+			"min": 0,
+			"name": "cache_vary_headers",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(25, []byte(`{
+			"hidden": false,
+			"id": "number4211061317",
+			"max": null,
+			"min": 0,
+			"name": "cache_ttl_seconds",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("bool1406870963")
+
+		// remove field
+		collection.Fields.RemoveById("text928500085")
+
+		// remove field
+		collection.Fields.RemoveById("number4211061317")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,142 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(27, []byte(`{
+			"hidden": false,
+			"id": "select2363381545",
+			"maxSelect": 1,
+			"name": "type",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "select",
+			"values": [
+				"proxy",
+				"mock"
+			]
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(28, []byte(`{
+			"hidden": false,
+			"id": "number656107843",
+			"max": 599,
+			"min": 0,
+			"name": "mock_status",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(29, []byte(`{
+			"hidden": false,
+			"id": "json210442476",
+			"maxSize": 0,
+			"name": "mock_headers",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "json"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(30, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text3660375327",
+			"max": 0,
+			"min": 0,
+			"name": "mock_body",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(31, []byte(`{
+			"hidden": false,
+			"id": "number1202062802",
+			"max": null,
+			"min": 0,
+			"name": "mock_latency_ms",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(32, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text3197934286",
+			"max": 0,
+			"min": 0,
+			"name": "mock_path_pattern",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("select2363381545")
+
+		// remove field
+		collection.Fields.RemoveById("number656107843")
+
+		// remove field
+		collection.Fields.RemoveById("json210442476")
+
+		// remove field
+		collection.Fields.RemoveById("text3660375327")
+
+		// remove field
+		collection.Fields.RemoveById("number1202062802")
+
+		// remove field
+		collection.Fields.RemoveById("text3197934286")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// update field
+		if err := collection.Fields.AddMarshaledJSONAt(5, []byte(`{
+			"exceptDomains": [],
+			"hidden": false,
+			"id": "url220594645",
+			"name": "origin_url",
+			"onlyDomains": [],
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "url"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// update field
+		if err := collection.Fields.AddMarshaledJSONAt(5, []byte(`{
+			"exceptDomains": [],
+			"hidden": false,
+			"id": "url220594645",
+			"name": "origin_url",
+			"onlyDomains": [],
+			"presentable": false,
+			"required": true,
+			"system": false,
+			"type": "url"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(33, []byte(`{
+			"exceptDomains": [],
+			"hidden": false,
+			"id": "url1077414606",
+			"name": "mirror_url",
+			"onlyDomains": [],
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "url"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(34, []byte(`{
+			"hidden": false,
+			"id": "number2208344281",
+			"max": 100,
+			"min": 0,
+			"name": "mirror_percent",
+			"onlyInt": false,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("url1077414606")
+
+		// remove field
+		collection.Fields.RemoveById("number2208344281")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(35, []byte(`{
+			"cascadeDelete": false,
+			"collectionId": "pbc_4095957613",
+			"hidden": false,
+			"id": "relation4169485379",
+			"maxSelect": 1,
+			"minSelect": 0,
+			"name": "openapi_spec",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "relation"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(36, []byte(`{
+			"hidden": false,
+			"id": "select4290709955",
+			"maxSelect": 1,
+			"name": "contract_mode",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "select",
+			"values": [
+				"off",
+				"report",
+				"enforce"
+			]
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(37, []byte(`{
+			"hidden": false,
+			"id": "bool2413834100",
+			"name": "contract_validate_responses",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "bool"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("relation4169485379")
+
+		// remove field
+		collection.Fields.RemoveById("select4290709955")
+
+		// remove field
+		collection.Fields.RemoveById("bool2413834100")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(38, []byte(`{
+			"hidden": false,
+			"id": "number3245242979",
+			"max": null,
+			"min": 0,
+			"name": "req_body_max_bytes",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(39, []byte(`{
+			"hidden": false,
+			"id": "number3965462541",
+			"max": null,
+			"min": 0,
+			"name": "req_headers_max_bytes",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(40, []byte(`{
+			"hidden": false,
+			"id": "number3625725857",
+			"max": null,
+			"min": 0,
+			"name": "req_headers_max_count",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("number3245242979")
+
+		// remove field
+		collection.Fields.RemoveById("number3965462541")
+
+		// remove field
+		collection.Fields.RemoveById("number3625725857")
+
+		return app.Save(collection)
+	})
+}
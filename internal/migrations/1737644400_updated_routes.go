@@ -0,0 +1,101 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(41, []byte(`{
+			"hidden": false,
+			"id": "bool1525056634",
+			"name": "compress_responses",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "bool"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(42, []byte(`{
+			"hidden": false,
+			"id": "number3201493866",
+			"max": null,
+			"min": 0,
+			"name": "compress_min_bytes",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(43, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text2068253273",
+			"max": 0,
+			"min": 0,
+			"name": "compress_content_types",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(44, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text2528952349",
+			"max": 0,
+			"min": 0,
+			"name": "compress_encodings",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("bool1525056634")
+
+		// remove field
+		collection.Fields.RemoveById("number3201493866")
+
+		// remove field
+		collection.Fields.RemoveById("text2068253273")
+
+		// remove field
+		collection.Fields.RemoveById("text2528952349")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_1003195976")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(25, []byte(`{
+			"hidden": false,
+			"id": "file764172927",
+			"maxSelect": 1,
+			"maxSize": 1073741824,
+			"mimeTypes": [],
+			"name": "req_body_file",
+			"presentable": false,
+			"protected": true,
+			"required": false,
+			"system": false,
+			"thumbs": [],
+			"type": "file"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(26, []byte(`{
+			"hidden": false,
+			"id": "file1977672638",
+			"maxSelect": 1,
+			"maxSize": 1073741824,
+			"mimeTypes": [],
+			"name": "res_body_file",
+			"presentable": false,
+			"protected": true,
+			"required": false,
+			"system": false,
+			"thumbs": [],
+			"type": "file"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_1003195976")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("file764172927")
+
+		// remove field
+		collection.Fields.RemoveById("file1977672638")
+
+		return app.Save(collection)
+	})
+}
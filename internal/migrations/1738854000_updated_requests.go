@@ -0,0 +1,223 @@
+package migrations
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// res_timestamp used to be a text field holding time.Time.String() and
+// res_duration a text field holding time.Duration.String(). They become a
+// date field and a number of microseconds so they can be sorted and filtered,
+// the existing values are converted in place.
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_1003195976")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(10, []byte(`{
+			"hidden": false,
+			"id": "date153980914",
+			"max": "",
+			"min": "",
+			"name": "res_timestamp_date",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "date"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(12, []byte(`{
+			"hidden": false,
+			"id": "number20754951",
+			"max": null,
+			"min": 0,
+			"name": "res_duration_us",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		rows := []struct {
+			ID           string `db:"id"`
+			ResTimestamp string `db:"res_timestamp"`
+			ResDuration  string `db:"res_duration"`
+		}{}
+		err = app.DB().
+			Select("id", "res_timestamp", "res_duration").
+			From(collection.Name).
+			Where(dbx.Or(dbx.NewExp("res_timestamp != ''"), dbx.NewExp("res_duration != ''"))).
+			All(&rows)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			resTimestamp := ""
+			if parsed, ok := parseTimeString(row.ResTimestamp); ok {
+				dt, _ := types.ParseDateTime(parsed)
+				resTimestamp = dt.String()
+			}
+
+			resDuration, _ := time.ParseDuration(row.ResDuration)
+
+			_, err := app.DB().
+				Update(
+					collection.Name,
+					dbx.Params{
+						"res_timestamp_date": resTimestamp,
+						"res_duration_us":    resDuration.Microseconds(),
+					},
+					dbx.HashExp{"id": row.ID},
+				).
+				Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		// remove field
+		collection.Fields.RemoveById("text153980914")
+
+		// remove field
+		collection.Fields.RemoveById("text3251571947")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// rename field
+		collection.Fields.GetById("date153980914").SetName("res_timestamp")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_1003195976")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(10, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text153980914",
+			"max": 0,
+			"min": 0,
+			"name": "res_timestamp_text",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(12, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text3251571947",
+			"max": 0,
+			"min": 0,
+			"name": "res_duration",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		rows := []struct {
+			ID            string `db:"id"`
+			ResTimestamp  string `db:"res_timestamp"`
+			ResDurationUs int64  `db:"res_duration_us"`
+		}{}
+		err = app.DB().
+			Select("id", "res_timestamp", "res_duration_us").
+			From(collection.Name).
+			Where(dbx.Or(dbx.NewExp("res_timestamp != ''"), dbx.NewExp("res_duration_us != 0"))).
+			All(&rows)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			resTimestamp := ""
+			if dt, err := types.ParseDateTime(row.ResTimestamp); err == nil && !dt.IsZero() {
+				resTimestamp = dt.Time().String()
+			}
+
+			resDuration := ""
+			if row.ResDurationUs != 0 {
+				resDuration = (time.Duration(row.ResDurationUs) * time.Microsecond).String()
+			}
+
+			_, err := app.DB().
+				Update(
+					collection.Name,
+					dbx.Params{
+						"res_timestamp_text": resTimestamp,
+						"res_duration":       resDuration,
+					},
+					dbx.HashExp{"id": row.ID},
+				).
+				Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		// remove field
+		collection.Fields.RemoveById("date153980914")
+
+		// remove field
+		collection.Fields.RemoveById("number20754951")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// rename field
+		collection.Fields.GetById("text153980914").SetName("res_timestamp")
+
+		return app.Save(collection)
+	})
+}
+
+// parseTimeString parses the time.Time.String() format, which ends with
+// the monotonic clock reading for times taken with time.Now().
+func parseTimeString(s string) (time.Time, bool) {
+	s, _, _ = strings.Cut(s, " m=")
+	parsed, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
@@ -0,0 +1,143 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(46, []byte(`{
+			"hidden": false,
+			"id": "select737961446",
+			"maxSelect": 1,
+			"name": "sample_mode",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "select",
+			"values": [
+				"all",
+				"percent",
+				"one_in_n"
+			]
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(47, []byte(`{
+			"hidden": false,
+			"id": "number1912360367",
+			"max": 100,
+			"min": 0,
+			"name": "sample_percent",
+			"onlyInt": false,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(48, []byte(`{
+			"hidden": false,
+			"id": "number2348346263",
+			"max": null,
+			"min": 0,
+			"name": "sample_one_in",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(49, []byte(`{
+			"hidden": false,
+			"id": "number744962990",
+			"max": 599,
+			"min": 0,
+			"name": "capture_min_status",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(50, []byte(`{
+			"hidden": false,
+			"id": "number699527880",
+			"max": null,
+			"min": 0,
+			"name": "capture_min_duration_ms",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(51, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text3300977415",
+			"max": 0,
+			"min": 0,
+			"name": "capture_header",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("select737961446")
+
+		// remove field
+		collection.Fields.RemoveById("number1912360367")
+
+		// remove field
+		collection.Fields.RemoveById("number2348346263")
+
+		// remove field
+		collection.Fields.RemoveById("number744962990")
+
+		// remove field
+		collection.Fields.RemoveById("number699527880")
+
+		// remove field
+		collection.Fields.RemoveById("text3300977415")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,318 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": null,
+			"deleteRule": null,
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "pbc_3090596648",
+					"hidden": false,
+					"id": "relation46407801",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "route",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "pbc_484305853",
+					"hidden": false,
+					"id": "relation800313582",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "project",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "select4012098334",
+					"maxSelect": 1,
+					"name": "granularity",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "select",
+					"values": [
+						"minute",
+						"hour",
+						"day"
+					]
+				},
+				{
+					"hidden": false,
+					"id": "date3879679654",
+					"max": "",
+					"min": "",
+					"name": "bucket",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"hidden": false,
+					"id": "number2072368721",
+					"max": null,
+					"min": 0,
+					"name": "requests",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number3183414503",
+					"max": null,
+					"min": 0,
+					"name": "status_1xx",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number3220812478",
+					"max": null,
+					"min": 0,
+					"name": "status_2xx",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number3191593097",
+					"max": null,
+					"min": 0,
+					"name": "status_3xx",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number3144994316",
+					"max": null,
+					"min": 0,
+					"name": "status_4xx",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number3132531771",
+					"max": null,
+					"min": 0,
+					"name": "status_5xx",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number1327669491",
+					"max": null,
+					"min": 0,
+					"name": "bytes_in",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number204106886",
+					"max": null,
+					"min": 0,
+					"name": "bytes_out",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number3877078296",
+					"max": null,
+					"min": 0,
+					"name": "latency_sum_us",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number745304092",
+					"max": null,
+					"min": 0,
+					"name": "latency_max_us",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number1538176",
+					"max": null,
+					"min": 0,
+					"name": "latency_p50_us",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number3320288641",
+					"max": null,
+					"min": 0,
+					"name": "latency_p90_us",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number4063847859",
+					"max": null,
+					"min": 0,
+					"name": "latency_p95_us",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number3102726667",
+					"max": null,
+					"min": 0,
+					"name": "latency_p99_us",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "json2598608012",
+					"maxSize": 0,
+					"name": "latency_histogram",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "json"
+				},
+				{
+					"hidden": false,
+					"id": "bool3875784451",
+					"name": "compacted",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "bool"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_3392689412",
+			"indexes": [
+				"CREATE UNIQUE INDEX ` + "`" + `idx_route_stats_route_bucket` + "`" + ` ON ` + "`" + `route_stats` + "`" + ` (` + "`" + `route` + "`" + `, ` + "`" + `granularity` + "`" + `, ` + "`" + `bucket` + "`" + `)",
+				"CREATE INDEX ` + "`" + `idx_route_stats_project_bucket` + "`" + ` ON ` + "`" + `route_stats` + "`" + ` (` + "`" + `project` + "`" + `, ` + "`" + `granularity` + "`" + `, ` + "`" + `bucket` + "`" + `)",
+				"CREATE INDEX ` + "`" + `idx_route_stats_compacted` + "`" + ` ON ` + "`" + `route_stats` + "`" + ` (` + "`" + `granularity` + "`" + `, ` + "`" + `compacted` + "`" + `)"
+			],
+			"listRule": "@request.auth.id = project.owner.id || project.members.id ?= @request.auth.id || project.guests.id ?= @request.auth.id",
+			"name": "route_stats",
+			"system": false,
+			"type": "base",
+			"updateRule": null,
+			"viewRule": "@request.auth.id = project.owner.id || project.members.id ?= @request.auth.id || project.guests.id ?= @request.auth.id"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3392689412")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
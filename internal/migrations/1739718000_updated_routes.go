@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// statuses below 100 made the gateway panic, so they fall back to
+		// the default
+		_, err = app.DB().
+			Update(
+				collection.Name,
+				dbx.Params{"mock_status": 0},
+				dbx.NewExp("mock_status < 100 OR mock_status > 599"),
+			).
+			Execute()
+		if err != nil {
+			return err
+		}
+
+		// update field
+		if err := collection.Fields.AddMarshaledJSONAt(28, []byte(`{
+			"hidden": false,
+			"id": "number656107843",
+			"max": 599,
+			"min": 100,
+			"name": "mock_status",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3090596648")
+		if err != nil {
+			return err
+		}
+
+		// update field
+		if err := collection.Fields.AddMarshaledJSONAt(28, []byte(`{
+			"hidden": false,
+			"id": "number656107843",
+			"max": 599,
+			"min": 0,
+			"name": "mock_status",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	})
+}
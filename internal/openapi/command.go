@@ -0,0 +1,50 @@
+package openapi
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCommand returns the "import-openapi" command that creates or updates the
+// routes of a project from an OpenAPI 3 document.
+func NewCommand(importer *Importer) *cobra.Command {
+	opts := ImportOptions{}
+	var projectID, file, mode string
+
+	command := &cobra.Command{
+		Use:   "import-openapi",
+		Short: "Creates or updates the routes of a project from an OpenAPI 3 document",
+		Example: `  ufogateway import-openapi --project abc123def456ghi --file openapi.yaml --dry-run
+  ufogateway import-openapi --project abc123def456ghi --file openapi.json --mode path --endpoint-prefix /petstore`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			opts.Mode = Mode(mode)
+			result, err := importer.Import(projectID, data, opts)
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(result)
+		},
+	}
+
+	command.Flags().StringVar(&projectID, "project", "", "the id of the project to import the routes into")
+	command.Flags().StringVar(&file, "file", "", "the OpenAPI 3 document in YAML or JSON format")
+	command.Flags().StringVar(&mode, "mode", string(ModePrefix), `"prefix" for a route per first path segment or "path" for a route per path`)
+	command.Flags().StringVar(&opts.EndpointPrefix, "endpoint-prefix", "", "a prefix for the endpoint of every route")
+	command.Flags().StringVar(&opts.ServerURL, "server-url", "", "the origin URL to use instead of the document servers")
+	command.Flags().BoolVar(&opts.DryRun, "dry-run", false, "only print what would be done")
+	_ = command.MarkFlagRequired("project")
+	_ = command.MarkFlagRequired("file")
+
+	return command
+}
@@ -0,0 +1,37 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the serialization of an OpenAPI document.
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+// EncodeDocument writes an OpenAPI document to w in the given format.
+func EncodeDocument(w io.Writer, doc *openapi3.T, format Format) error {
+	switch format {
+	case FormatYAML, "":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	default:
+		return fmt.Errorf("unknown format %q, use yaml or json", format)
+	}
+}
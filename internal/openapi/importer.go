@@ -0,0 +1,146 @@
+package openapi
+
+import (
+	"fmt"
+
+	"github.com/uforg/ufogateway/internal/db"
+)
+
+// Action is what an import does with a planned route.
+type Action string
+
+const (
+	ActionCreate    Action = "create"    // a new route is created
+	ActionUpdate    Action = "update"    // the route of the project with the same endpoint is updated
+	ActionUnchanged Action = "unchanged" // the route of the project with the same endpoint is up to date
+	ActionConflict  Action = "conflict"  // another project already routes the endpoint, nothing is done
+)
+
+// ImportOptions configures an import.
+type ImportOptions struct {
+	PlanOptions
+	DryRun bool // only reports the actions without applying them
+}
+
+// ImportedRoute is a planned route along with what the import did with it.
+type ImportedRoute struct {
+	PlannedRoute
+	Action  Action `json:"action"`
+	RouteID string `json:"route_id,omitempty"`
+}
+
+// ImportResult is the outcome of an import.
+type ImportResult struct {
+	DryRun bool            `json:"dry_run"`
+	Routes []ImportedRoute `json:"routes"`
+}
+
+type Importer struct {
+	db *db.DB
+}
+
+func NewImporter(db *db.DB) *Importer {
+	return &Importer{
+		db: db,
+	}
+}
+
+// Import creates the routes of an OpenAPI document in a project.
+//
+// Routes are identified by project and endpoint, so importing the same
+// document again updates the name and origin URL of the routes it created
+// earlier instead of duplicating them, leaving the rest of their settings
+// untouched. The routes are written in a single transaction, so a failure
+// leaves none of them imported.
+func (im *Importer) Import(projectID string, data []byte, opts ImportOptions) (ImportResult, error) {
+	if _, err := im.db.GetProjectByID(projectID); err != nil {
+		return ImportResult{}, fmt.Errorf("project %q not found: %w", projectID, err)
+	}
+
+	doc, err := LoadDocument(data)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	planned, err := PlanRoutes(doc, opts.PlanOptions)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	result := ImportResult{
+		DryRun: opts.DryRun,
+		Routes: make([]ImportedRoute, 0, len(planned)),
+	}
+
+	importRoutes := func(database *db.DB) error {
+		for _, plannedRoute := range planned {
+			existing, err := database.GetRoutesByEndpoint(plannedRoute.Endpoint)
+			if err != nil {
+				return err
+			}
+
+			imported := decideAction(projectID, plannedRoute, existing)
+			if !opts.DryRun {
+				switch imported.Action {
+				case ActionCreate:
+					imported.RouteID, err = database.CreateProxyRoute(
+						projectID,
+						plannedRoute.Name,
+						plannedRoute.Endpoint,
+						plannedRoute.OriginURL,
+					)
+				case ActionUpdate:
+					err = database.UpdateRouteNameAndOriginURL(
+						imported.RouteID,
+						plannedRoute.Name,
+						plannedRoute.OriginURL,
+					)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to import route %s: %w", plannedRoute.Endpoint, err)
+				}
+			}
+
+			result.Routes = append(result.Routes, imported)
+		}
+		return nil
+	}
+
+	if opts.DryRun {
+		err = importRoutes(im.db)
+	} else {
+		err = im.db.RunInTransaction(importRoutes)
+	}
+	if err != nil {
+		return ImportResult{DryRun: opts.DryRun, Routes: []ImportedRoute{}}, err
+	}
+
+	return result, nil
+}
+
+// decideAction returns what to do with a planned route given the existing
+// routes with the same endpoint.
+func decideAction(projectID string, planned PlannedRoute, existing []db.Route) ImportedRoute {
+	imported := ImportedRoute{PlannedRoute: planned, Action: ActionCreate}
+
+	for _, route := range existing {
+		if route.Project != projectID {
+			continue
+		}
+
+		imported.RouteID = route.ID
+		if route.Name == planned.Name && route.OriginURL == planned.OriginURL {
+			imported.Action = ActionUnchanged
+		} else {
+			imported.Action = ActionUpdate
+		}
+		return imported
+	}
+
+	if len(existing) > 0 {
+		imported.Action = ActionConflict
+		imported.RouteID = existing[0].ID
+	}
+
+	return imported
+}
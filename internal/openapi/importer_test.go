@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uforg/ufogateway/internal/db"
+)
+
+func TestDecideAction(t *testing.T) {
+	planned := PlannedRoute{
+		Name:      "Petstore /pets",
+		Endpoint:  "/pets",
+		OriginURL: "https://api.example.com/pets",
+	}
+
+	tests := []struct {
+		name        string
+		existing    []db.Route
+		wantAction  Action
+		wantRouteID string
+	}{
+		{
+			name:       "no existing route",
+			existing:   nil,
+			wantAction: ActionCreate,
+		},
+		{
+			name: "route of the project is up to date",
+			existing: []db.Route{
+				{ID: "r1", Project: "p1", Name: "Petstore /pets", OriginURL: "https://api.example.com/pets"},
+			},
+			wantAction:  ActionUnchanged,
+			wantRouteID: "r1",
+		},
+		{
+			name: "route of the project has another origin",
+			existing: []db.Route{
+				{ID: "r1", Project: "p1", Name: "Petstore /pets", OriginURL: "https://old.example.com/pets"},
+			},
+			wantAction:  ActionUpdate,
+			wantRouteID: "r1",
+		},
+		{
+			name: "route of another project",
+			existing: []db.Route{
+				{ID: "r2", Project: "p2", Name: "Other", OriginURL: "https://other.example.com"},
+			},
+			wantAction:  ActionConflict,
+			wantRouteID: "r2",
+		},
+		{
+			name: "routes of the project and of another project",
+			existing: []db.Route{
+				{ID: "r2", Project: "p2", Name: "Other", OriginURL: "https://other.example.com"},
+				{ID: "r1", Project: "p1", Name: "Old name", OriginURL: "https://api.example.com/pets"},
+			},
+			wantAction:  ActionUpdate,
+			wantRouteID: "r1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decideAction("p1", planned, tt.existing)
+			assert.Equal(t, planned, got.PlannedRoute)
+			assert.Equal(t, tt.wantAction, got.Action)
+			assert.Equal(t, tt.wantRouteID, got.RouteID)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package openapi
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewInferCommand returns the "infer-openapi" command that writes an OpenAPI
+// document inferred from the stored requests of a route.
+func NewInferCommand(inferrer *Inferrer) *cobra.Command {
+	var routeID, format, output string
+	var limit int
+
+	command := &cobra.Command{
+		Use:   "infer-openapi",
+		Short: "Infers an OpenAPI 3 document from the stored requests of a route",
+		Example: `  ufogateway infer-openapi --route abc123def456ghi --output openapi.yaml
+  ufogateway infer-openapi --route abc123def456ghi --format json --limit 5000`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			doc, err := inferrer.Infer(routeID, limit)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = os.Stdout
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+
+			return EncodeDocument(w, doc, Format(format))
+		},
+	}
+
+	command.Flags().StringVar(&routeID, "route", "", "the id of the route whose requests are inspected")
+	command.Flags().StringVar(&format, "format", string(FormatYAML), `"yaml" or "json"`)
+	command.Flags().IntVar(&limit, "limit", DefaultInferLimit, "the maximum number of requests to inspect")
+	command.Flags().StringVarP(&output, "output", "o", "", "the file to write, defaults to stdout")
+	_ = command.MarkFlagRequired("route")
+
+	return command
+}
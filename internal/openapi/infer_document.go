@@ -0,0 +1,293 @@
+package openapi
+
+import (
+	"encoding/json"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+var (
+	numericRegexp = regexp.MustCompile(`^[0-9]+$`)
+	hexRegexp     = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	ulidRegexp    = regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+	tokenRegexp   = regexp.MustCompile(`^[A-Za-z0-9]{12,}$`)
+	digitRegexp   = regexp.MustCompile(`[0-9]`)
+	letterRegexp  = regexp.MustCompile(`[A-Za-z]`)
+)
+
+// Sample is an observed request and its response, used to infer a document.
+type Sample struct {
+	Method         string
+	Path           string // is the path at the origin, relative to the server URL
+	Query          url.Values
+	ReqContentType string
+	ReqBody        string
+	Status         int // is zero when the response is unknown
+	ResContentType string
+	ResBody        string
+}
+
+// operationSamples accumulates the samples of one operation.
+type operationSamples struct {
+	count       int
+	pathParams  []string
+	paramValues map[string]*schemaNode
+	query       map[string]*schemaNode
+	queryCounts map[string]int
+	reqBodies   map[string]*schemaNode         // by media type
+	responses   map[int]map[string]*schemaNode // by status and media type
+}
+
+// InferDocument infers an OpenAPI 3 document from observed traffic.
+//
+// Path segments that look like identifiers (numbers, UUIDs, long hex or
+// random looking tokens) become path parameters. Query parameters present
+// in every sample of an operation are required, and so are the properties
+// of JSON bodies present in every sample. Non JSON bodies are described as
+// strings.
+func InferDocument(title string, serverURL string, samples []Sample) *openapi3.T {
+	operations := map[string]map[string]*operationSamples{} // by path template and method
+
+	for _, sample := range samples {
+		template, params, values := templatePath(sample.Path)
+
+		byMethod, ok := operations[template]
+		if !ok {
+			byMethod = map[string]*operationSamples{}
+			operations[template] = byMethod
+		}
+		method := strings.ToUpper(sample.Method)
+		op, ok := byMethod[method]
+		if !ok {
+			op = &operationSamples{
+				pathParams:  params,
+				paramValues: map[string]*schemaNode{},
+				query:       map[string]*schemaNode{},
+				queryCounts: map[string]int{},
+				reqBodies:   map[string]*schemaNode{},
+				responses:   map[int]map[string]*schemaNode{},
+			}
+			for _, param := range params {
+				op.paramValues[param] = newSchemaNode()
+			}
+			byMethod[method] = op
+		}
+
+		op.count++
+		for i, param := range params {
+			op.paramValues[param].addText(values[i])
+		}
+		for name, queryValues := range sample.Query {
+			node, ok := op.query[name]
+			if !ok {
+				node = newSchemaNode()
+				op.query[name] = node
+			}
+			for _, value := range queryValues {
+				node.addText(value)
+			}
+			op.queryCounts[name]++
+		}
+		if sample.ReqBody != "" {
+			addBody(op.reqBodies, sample.ReqContentType, sample.ReqBody)
+		}
+		if sample.Status > 0 {
+			byMediaType, ok := op.responses[sample.Status]
+			if !ok {
+				byMediaType = map[string]*schemaNode{}
+				op.responses[sample.Status] = byMediaType
+			}
+			if sample.ResBody != "" {
+				addBody(byMediaType, sample.ResContentType, sample.ResBody)
+			}
+		}
+	}
+
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       title,
+			Version:     "1.0.0",
+			Description: "Inferred from the traffic observed by UFO Gateway.",
+		},
+		Paths: openapi3.NewPaths(),
+	}
+	if serverURL != "" {
+		doc.Servers = openapi3.Servers{{URL: serverURL}}
+	}
+
+	for _, template := range slices.Sorted(maps.Keys(operations)) {
+		pathItem := &openapi3.PathItem{}
+		for _, method := range slices.Sorted(maps.Keys(operations[template])) {
+			pathItem.SetOperation(method, operations[template][method].operation())
+		}
+		doc.Paths.Set(template, pathItem)
+	}
+
+	return doc
+}
+
+// operation returns the OpenAPI operation described by the samples.
+func (op *operationSamples) operation() *openapi3.Operation {
+	operation := openapi3.NewOperation()
+
+	for _, param := range op.pathParams {
+		operation.AddParameter(openapi3.NewPathParameter(param).
+			WithSchema(op.paramValues[param].schema()))
+	}
+	for _, name := range slices.Sorted(maps.Keys(op.query)) {
+		parameter := openapi3.NewQueryParameter(name).WithSchema(op.query[name].schema())
+		parameter.Required = op.queryCounts[name] == op.count
+		operation.AddParameter(parameter)
+	}
+
+	if len(op.reqBodies) > 0 {
+		operation.RequestBody = &openapi3.RequestBodyRef{
+			Value: openapi3.NewRequestBody().WithContent(bodyContent(op.reqBodies)),
+		}
+	}
+
+	// Every operation needs a response, the default one is only kept when
+	// no response was observed
+	operation.Responses = openapi3.NewResponses()
+	if len(op.responses) > 0 {
+		operation.Responses.Delete("default")
+	}
+	for _, status := range slices.Sorted(maps.Keys(op.responses)) {
+		description := http.StatusText(status)
+		if description == "" {
+			description = "Status " + strconv.Itoa(status)
+		}
+		response := openapi3.NewResponse().WithDescription(description)
+		if len(op.responses[status]) > 0 {
+			response.Content = bodyContent(op.responses[status])
+		}
+		operation.AddResponse(status, response)
+	}
+
+	return operation
+}
+
+// addBody records a body under its media type. JSON bodies that can't be
+// parsed are recorded as text.
+func addBody(bodies map[string]*schemaNode, contentType string, body string) {
+	mediaType := "application/octet-stream"
+	if contentType != "" {
+		if parsed, _, err := mime.ParseMediaType(contentType); err == nil {
+			mediaType = parsed
+		}
+	}
+
+	node, ok := bodies[mediaType]
+	if !ok {
+		node = newSchemaNode()
+		bodies[mediaType] = node
+	}
+
+	if isJSONMediaType(mediaType) {
+		var value any
+		if err := json.Unmarshal([]byte(body), &value); err == nil {
+			node.add(value)
+			return
+		}
+	}
+	node.add(body)
+}
+
+// bodyContent returns the content of a request or response body.
+func bodyContent(bodies map[string]*schemaNode) openapi3.Content {
+	content := openapi3.Content{}
+	for mediaType, node := range bodies {
+		schema := node.schema()
+		if !isJSONMediaType(mediaType) {
+			schema = openapi3.NewStringSchema()
+		}
+		content[mediaType] = openapi3.NewMediaType().WithSchema(schema)
+	}
+	return content
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// templatePath replaces the identifier segments of a path with parameters.
+// It returns the path template along with the parameter names and values.
+func templatePath(path string) (string, []string, []string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	original := slices.Clone(segments)
+	params, values := []string{}, []string{}
+
+	for i, segment := range original {
+		if segment == "" || !isIDSegment(segment) {
+			continue
+		}
+
+		name := "id"
+		if i > 0 && !isIDSegment(original[i-1]) {
+			name = singular(original[i-1]) + "Id"
+		}
+		for slices.Contains(params, name) {
+			name += strconv.Itoa(len(params))
+		}
+
+		params = append(params, name)
+		values = append(values, segment)
+		segments[i] = "{" + name + "}"
+	}
+
+	return "/" + strings.Join(segments, "/"), params, values
+}
+
+// isIDSegment reports whether a path segment looks like an identifier.
+func isIDSegment(segment string) bool {
+	if unescaped, err := url.PathUnescape(segment); err == nil {
+		segment = unescaped
+	}
+
+	switch {
+	case numericRegexp.MatchString(segment):
+		return true
+	case uuidRegexp.MatchString(segment):
+		return true
+	case hexRegexp.MatchString(segment):
+		return true
+	case ulidRegexp.MatchString(segment):
+		return true
+	case tokenRegexp.MatchString(segment):
+		// Long tokens mixing letters and digits, like PocketBase IDs
+		return digitRegexp.MatchString(segment) && letterRegexp.MatchString(segment)
+	}
+	return false
+}
+
+// singular returns a naive singular form of a resource name for naming the
+// parameters that follow it, e.g. users/{userId}.
+func singular(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' {
+			return -1
+		}
+		return r
+	}, name)
+
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "ses") && len(name) > 3:
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "ss"), strings.HasSuffix(name, "us"), strings.HasSuffix(name, "is"):
+		return name
+	case strings.HasSuffix(name, "s") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
@@ -0,0 +1,228 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatePath(t *testing.T) {
+	tests := []struct {
+		path       string
+		want       string
+		wantParams []string
+		wantValues []string
+	}{
+		{path: "/", want: "/", wantParams: []string{}, wantValues: []string{}},
+		{path: "/users", want: "/users", wantParams: []string{}, wantValues: []string{}},
+		{path: "/users/42", want: "/users/{userId}", wantParams: []string{"userId"}, wantValues: []string{"42"}},
+		{
+			path:       "/companies/3b241101-e2bb-4255-8caf-4136c566a962/employees/abc123def456ghi/",
+			want:       "/companies/{companyId}/employees/{employeeId}",
+			wantParams: []string{"companyId", "employeeId"},
+			wantValues: []string{"3b241101-e2bb-4255-8caf-4136c566a962", "abc123def456ghi"},
+		},
+		{path: "/42/43", want: "/{id}/{id1}", wantParams: []string{"id", "id1"}, wantValues: []string{"42", "43"}},
+		{path: "/users/me/settings", want: "/users/me/settings", wantParams: []string{}, wantValues: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, params, values := templatePath(tt.path)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantParams, params)
+			assert.Equal(t, tt.wantValues, values)
+		})
+	}
+}
+
+func TestIsIDSegment(t *testing.T) {
+	tests := []struct {
+		segment string
+		want    bool
+	}{
+		{segment: "42", want: true},
+		{segment: "3b241101-e2bb-4255-8caf-4136c566a962", want: true},
+		{segment: "507f1f77bcf86cd799439011", want: true},
+		{segment: "01ARZ3NDEKTSV4RRFFQ69G5FAV", want: true},
+		{segment: "abc123def456ghi", want: true},
+		{segment: "users", want: false},
+		{segment: "v2", want: false},
+		{segment: "settings", want: false},
+		{segment: "release-2024-01", want: false},
+		{segment: "organizations", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.segment, func(t *testing.T) {
+			assert.Equal(t, tt.want, isIDSegment(tt.segment))
+		})
+	}
+}
+
+func TestSingular(t *testing.T) {
+	tests := map[string]string{
+		"users":      "user",
+		"companies":  "company",
+		"addresses":  "address",
+		"class":      "class",
+		"status":     "status",
+		"statuses":   "status",
+		"analysis":   "analysis",
+		"data":       "data",
+		"line-items": "lineitem",
+	}
+
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, want, singular(name))
+		})
+	}
+}
+
+func TestInferDocument(t *testing.T) {
+	samples := []Sample{
+		{
+			Method:         "GET",
+			Path:           "/users",
+			Query:          url.Values{"limit": {"10"}, "sort": {"name"}},
+			Status:         200,
+			ResContentType: "application/json; charset=utf-8",
+			ResBody:        `[{"id":1,"name":"ann"}]`,
+		},
+		{
+			Method:         "GET",
+			Path:           "/users",
+			Query:          url.Values{"limit": {"20"}},
+			Status:         200,
+			ResContentType: "application/json",
+			ResBody:        `[]`,
+		},
+		{
+			Method:         "post",
+			Path:           "/users",
+			ReqContentType: "application/json",
+			ReqBody:        `{"name":"bob"}`,
+			Status:         201,
+			ResContentType: "application/json",
+			ResBody:        `{"id":2,"name":"bob"}`,
+		},
+		{
+			Method:         "GET",
+			Path:           "/users/1",
+			Status:         200,
+			ResContentType: "application/json",
+			ResBody:        `{"id":1,"name":"ann"}`,
+		},
+		{
+			Method:         "GET",
+			Path:           "/users/99",
+			Status:         404,
+			ResContentType: "text/plain",
+			ResBody:        "not found",
+		},
+		{
+			Method: "DELETE",
+			Path:   "/users/1",
+			Status: 204,
+		},
+		{
+			Method: "GET",
+			Path:   "/health",
+		},
+	}
+
+	doc := InferDocument("Users", "https://api.example.com", samples)
+	require.NoError(t, doc.Validate(context.Background()))
+
+	got, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"openapi": "3.0.3",
+		"info": {
+			"title": "Users",
+			"version": "1.0.0",
+			"description": "Inferred from the traffic observed by UFO Gateway."
+		},
+		"servers": [{"url": "https://api.example.com"}],
+		"paths": {
+			"/health": {
+				"get": {
+					"responses": {"default": {"description": ""}}
+				}
+			},
+			"/users": {
+				"get": {
+					"parameters": [
+						{"in": "query", "name": "limit", "required": true, "schema": {"type": "integer"}},
+						{"in": "query", "name": "sort", "schema": {"type": "string"}}
+					],
+					"responses": {
+						"200": {
+							"description": "OK",
+							"content": {"application/json": {"schema": {
+								"type": "array",
+								"items": {
+									"type": "object",
+									"properties": {"id": {"type": "integer"}, "name": {"type": "string"}},
+									"required": ["id", "name"]
+								}
+							}}}
+						}
+					}
+				},
+				"post": {
+					"requestBody": {
+						"content": {"application/json": {"schema": {
+							"type": "object",
+							"properties": {"name": {"type": "string"}},
+							"required": ["name"]
+						}}}
+					},
+					"responses": {
+						"201": {
+							"description": "Created",
+							"content": {"application/json": {"schema": {
+								"type": "object",
+								"properties": {"id": {"type": "integer"}, "name": {"type": "string"}},
+								"required": ["id", "name"]
+							}}}
+						}
+					}
+				}
+			},
+			"/users/{userId}": {
+				"delete": {
+					"parameters": [
+						{"in": "path", "name": "userId", "required": true, "schema": {"type": "integer"}}
+					],
+					"responses": {"204": {"description": "No Content"}}
+				},
+				"get": {
+					"parameters": [
+						{"in": "path", "name": "userId", "required": true, "schema": {"type": "integer"}}
+					],
+					"responses": {
+						"200": {
+							"description": "OK",
+							"content": {"application/json": {"schema": {
+								"type": "object",
+								"properties": {"id": {"type": "integer"}, "name": {"type": "string"}},
+								"required": ["id", "name"]
+							}}}
+						},
+						"404": {
+							"description": "Not Found",
+							"content": {"text/plain": {"schema": {"type": "string"}}}
+						}
+					}
+				}
+			}
+		}
+	}`, string(got))
+}
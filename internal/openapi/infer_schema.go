@@ -0,0 +1,164 @@
+package openapi
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+var (
+	uuidRegexp  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	emailRegexp = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// schemaNode accumulates the values observed at one place of a document,
+// e.g. a query parameter or a property of a body, to infer their schema.
+type schemaNode struct {
+	types          map[string]int         // number of samples per JSON schema type, plus "null"
+	formats        map[string]int         // number of string samples per detected format
+	properties     map[string]*schemaNode // properties of the object samples
+	propertyCounts map[string]int         // number of object samples with each property
+	items          *schemaNode            // items of the array samples
+}
+
+func newSchemaNode() *schemaNode {
+	return &schemaNode{
+		types:          map[string]int{},
+		formats:        map[string]int{},
+		properties:     map[string]*schemaNode{},
+		propertyCounts: map[string]int{},
+	}
+}
+
+// add records a value decoded from JSON.
+func (n *schemaNode) add(v any) {
+	switch value := v.(type) {
+	case nil:
+		n.types["null"]++
+	case bool:
+		n.types[openapi3.TypeBoolean]++
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			n.types[openapi3.TypeInteger]++
+		} else {
+			n.types[openapi3.TypeNumber]++
+		}
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			n.types[openapi3.TypeInteger]++
+		} else {
+			n.types[openapi3.TypeNumber]++
+		}
+	case string:
+		n.types[openapi3.TypeString]++
+		n.formats[stringFormat(value)]++
+	case []any:
+		n.types[openapi3.TypeArray]++
+		if n.items == nil {
+			n.items = newSchemaNode()
+		}
+		for _, item := range value {
+			n.items.add(item)
+		}
+	case map[string]any:
+		n.types[openapi3.TypeObject]++
+		for key, propertyValue := range value {
+			property, ok := n.properties[key]
+			if !ok {
+				property = newSchemaNode()
+				n.properties[key] = property
+			}
+			property.add(propertyValue)
+			n.propertyCounts[key]++
+		}
+	}
+}
+
+// addText records a value that is only known as text, like a query
+// parameter, using the most specific type it can be parsed as.
+func (n *schemaNode) addText(s string) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		n.add(float64(i))
+		return
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		n.types[openapi3.TypeNumber]++
+		return
+	}
+	if b, err := strconv.ParseBool(s); err == nil && (s == "true" || s == "false") {
+		n.add(b)
+		return
+	}
+	n.add(s)
+}
+
+// schema returns the schema that accepts every recorded value.
+func (n *schemaNode) schema() *openapi3.Schema {
+	schema := openapi3.NewSchema()
+
+	types := map[string]int{}
+	for t, count := range n.types {
+		if t == "null" {
+			schema.Nullable = true
+			continue
+		}
+		types[t] = count
+	}
+	if types[openapi3.TypeInteger] > 0 && types[openapi3.TypeNumber] > 0 {
+		delete(types, openapi3.TypeInteger)
+	}
+
+	// Values of mixed types are left without a type so they accept anything
+	if len(types) != 1 {
+		return schema
+	}
+	typeName := slices.Collect(maps.Keys(types))[0]
+	schema.Type = &openapi3.Types{typeName}
+
+	switch typeName {
+	case openapi3.TypeString:
+		if len(n.formats) == 1 {
+			schema.Format = slices.Collect(maps.Keys(n.formats))[0]
+		}
+	case openapi3.TypeArray:
+		items := openapi3.NewSchema()
+		if n.items != nil {
+			items = n.items.schema()
+		}
+		schema.Items = openapi3.NewSchemaRef("", items)
+	case openapi3.TypeObject:
+		objects := n.types[openapi3.TypeObject]
+		schema.Properties = openapi3.Schemas{}
+		for _, key := range slices.Sorted(maps.Keys(n.properties)) {
+			schema.Properties[key] = openapi3.NewSchemaRef("", n.properties[key].schema())
+			if n.propertyCounts[key] == objects {
+				schema.Required = append(schema.Required, key)
+			}
+		}
+	}
+
+	return schema
+}
+
+// stringFormat returns the OpenAPI format of a string value, if any.
+func stringFormat(s string) string {
+	if _, err := time.Parse(time.RFC3339, s); err == nil {
+		return "date-time"
+	}
+	if _, err := time.Parse(time.DateOnly, s); err == nil {
+		return "date"
+	}
+	if uuidRegexp.MatchString(s) {
+		return "uuid"
+	}
+	if emailRegexp.MatchString(s) {
+		return "email"
+	}
+	return ""
+}
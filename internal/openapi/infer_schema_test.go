@@ -0,0 +1,101 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaNode(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []string
+		want    string
+	}{
+		{
+			name:    "integers",
+			samples: []string{`1`, `2`},
+			want:    `{"type":"integer"}`,
+		},
+		{
+			name:    "integers and numbers",
+			samples: []string{`1`, `2.5`},
+			want:    `{"type":"number"}`,
+		},
+		{
+			name:    "nullable string",
+			samples: []string{`"a"`, `null`},
+			want:    `{"nullable":true,"type":"string"}`,
+		},
+		{
+			name:    "mixed types",
+			samples: []string{`"a"`, `1`},
+			want:    `{}`,
+		},
+		{
+			name:    "string formats",
+			samples: []string{`"2025-01-02T03:04:05Z"`, `"2024-12-31T00:00:00+01:00"`},
+			want:    `{"format":"date-time","type":"string"}`,
+		},
+		{
+			name:    "mixed string formats",
+			samples: []string{`"2025-01-02T03:04:05Z"`, `"ann"`},
+			want:    `{"type":"string"}`,
+		},
+		{
+			name:    "objects with optional properties",
+			samples: []string{`{"id":1,"name":"ann"}`, `{"id":2,"email":"bob@example.com"}`},
+			want: `{"properties":{"email":{"format":"email","type":"string"},"id":{"type":"integer"},` +
+				`"name":{"type":"string"}},"required":["id"],"type":"object"}`,
+		},
+		{
+			name:    "arrays",
+			samples: []string{`[{"id":"3b241101-e2bb-4255-8caf-4136c566a962"}]`, `[]`},
+			want:    `{"items":{"properties":{"id":{"format":"uuid","type":"string"}},"required":["id"],"type":"object"},"type":"array"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := newSchemaNode()
+			for _, sample := range tt.samples {
+				var value any
+				require.NoError(t, json.Unmarshal([]byte(sample), &value))
+				node.add(value)
+			}
+
+			got, err := json.Marshal(node.schema())
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestSchemaNodeAddText(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []string
+		want    string
+	}{
+		{name: "integers", samples: []string{"1", "20"}, want: `{"type":"integer"}`},
+		{name: "numbers", samples: []string{"1", "2.5"}, want: `{"type":"number"}`},
+		{name: "booleans", samples: []string{"true", "false"}, want: `{"type":"boolean"}`},
+		{name: "strings", samples: []string{"asc", "1"}, want: `{}`},
+		{name: "dates", samples: []string{"2025-01-02"}, want: `{"format":"date","type":"string"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := newSchemaNode()
+			for _, sample := range tt.samples {
+				node.addText(sample)
+			}
+
+			got, err := json.Marshal(node.schema())
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package openapi
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pocketbase/dbx"
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/util/strutil"
+)
+
+const (
+	// DefaultInferLimit is the number of stored requests inspected when no
+	// limit is given.
+	DefaultInferLimit = 1000
+	// MaxInferLimit is the maximum number of stored requests inspected by a
+	// single inference.
+	MaxInferLimit = 10000
+)
+
+type Inferrer struct {
+	db *db.DB
+}
+
+func NewInferrer(db *db.DB) *Inferrer {
+	return &Inferrer{
+		db: db,
+	}
+}
+
+// Infer returns an OpenAPI document inferred from the stored requests of a
+// route, using the route origin URL as the server.
+//
+// At most limit requests that reached the origin are inspected, the
+// blocked ones never got a real response. The request and response bodies
+// are only known if the route stores them.
+func (in *Inferrer) Infer(routeID string, limit int) (*openapi3.T, error) {
+	if limit <= 0 {
+		limit = DefaultInferLimit
+	}
+	if limit > MaxInferLimit {
+		return nil, errors.New("limit exceeds the maximum of 10000 requests")
+	}
+
+	route, err := in.db.GetRouteByID(routeID)
+	if err != nil {
+		return nil, fmt.Errorf("route %q not found: %w", routeID, err)
+	}
+
+	storedRequests, err := in.db.FindStoredRequests(
+		"route = {:route} && req_block_reason = ''",
+		limit,
+		dbx.Params{"route": route.ID},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]Sample, 0, len(storedRequests))
+	for _, sr := range storedRequests {
+		sample, err := sampleFromStoredRequest(sr, route.Endpoint)
+		if err != nil {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+
+	return InferDocument(route.Name, route.OriginURL, samples), nil
+}
+
+// sampleFromStoredRequest converts a stored request to a sample with the
+// path relative to the route origin URL. Requests whose bodies can't be read
+// are not samples.
+func sampleFromStoredRequest(sr db.StoredRequest, endpoint string) (Sample, error) {
+	if bodyErr := cmp.Or(sr.ReqBodyError, sr.ResBodyError); bodyErr != "" {
+		return Sample{}, errors.New(bodyErr)
+	}
+
+	gatewayURL, err := url.Parse(sr.ReqGatewayURL)
+	if err != nil {
+		return Sample{}, err
+	}
+
+	return Sample{
+		Method:         sr.ReqMethod,
+		Path:           trimEndpoint(gatewayURL.Path, endpoint),
+		Query:          gatewayURL.Query(),
+		ReqContentType: http.Header(sr.ReqHeaders).Get("Content-Type"),
+		ReqBody:        string(sr.ReqBodyBytes()),
+		Status:         sr.ResStatus,
+		ResContentType: http.Header(sr.ResHeaders).Get("Content-Type"),
+		ResBody:        string(sr.ResBodyBytes()),
+	}, nil
+}
+
+// trimEndpoint returns the gateway path relative to the route endpoint. The
+// endpoint is only trimmed when it ends at a path segment, so /apiv2/users
+// keeps its path for the /api endpoint.
+func trimEndpoint(gatewayPath string, endpoint string) string {
+	cleanPath := strutil.RemoveAllLeadingSlashes(gatewayPath)
+	cleanEndpoint := strutil.RemoveAllTrailingSlashes(strutil.RemoveAllLeadingSlashes(endpoint))
+
+	rest, ok := strings.CutPrefix(cleanPath, cleanEndpoint)
+	if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		return "/" + cleanPath
+	}
+	return "/" + strutil.RemoveAllLeadingSlashes(rest)
+}
@@ -0,0 +1,103 @@
+package openapi
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uforg/ufogateway/internal/db"
+)
+
+func TestSampleFromStoredRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		sr       db.StoredRequest
+		endpoint string
+		want     Sample
+		wantErr  bool
+	}{
+		{
+			name: "strips the endpoint",
+			sr: db.StoredRequest{
+				ReqMethod:     "POST",
+				ReqGatewayURL: "http://localhost:8090/api/users/42?expand=company",
+				ReqHeaders:    map[string][]string{"Content-Type": {"application/json"}},
+				ReqBody:       `{"name":"ann"}`,
+				ResStatus:     200,
+				ResHeaders:    map[string][]string{"Content-Type": {"application/json"}},
+				ResBody:       `{"id":42}`,
+			},
+			endpoint: "/api",
+			want: Sample{
+				Method:         "POST",
+				Path:           "/users/42",
+				Query:          url.Values{"expand": {"company"}},
+				ReqContentType: "application/json",
+				ReqBody:        `{"name":"ann"}`,
+				Status:         200,
+				ResContentType: "application/json",
+				ResBody:        `{"id":42}`,
+			},
+		},
+		{
+			name: "request to the endpoint itself",
+			sr: db.StoredRequest{
+				ReqMethod:     "GET",
+				ReqGatewayURL: "http://localhost:8090/api",
+			},
+			endpoint: "api",
+			want: Sample{
+				Method: "GET",
+				Path:   "/",
+				Query:  url.Values{},
+			},
+		},
+		{
+			name: "endpoint with a trailing slash",
+			sr: db.StoredRequest{
+				ReqMethod:     "GET",
+				ReqGatewayURL: "http://localhost:8090/api/users",
+			},
+			endpoint: "/api/",
+			want: Sample{
+				Method: "GET",
+				Path:   "/users",
+				Query:  url.Values{},
+			},
+		},
+		{
+			name: "endpoint ending inside a segment",
+			sr: db.StoredRequest{
+				ReqMethod:     "GET",
+				ReqGatewayURL: "http://localhost:8090/apiv2/users",
+			},
+			endpoint: "/api",
+			want: Sample{
+				Method: "GET",
+				Path:   "/apiv2/users",
+				Query:  url.Values{},
+			},
+		},
+		{
+			name: "invalid url",
+			sr: db.StoredRequest{
+				ReqGatewayURL: "http://[::1",
+			},
+			endpoint: "/api",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sampleFromStoredRequest(tt.sr, tt.endpoint)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/uforg/ufogateway/internal/util/strutil"
+)
+
+// Mode is the way the paths of a document are grouped into routes.
+type Mode string
+
+const (
+	// ModePrefix creates one route per first path segment, e.g. /users
+	// for /users, /users/{id} and /users/{id}/posts.
+	ModePrefix Mode = "prefix"
+	// ModePath creates one route per path, up to its first path
+	// parameter, e.g. /users for /users and /users/{id} and
+	// /users/me/settings for /users/me/settings.
+	ModePath Mode = "path"
+)
+
+// PlanOptions configures how the routes are derived from a document.
+type PlanOptions struct {
+	Mode           Mode   // defaults to ModePrefix
+	EndpointPrefix string // is prepended to every route endpoint, e.g. /petstore
+	ServerURL      string // replaces the servers of the document
+}
+
+// PlannedRoute is a route derived from a document.
+type PlannedRoute struct {
+	Name       string   `json:"name"`
+	Endpoint   string   `json:"endpoint"`
+	OriginURL  string   `json:"origin_url"`
+	Operations []string `json:"operations"` // e.g. "GET /users/{id}"
+}
+
+// PlanRoutes derives the routes for the paths of doc, sorted by endpoint.
+//
+// Routes match requests by endpoint prefix regardless of the method, so
+// every operation under the same endpoint shares a route.
+func PlanRoutes(doc *openapi3.T, opts PlanOptions) ([]PlannedRoute, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = ModePrefix
+	}
+	if mode != ModePrefix && mode != ModePath {
+		return nil, fmt.Errorf("invalid mode %q, expected %q or %q", mode, ModePrefix, ModePath)
+	}
+
+	endpointPrefix := ""
+	if opts.EndpointPrefix != "" {
+		endpointPrefix = "/" + strutil.RemoveAllTrailingSlashes(
+			strutil.RemoveAllLeadingSlashes(opts.EndpointPrefix),
+		)
+	}
+
+	title := ""
+	if doc.Info != nil {
+		title = doc.Info.Title
+	}
+
+	planned := map[string]*PlannedRoute{}
+	if doc.Paths == nil {
+		return []PlannedRoute{}, nil
+	}
+
+	for _, path := range doc.Paths.InMatchingOrder() {
+		pathItem := doc.Paths.Value(path)
+		if pathItem == nil || len(pathItem.Operations()) == 0 {
+			continue
+		}
+
+		originPath := staticPathPrefix(path)
+		if mode == ModePrefix {
+			originPath, _, _ = strings.Cut(originPath, "/")
+		}
+		endpoint := strutil.RemoveAllTrailingSlashes(endpointPrefix + "/" + originPath)
+		if len(endpoint) < 2 {
+			return nil, fmt.Errorf(
+				"path %q has no static prefix to route on, set an endpoint prefix",
+				path,
+			)
+		}
+
+		serverURL, err := resolveServerURL(doc, pathItem, opts.ServerURL)
+		if err != nil {
+			return nil, fmt.Errorf("path %q: %w", path, err)
+		}
+		originURL := strutil.RemoveAllTrailingSlashes(serverURL)
+		if originPath != "" {
+			originURL += "/" + originPath
+		}
+
+		route, ok := planned[endpoint]
+		if !ok {
+			route = &PlannedRoute{
+				Name:      strings.TrimSpace(title + " " + endpoint),
+				Endpoint:  endpoint,
+				OriginURL: originURL,
+			}
+			planned[endpoint] = route
+		}
+		if route.OriginURL != originURL {
+			return nil, fmt.Errorf(
+				"the paths under %s use different servers (%s and %s), use the %q mode or a server URL",
+				endpoint, route.OriginURL, originURL, ModePath,
+			)
+		}
+
+		for method := range pathItem.Operations() {
+			route.Operations = append(route.Operations, method+" "+path)
+		}
+	}
+
+	routes := make([]PlannedRoute, 0, len(planned))
+	for _, route := range planned {
+		slices.SortFunc(route.Operations, compareOperations)
+		routes = append(routes, *route)
+	}
+	slices.SortFunc(routes, func(a, b PlannedRoute) int {
+		return strings.Compare(a.Endpoint, b.Endpoint)
+	})
+
+	return routes, nil
+}
+
+// staticPathPrefix returns the segments of an OpenAPI path before its first
+// templated segment, without leading or trailing slashes.
+func staticPathPrefix(path string) string {
+	segments := []string{}
+	for _, segment := range strings.Split(strutil.RemoveAllLeadingSlashes(path), "/") {
+		if segment == "" || strings.Contains(segment, "{") {
+			break
+		}
+		segments = append(segments, segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// resolveServerURL returns the absolute server URL for the operations of a
+// path, using the server URL override, the path servers or the document
+// servers in that order. Server variables are replaced by their defaults.
+func resolveServerURL(doc *openapi3.T, pathItem *openapi3.PathItem, override string) (string, error) {
+	var server *openapi3.Server
+	switch {
+	case override != "":
+		server = &openapi3.Server{URL: override}
+	case len(pathItem.Servers) > 0:
+		server = pathItem.Servers[0]
+	case len(doc.Servers) > 0:
+		server = doc.Servers[0]
+	default:
+		return "", fmt.Errorf("the document has no servers, set a server URL")
+	}
+
+	serverURL := server.URL
+	for name, variable := range server.Variables {
+		serverURL = strings.ReplaceAll(serverURL, "{"+name+"}", variable.Default)
+	}
+
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("server URL %q is not absolute, set a server URL", serverURL)
+	}
+
+	return serverURL, nil
+}
+
+// compareOperations sorts operations by path and then by the usual order of
+// the HTTP methods.
+func compareOperations(a, b string) int {
+	methodA, pathA, _ := strings.Cut(a, " ")
+	methodB, pathB, _ := strings.Cut(b, " ")
+	if c := strings.Compare(pathA, pathB); c != 0 {
+		return c
+	}
+	return methodOrder(methodA) - methodOrder(methodB)
+}
+
+func methodOrder(method string) int {
+	order := []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions, http.MethodTrace,
+	}
+	if i := slices.Index(order, method); i >= 0 {
+		return i
+	}
+	return len(order)
+}
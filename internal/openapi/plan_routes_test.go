@@ -0,0 +1,197 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const petstoreYAML = `
+openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+servers:
+  - url: https://{env}.example.com/v1/
+    variables:
+      env:
+        default: api
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: ok
+    post:
+      responses:
+        "201":
+          description: created
+  /pets/{id}:
+    get:
+      responses:
+        "200":
+          description: ok
+    delete:
+      responses:
+        "204":
+          description: deleted
+  /pets/{id}/photos:
+    get:
+      responses:
+        "200":
+          description: ok
+  /stores/inventory:
+    get:
+      responses:
+        "200":
+          description: ok
+`
+
+func TestPlanRoutes(t *testing.T) {
+	doc, err := LoadDocument([]byte(petstoreYAML))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		opts    PlanOptions
+		want    []PlannedRoute
+		wantErr bool
+	}{
+		{
+			name: "prefix mode",
+			opts: PlanOptions{},
+			want: []PlannedRoute{
+				{
+					Name:      "Petstore /pets",
+					Endpoint:  "/pets",
+					OriginURL: "https://api.example.com/v1/pets",
+					Operations: []string{
+						"GET /pets", "POST /pets",
+						"GET /pets/{id}", "DELETE /pets/{id}",
+						"GET /pets/{id}/photos",
+					},
+				},
+				{
+					Name:       "Petstore /stores",
+					Endpoint:   "/stores",
+					OriginURL:  "https://api.example.com/v1/stores",
+					Operations: []string{"GET /stores/inventory"},
+				},
+			},
+		},
+		{
+			name: "path mode with endpoint prefix and server url",
+			opts: PlanOptions{
+				Mode:           ModePath,
+				EndpointPrefix: "petstore/",
+				ServerURL:      "http://localhost:3000",
+			},
+			want: []PlannedRoute{
+				{
+					Name:      "Petstore /petstore/pets",
+					Endpoint:  "/petstore/pets",
+					OriginURL: "http://localhost:3000/pets",
+					Operations: []string{
+						"GET /pets", "POST /pets",
+						"GET /pets/{id}", "DELETE /pets/{id}",
+						"GET /pets/{id}/photos",
+					},
+				},
+				{
+					Name:       "Petstore /petstore/stores/inventory",
+					Endpoint:   "/petstore/stores/inventory",
+					OriginURL:  "http://localhost:3000/stores/inventory",
+					Operations: []string{"GET /stores/inventory"},
+				},
+			},
+		},
+		{
+			name:    "invalid mode",
+			opts:    PlanOptions{Mode: "operation"},
+			wantErr: true,
+		},
+		{
+			name:    "relative server url",
+			opts:    PlanOptions{ServerURL: "/v1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PlanRoutes(doc, tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanRoutesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{
+			name: "no servers",
+			spec: `{"openapi":"3.0.0","info":{"title":"t","version":"1"},"paths":{"/a":{"get":{"responses":{"200":{"description":"ok"}}}}}}`,
+		},
+		{
+			name: "templated first segment",
+			spec: `{"openapi":"3.0.0","info":{"title":"t","version":"1"},"servers":[{"url":"http://a"}],"paths":{"/{tenant}/a":{"get":{"responses":{"200":{"description":"ok"}}}}}}`,
+		},
+		{
+			name: "different servers under the same prefix",
+			spec: `{"openapi":"3.0.0","info":{"title":"t","version":"1"},"servers":[{"url":"http://a"}],"paths":{
+				"/a/x":{"get":{"responses":{"200":{"description":"ok"}}}},
+				"/a/y":{"servers":[{"url":"http://b"}],"get":{"responses":{"200":{"description":"ok"}}}}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := LoadDocument([]byte(tt.spec))
+			require.NoError(t, err)
+
+			_, err = PlanRoutes(doc, PlanOptions{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStaticPathPrefix(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/", want: ""},
+		{path: "/users", want: "users"},
+		{path: "/users/", want: "users"},
+		{path: "/users/{id}", want: "users"},
+		{path: "/users/me/settings", want: "users/me/settings"},
+		{path: "/users/{id}/posts", want: "users"},
+		{path: "/{tenant}/users", want: ""},
+		{path: "/files/{name}.json", want: "files"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, staticPathPrefix(tt.path))
+		})
+	}
+}
+
+func TestLoadDocument(t *testing.T) {
+	_, err := LoadDocument([]byte(petstoreYAML))
+	assert.NoError(t, err)
+
+	_, err = LoadDocument([]byte(`{"swagger":"2.0","info":{"title":"t","version":"1"},"paths":{}}`))
+	assert.Error(t, err)
+
+	_, err = LoadDocument([]byte(`not: [valid`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,339 @@
+package openapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/uforg/ufogateway/internal/gateway"
+	"github.com/uforg/ufogateway/internal/util/strutil"
+)
+
+// pathParamRegexp matches the parameters of an OpenAPI path template.
+var pathParamRegexp = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// compiledPath is an OpenAPI path template ready to match request paths.
+type compiledPath struct {
+	path      string
+	item      *openapi3.PathItem
+	regexp    *regexp.Regexp
+	params    []string
+	staticLen int // is the number of literal characters, used to prefer the most specific path
+}
+
+// Validator validates requests and responses against an OpenAPI document, it
+// implements gateway.ContractValidator.
+//
+// Requests are matched by path only, ignoring the host of the document
+// servers, so the same document works for any origin. The base paths of the
+// servers are stripped before matching.
+type Validator struct {
+	doc       *openapi3.T
+	basePaths []string
+	paths     []compiledPath
+}
+
+var _ gateway.ContractValidator = (*Validator)(nil)
+
+// NewValidator returns a Validator for the given document.
+func NewValidator(doc *openapi3.T) (*Validator, error) {
+	v := &Validator{doc: doc}
+
+	servers := slices.Clone(doc.Servers)
+	if doc.Paths != nil {
+		for path, item := range doc.Paths.Map() {
+			compiled, err := compilePath(path, item)
+			if err != nil {
+				return nil, err
+			}
+			v.paths = append(v.paths, compiled)
+			servers = append(servers, item.Servers...)
+		}
+	}
+	slices.SortFunc(v.paths, func(a, b compiledPath) int {
+		return b.staticLen - a.staticLen
+	})
+
+	v.basePaths = serverBasePaths(servers)
+	return v, nil
+}
+
+// ValidateRequest validates the method, path, parameters and body of a
+// request. Security requirements are not checked.
+func (v *Validator) ValidateRequest(r *http.Request, originPath string, body []byte) []gateway.ContractFinding {
+	route, params, finding := v.findRoute(r.Method, originPath)
+	if finding != nil {
+		return []gateway.ContractFinding{*finding}
+	}
+
+	err := openapi3filter.ValidateRequest(r.Context(), v.requestInput(r, body, route, params))
+	return errorFindings("request", err)
+}
+
+// ValidateResponse validates the status, headers and body of the response to
+// a request. Responses to requests without a matching operation are not
+// validated, that is already reported by ValidateRequest.
+func (v *Validator) ValidateResponse(
+	r *http.Request,
+	originPath string,
+	statusCode int,
+	header http.Header,
+	body []byte,
+) []gateway.ContractFinding {
+	route, params, finding := v.findRoute(r.Method, originPath)
+	if finding != nil {
+		return nil
+	}
+
+	options := validationOptions()
+	options.IncludeResponseStatus = true
+
+	// Compressed bodies and the bodies of HEAD responses can't be decoded
+	encoding := header.Get("Content-Encoding")
+	if (encoding != "" && encoding != "identity") || r.Method == http.MethodHead {
+		options.ExcludeResponseBody = true
+	}
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: v.requestInput(r, nil, route, params),
+		Status:                 statusCode,
+		Header:                 header,
+		Body:                   io.NopCloser(bytes.NewReader(body)),
+		Options:                options,
+	}
+
+	err := openapi3filter.ValidateResponse(r.Context(), input)
+	return errorFindings("response", err)
+}
+
+func (v *Validator) requestInput(
+	r *http.Request,
+	body []byte,
+	route *routers.Route,
+	params map[string]string,
+) *openapi3filter.RequestValidationInput {
+	req := r.Clone(r.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	return &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: params,
+		Route:      route,
+		Options:    validationOptions(),
+	}
+}
+
+func validationOptions() *openapi3filter.Options {
+	return &openapi3filter.Options{
+		MultiError:          true,
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults: true,
+	}
+}
+
+// findRoute returns the operation of the document for a request, or the
+// finding explaining why there is none.
+func (v *Validator) findRoute(method string, originPath string) (*routers.Route, map[string]string, *gateway.ContractFinding) {
+	pathMatched := false
+
+	for _, basePath := range v.basePaths {
+		rest, ok := strings.CutPrefix(originPath, basePath)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			continue
+		}
+		if rest == "" {
+			rest = "/"
+		}
+
+		for _, p := range v.paths {
+			matches := p.regexp.FindStringSubmatch(rest)
+			if matches == nil {
+				continue
+			}
+			pathMatched = true
+
+			operation := p.item.GetOperation(method)
+			if operation == nil {
+				continue
+			}
+
+			params := make(map[string]string, len(p.params))
+			for i, name := range p.params {
+				value, err := url.PathUnescape(matches[i+1])
+				if err != nil {
+					value = matches[i+1]
+				}
+				params[name] = value
+			}
+
+			return &routers.Route{
+				Spec:      v.doc,
+				Path:      p.path,
+				PathItem:  p.item,
+				Method:    method,
+				Operation: operation,
+			}, params, nil
+		}
+	}
+
+	if pathMatched {
+		return nil, nil, &gateway.ContractFinding{
+			Kind:     "request",
+			Location: "method",
+			Message:  fmt.Sprintf("method %s is not allowed for path %s", method, originPath),
+		}
+	}
+	return nil, nil, &gateway.ContractFinding{
+		Kind:     "request",
+		Location: "path",
+		Message:  fmt.Sprintf("path %s is not part of the contract", originPath),
+	}
+}
+
+// compilePath turns an OpenAPI path template into a regular expression.
+func compilePath(path string, item *openapi3.PathItem) (compiledPath, error) {
+	compiled := compiledPath{path: path, item: item}
+
+	pattern := strings.Builder{}
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range pathParamRegexp.FindAllStringSubmatchIndex(path, -1) {
+		literal := path[last:loc[0]]
+		pattern.WriteString(regexp.QuoteMeta(literal))
+		pattern.WriteString("([^/]+)")
+		compiled.params = append(compiled.params, path[loc[2]:loc[3]])
+		compiled.staticLen += len(literal)
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(path[last:]))
+	pattern.WriteString("/?$")
+	compiled.staticLen += len(path) - last
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return compiledPath{}, fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	compiled.regexp = re
+
+	return compiled, nil
+}
+
+// serverBasePaths returns the distinct base paths of the servers, longest
+// first, always including the empty base path.
+func serverBasePaths(servers openapi3.Servers) []string {
+	basePaths := []string{""}
+	for _, server := range servers {
+		serverURL := server.URL
+		for name, variable := range server.Variables {
+			serverURL = strings.ReplaceAll(serverURL, "{"+name+"}", variable.Default)
+		}
+
+		u, err := url.Parse(serverURL)
+		if err != nil {
+			continue
+		}
+
+		basePath := strutil.RemoveAllTrailingSlashes(u.Path)
+		if basePath != "" && !strings.HasPrefix(basePath, "/") {
+			basePath = "/" + basePath
+		}
+		if !slices.Contains(basePaths, basePath) {
+			basePaths = append(basePaths, basePath)
+		}
+	}
+
+	slices.SortFunc(basePaths, func(a, b string) int {
+		return len(b) - len(a)
+	})
+	return basePaths
+}
+
+// errorFindings flattens the validation errors of kin-openapi into findings.
+func errorFindings(kind string, err error) []gateway.ContractFinding {
+	if err == nil {
+		return nil
+	}
+
+	// Only a top level MultiError is flattened here, the ones wrapped by
+	// other errors are the causes handled below
+	if multiErr, ok := err.(openapi3.MultiError); ok {
+		findings := []gateway.ContractFinding{}
+		for _, e := range multiErr {
+			findings = append(findings, errorFindings(kind, e)...)
+		}
+		return findings
+	}
+
+	location := ""
+	reason := err.Error()
+	var cause error
+
+	var requestErr *openapi3filter.RequestError
+	var responseErr *openapi3filter.ResponseError
+	var securityErr *openapi3filter.SecurityRequirementsError
+	switch {
+	case errors.As(err, &requestErr):
+		reason, cause = requestErr.Reason, requestErr.Err
+		switch {
+		case requestErr.Parameter != nil:
+			location = requestErr.Parameter.In + "." + requestErr.Parameter.Name
+		case requestErr.RequestBody != nil:
+			location = "body"
+		}
+	case errors.As(err, &responseErr):
+		reason, cause = responseErr.Reason, responseErr.Err
+		switch {
+		case strings.HasPrefix(reason, "status"):
+			location = "status"
+		case strings.Contains(reason, "header"):
+			location = "header"
+		default:
+			location = "body"
+		}
+	case errors.As(err, &securityErr):
+		location = "security"
+	}
+
+	// Schema errors point at the invalid value
+	var causeMultiErr openapi3.MultiError
+	if errors.As(cause, &causeMultiErr) {
+		findings := []gateway.ContractFinding{}
+		for _, e := range causeMultiErr {
+			findings = append(findings, schemaFinding(kind, location, reason, e))
+		}
+		return findings
+	}
+	if cause != nil {
+		return []gateway.ContractFinding{schemaFinding(kind, location, reason, cause)}
+	}
+
+	return []gateway.ContractFinding{{Kind: kind, Location: location, Message: reason}}
+}
+
+// schemaFinding returns the finding for the cause of a validation error,
+// pointing at the invalid value when the cause is a schema error.
+func schemaFinding(kind string, location string, reason string, cause error) gateway.ContractFinding {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(cause, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			location += "/" + strings.Join(pointer, "/")
+		}
+		return gateway.ContractFinding{Kind: kind, Location: location, Message: schemaErr.Reason}
+	}
+
+	message := cause.Error()
+	if reason != "" && reason != message {
+		message = reason + ": " + message
+	}
+	return gateway.ContractFinding{Kind: kind, Location: location, Message: message}
+}
@@ -0,0 +1,276 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uforg/ufogateway/internal/gateway"
+)
+
+const usersYAML = `
+openapi: 3.0.3
+info:
+  title: Users
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          required: true
+          schema:
+            type: integer
+            maximum: 100
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: object
+    post:
+      parameters:
+        - name: X-Tenant
+          in: header
+          required: true
+          schema:
+            type: string
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                age:
+                  type: integer
+                  minimum: 0
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id]
+                properties:
+                  id:
+                    type: string
+  /users/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        "200":
+          description: ok
+  /users/me:
+    get:
+      responses:
+        "200":
+          description: ok
+`
+
+func newTestValidator(t *testing.T) *Validator {
+	doc, err := LoadDocument([]byte(usersYAML))
+	require.NoError(t, err)
+	v, err := NewValidator(doc)
+	require.NoError(t, err)
+	return v
+}
+
+func TestValidatorValidateRequest(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		originPath string
+		query      string
+		header     map[string]string
+		body       string
+		want       []gateway.ContractFinding
+	}{
+		{
+			name:       "valid get",
+			method:     http.MethodGet,
+			originPath: "/v1/users",
+			query:      "limit=10",
+			want:       nil,
+		},
+		{
+			name:       "path without the server base path",
+			method:     http.MethodGet,
+			originPath: "/users",
+			query:      "limit=10",
+			want:       nil,
+		},
+		{
+			name:       "static path preferred over parameter",
+			method:     http.MethodGet,
+			originPath: "/v1/users/me",
+			want:       nil,
+		},
+		{
+			name:       "invalid path parameter",
+			method:     http.MethodGet,
+			originPath: "/v1/users/abc",
+			want: []gateway.ContractFinding{
+				{Kind: "request", Location: "path.id", Message: "value abc: an invalid integer: invalid syntax"},
+			},
+		},
+		{
+			name:       "invalid query parameter",
+			method:     http.MethodGet,
+			originPath: "/v1/users",
+			query:      "limit=500",
+			want: []gateway.ContractFinding{
+				{Kind: "request", Location: "query.limit", Message: "number must be at most 100"},
+			},
+		},
+		{
+			name:       "valid post",
+			method:     http.MethodPost,
+			originPath: "/v1/users",
+			header:     map[string]string{"X-Tenant": "a", "Content-Type": "application/json"},
+			body:       `{"name":"ann","age":3}`,
+			want:       nil,
+		},
+		{
+			name:       "invalid header and body",
+			method:     http.MethodPost,
+			originPath: "/v1/users",
+			header:     map[string]string{"Content-Type": "application/json"},
+			body:       `{"age":-1}`,
+			want: []gateway.ContractFinding{
+				{Kind: "request", Location: "header.X-Tenant", Message: "value is required but missing"},
+				{Kind: "request", Location: "body/age", Message: "number must be at least 0"},
+				{Kind: "request", Location: "body/name", Message: `property "name" is missing`},
+			},
+		},
+		{
+			name:       "unknown path",
+			method:     http.MethodGet,
+			originPath: "/v1/orders",
+			want: []gateway.ContractFinding{
+				{Kind: "request", Location: "path", Message: "path /v1/orders is not part of the contract"},
+			},
+		},
+		{
+			name:       "method not allowed",
+			method:     http.MethodDelete,
+			originPath: "/v1/users",
+			want: []gateway.ContractFinding{
+				{Kind: "request", Location: "method", Message: "method DELETE is not allowed for path /v1/users"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/gateway?"+tt.query, strings.NewReader(tt.body))
+			for k, val := range tt.header {
+				r.Header.Set(k, val)
+			}
+
+			got := v.ValidateRequest(r, tt.originPath, []byte(tt.body))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidatorValidateResponse(t *testing.T) {
+	v := newTestValidator(t)
+	jsonHeader := http.Header{"Content-Type": {"application/json"}}
+
+	tests := []struct {
+		name       string
+		method     string
+		originPath string
+		status     int
+		header     http.Header
+		body       string
+		want       []gateway.ContractFinding
+	}{
+		{
+			name:       "valid response",
+			method:     http.MethodPost,
+			originPath: "/v1/users",
+			status:     201,
+			header:     jsonHeader,
+			body:       `{"id":"1"}`,
+			want:       nil,
+		},
+		{
+			name:       "invalid body",
+			method:     http.MethodPost,
+			originPath: "/v1/users",
+			status:     201,
+			header:     jsonHeader,
+			body:       `{"id":1}`,
+			want: []gateway.ContractFinding{
+				{Kind: "response", Location: "body/id", Message: "value must be a string"},
+			},
+		},
+		{
+			name:       "undocumented status",
+			method:     http.MethodPost,
+			originPath: "/v1/users",
+			status:     500,
+			header:     jsonHeader,
+			body:       `{}`,
+			want: []gateway.ContractFinding{
+				{Kind: "response", Location: "status", Message: "status is not supported"},
+			},
+		},
+		{
+			name:       "compressed body is not validated",
+			method:     http.MethodPost,
+			originPath: "/v1/users",
+			status:     201,
+			header:     http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}},
+			body:       "\x1f\x8b",
+			want:       nil,
+		},
+		{
+			name:       "unknown path is not validated",
+			method:     http.MethodGet,
+			originPath: "/v1/orders",
+			status:     200,
+			header:     jsonHeader,
+			body:       `{}`,
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/gateway", nil)
+			got := v.ValidateResponse(r, tt.originPath, tt.status, tt.header, []byte(tt.body))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServerBasePaths(t *testing.T) {
+	doc, err := LoadDocument([]byte(`{"openapi":"3.0.0","info":{"title":"t","version":"1"},"paths":{},
+		"servers":[{"url":"https://a.example.com/v1/"},{"url":"/api/{version}","variables":{"version":{"default":"v2"}}},{"url":"https://b.example.com"}]}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/api/v2", "/v1", ""}, serverBasePaths(doc.Servers))
+}
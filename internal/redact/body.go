@@ -0,0 +1,39 @@
+package redact
+
+import (
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaskBody masks a body according to its media type, JSON paths in JSON
+// bodies and form fields in URL encoded forms, and then the patterns in any
+// text body. It returns what was masked.
+func (r Rules) MaskBody(body []byte, contentType string) ([]byte, []string) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	masked := []string{}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var paths []string
+		body, paths = r.MaskJSON(body)
+		masked = append(masked, paths...)
+	case mediaType == "application/x-www-form-urlencoded":
+		form, fields := r.MaskForm(string(body))
+		if len(fields) > 0 {
+			body = []byte(form)
+			masked = append(masked, fields...)
+		}
+	}
+
+	if len(r.Patterns) == 0 || !utf8.Valid(body) {
+		return body, masked
+	}
+
+	text, patterns := r.MaskText(string(body))
+	if len(patterns) > 0 {
+		body = []byte(text)
+		masked = append(masked, patterns...)
+	}
+	return body, masked
+}
@@ -0,0 +1,61 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+		wantMasked  []string
+	}{
+		{
+			name:        "json",
+			body:        `{"password":"x","note":"card 4111111111111111"}`,
+			contentType: "application/json; charset=utf-8",
+			want:        `{"note":"card [REDACTED]","password":"[REDACTED]"}`,
+			wantMasked:  []string{"$.password", "pattern:card_number"},
+		},
+		{
+			name:        "problem json",
+			body:        `{"secret":"x"}`,
+			contentType: "application/problem+json",
+			want:        `{"secret":"[REDACTED]"}`,
+			wantMasked:  []string{"$.secret"},
+		},
+		{
+			name:        "form",
+			body:        "user=bob&password=x",
+			contentType: "application/x-www-form-urlencoded",
+			want:        "user=bob&password=%5BREDACTED%5D",
+			wantMasked:  []string{"password"},
+		},
+		{
+			name:        "text is only masked by patterns",
+			body:        "password=x card=4111111111111111",
+			contentType: "text/plain",
+			want:        "password=x card=[REDACTED]",
+			wantMasked:  []string{"pattern:card_number"},
+		},
+		{
+			name:        "binary",
+			body:        "\xff4111111111111111",
+			contentType: "application/octet-stream",
+			want:        "\xff4111111111111111",
+			wantMasked:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, masked := DefaultRules().MaskBody([]byte(tt.body), tt.contentType)
+			assert.Equal(t, tt.want, string(got))
+			assert.Equal(t, tt.wantMasked, masked)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package redact
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// MaskForm masks the values of the rule form fields in an URL encoded form
+// or query string, keeping the order and encoding of everything else. It
+// returns the masked field names.
+func (r Rules) MaskForm(s string) (string, []string) {
+	if s == "" || len(r.FormFields) == 0 {
+		return s, nil
+	}
+
+	masked := []string{}
+	pairs := strings.Split(s, "&")
+	for i, pair := range pairs {
+		rawKey, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+
+		if !r.isFormField(key) {
+			continue
+		}
+
+		pairs[i] = rawKey + "=" + url.QueryEscape(Mask)
+		if !slices.Contains(masked, key) {
+			masked = append(masked, key)
+		}
+	}
+
+	return strings.Join(pairs, "&"), masked
+}
+
+// isFormField reports whether key is one of the rule form fields, which are
+// matched without case.
+func (r Rules) isFormField(key string) bool {
+	for _, field := range r.FormFields {
+		if strings.EqualFold(field, key) {
+			return true
+		}
+	}
+	return false
+}
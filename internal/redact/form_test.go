@@ -0,0 +1,38 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskForm(t *testing.T) {
+	tests := []struct {
+		name       string
+		s          string
+		want       string
+		wantMasked []string
+	}{
+		{name: "empty", s: "", want: "", wantMasked: nil},
+		{
+			name:       "nothing to mask",
+			s:          "b=2&a=1",
+			want:       "b=2&a=1",
+			wantMasked: []string{},
+		},
+		{
+			name:       "masked fields keep the order",
+			s:          "user=bob&Password=hunter2&x=%20&password&api%5Fkey=k",
+			want:       "user=bob&Password=%5BREDACTED%5D&x=%20&password=%5BREDACTED%5D&api%5Fkey=%5BREDACTED%5D",
+			wantMasked: []string{"Password", "password", "api_key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, masked := DefaultRules().MaskForm(tt.s)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantMasked, masked)
+		})
+	}
+}
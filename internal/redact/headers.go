@@ -0,0 +1,41 @@
+package redact
+
+import (
+	"net/http"
+	"slices"
+)
+
+// MaskHeaders returns a copy of the headers with the values of the rule
+// headers masked and the patterns masked in the other values. It also
+// returns what was masked.
+func (r Rules) MaskHeaders(headers map[string][]string) (map[string][]string, []string) {
+	if headers == nil {
+		return nil, nil
+	}
+
+	masked := []string{}
+	result := make(map[string][]string, len(headers))
+	for key, values := range headers {
+		name := http.CanonicalHeaderKey(key)
+		maskedValues := make([]string, len(values))
+
+		if slices.Contains(r.Headers, name) {
+			for i := range values {
+				maskedValues[i] = Mask
+			}
+			masked = append(masked, name)
+			result[key] = maskedValues
+			continue
+		}
+
+		for i, value := range values {
+			var patterns []string
+			maskedValues[i], patterns = r.MaskText(value)
+			masked = append(masked, patterns...)
+		}
+		result[key] = maskedValues
+	}
+
+	slices.Sort(masked)
+	return result, slices.Compact(masked)
+}
@@ -0,0 +1,31 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskHeaders(t *testing.T) {
+	headers := map[string][]string{
+		"Authorization": {"Bearer abc"},
+		"cookie":        {"a=1", "b=2"},
+		"X-Card":        {"4111111111111111"},
+		"Accept":        {"application/json"},
+	}
+
+	got, masked := DefaultRules().MaskHeaders(headers)
+
+	assert.Equal(t, map[string][]string{
+		"Authorization": {Mask},
+		"cookie":        {Mask, Mask},
+		"X-Card":        {Mask},
+		"Accept":        {"application/json"},
+	}, got)
+	assert.Equal(t, []string{"Authorization", "Cookie", "pattern:card_number"}, masked)
+	assert.Equal(t, []string{"Bearer abc"}, headers["Authorization"], "the headers are copied")
+
+	got, masked = DefaultRules().MaskHeaders(nil)
+	assert.Nil(t, got)
+	assert.Empty(t, masked)
+}
@@ -0,0 +1,101 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// MaskJSON masks the values at the rule JSON paths of a JSON document and
+// returns the paths that were masked. Documents that don't parse or have
+// nothing to mask are returned as they are.
+//
+// A path is a dot separated list of object keys, where * matches any key and
+// arrays are walked through as if their elements were in place of the array,
+// so "items.card" matches the card key of every element of items. Paths that
+// start with "$." are anchored at the root of the document, others match at
+// any depth, so "password" matches every password key.
+func (r Rules) MaskJSON(data []byte) ([]byte, []string) {
+	if len(r.JSONPaths) == 0 {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return data, nil
+	}
+
+	paths := make([]jsonPath, 0, len(r.JSONPaths))
+	for _, path := range r.JSONPaths {
+		paths = append(paths, parseJSONPath(path))
+	}
+
+	masked := []string{}
+	document = maskJSONValue(document, nil, paths, &masked)
+	if len(masked) == 0 {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(document); err != nil {
+		return data, nil
+	}
+
+	slices.Sort(masked)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), slices.Compact(masked)
+}
+
+type jsonPath struct {
+	anchored bool
+	keys     []string
+}
+
+func parseJSONPath(path string) jsonPath {
+	path = strings.TrimSpace(path)
+	anchored := strings.HasPrefix(path, "$.")
+	return jsonPath{
+		anchored: anchored,
+		keys:     strings.Split(strings.TrimPrefix(path, "$."), "."),
+	}
+}
+
+// matches reports whether the path selects the value at location.
+func (p jsonPath) matches(location []string) bool {
+	if len(location) < len(p.keys) || (p.anchored && len(location) != len(p.keys)) {
+		return false
+	}
+
+	offset := len(location) - len(p.keys)
+	for i, key := range p.keys {
+		if key != "*" && key != location[offset+i] {
+			return false
+		}
+	}
+	return true
+}
+
+func maskJSONValue(value any, location []string, paths []jsonPath, masked *[]string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			childLocation := append(slices.Clip(location), key)
+			if slices.ContainsFunc(paths, func(p jsonPath) bool { return p.matches(childLocation) }) {
+				v[key] = Mask
+				*masked = append(*masked, "$."+strings.Join(childLocation, "."))
+				continue
+			}
+			v[key] = maskJSONValue(child, childLocation, paths, masked)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = maskJSONValue(child, location, paths, masked)
+		}
+	}
+	return value
+}
@@ -0,0 +1,68 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		paths      []string
+		data       string
+		want       string
+		wantMasked []string
+	}{
+		{
+			name:       "invalid json",
+			paths:      []string{"password"},
+			data:       `{"password":`,
+			want:       `{"password":`,
+			wantMasked: nil,
+		},
+		{
+			name:       "nothing to mask keeps the document",
+			paths:      []string{"password"},
+			data:       `{ "b": 1, "a": 2 }`,
+			want:       `{ "b": 1, "a": 2 }`,
+			wantMasked: nil,
+		},
+		{
+			name:       "key at any depth",
+			paths:      []string{"password"},
+			data:       `{"password":"a","user":{"password":{"old":"b"},"id":12345678901234567890}}`,
+			want:       `{"password":"[REDACTED]","user":{"id":12345678901234567890,"password":"[REDACTED]"}}`,
+			wantMasked: []string{"$.password", "$.user.password"},
+		},
+		{
+			name:       "anchored path",
+			paths:      []string{"$.pin"},
+			data:       `{"pin":"1","user":{"pin":"2"}}`,
+			want:       `{"pin":"[REDACTED]","user":{"pin":"2"}}`,
+			wantMasked: []string{"$.pin"},
+		},
+		{
+			name:       "arrays and wildcards",
+			paths:      []string{"$.items.card.*"},
+			data:       `{"items":[{"card":{"number":"4111","cvc":"123"}},{"card":null}],"card":{"cvc":"1"}}`,
+			want:       `{"card":{"cvc":"1"},"items":[{"card":{"cvc":"[REDACTED]","number":"[REDACTED]"}},{"card":null}]}`,
+			wantMasked: []string{"$.items.card.cvc", "$.items.card.number"},
+		},
+		{
+			name:       "no html escaping",
+			paths:      []string{"secret"},
+			data:       `[{"secret":"x","html":"<b>&</b>"}]`,
+			want:       `[{"html":"<b>&</b>","secret":"[REDACTED]"}]`,
+			wantMasked: []string{"$.secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, masked := Rules{JSONPaths: tt.paths}.MaskJSON([]byte(tt.data))
+			assert.Equal(t, tt.want, string(got))
+			assert.Equal(t, tt.wantMasked, masked)
+		})
+	}
+}
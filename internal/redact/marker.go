@@ -0,0 +1,17 @@
+package redact
+
+import "slices"
+
+// Marker records what was masked in each stored field, keyed by the field
+// name. Values are header names, JSON paths, form fields or "pattern:" and
+// the pattern name, never the masked values.
+type Marker map[string][]string
+
+// Add records the masked parts of a field, ignoring duplicates.
+func (m Marker) Add(field string, masked []string) {
+	for _, part := range masked {
+		if !slices.Contains(m[field], part) {
+			m[field] = append(m[field], part)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkerAdd(t *testing.T) {
+	marker := Marker{}
+	marker.Add("req_headers", []string{"Authorization"})
+	marker.Add("req_headers", []string{"Cookie", "Authorization"})
+	marker.Add("req_body", nil)
+
+	assert.Equal(t, Marker{"req_headers": {"Authorization", "Cookie"}}, marker)
+}
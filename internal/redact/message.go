@@ -0,0 +1,62 @@
+package redact
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var urlInText = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// MaskMessage masks a free text message that can quote parts of a request,
+// like an error of the transport. URLs in it are masked as by MaskURL and the
+// patterns anywhere else.
+func (r Rules) MaskMessage(s string) (string, []string) {
+	masked := []string{}
+	s = urlInText.ReplaceAllStringFunc(s, func(rawURL string) string {
+		rawURL, parts := r.MaskURL(rawURL)
+		masked = append(masked, parts...)
+		return rawURL
+	})
+
+	s, patterns := r.MaskText(s)
+	masked = append(masked, patterns...)
+
+	slices.Sort(masked)
+	return s, slices.Compact(masked)
+}
+
+// MaskedLocation reports whether the rules mask the value at a location of a
+// request or response and returns what masks it. Locations are written as
+// "header.Name", "cookie.name", "query.name" or "body/key/..." with the JSON
+// pointer of the value, array indexes included.
+func (r Rules) MaskedLocation(location string) (string, bool) {
+	if pointer, ok := strings.CutPrefix(location, "body/"); ok {
+		keys := []string{}
+		for _, key := range strings.Split(pointer, "/") {
+			if _, err := strconv.Atoi(key); err != nil {
+				keys = append(keys, key)
+			}
+		}
+
+		for _, path := range r.JSONPaths {
+			if parseJSONPath(path).matches(keys) {
+				return "$." + strings.Join(keys, "."), true
+			}
+		}
+		return "", false
+	}
+
+	in, name, _ := strings.Cut(location, ".")
+	switch {
+	case in == "header" && slices.Contains(r.Headers, http.CanonicalHeaderKey(name)):
+		return http.CanonicalHeaderKey(name), true
+	case in == "cookie" && slices.Contains(r.Headers, "Cookie"):
+		return "Cookie", true
+	case in == "query" && r.isFormField(name):
+		return name, true
+	}
+	return "", false
+}
@@ -0,0 +1,71 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		s          string
+		want       string
+		wantMasked []string
+	}{
+		{
+			name:       "nothing to mask",
+			s:          `Get "http://origin/users?page=2": dial tcp: connection refused`,
+			want:       `Get "http://origin/users?page=2": dial tcp: connection refused`,
+			wantMasked: []string{},
+		},
+		{
+			name:       "query field in url",
+			s:          `Get "http://origin/login?access_token=abc&user=bob": EOF`,
+			want:       `Get "http://origin/login?access_token=%5BREDACTED%5D&user=bob": EOF`,
+			wantMasked: []string{"access_token"},
+		},
+		{
+			name:       "pattern in url and text",
+			s:          `Post "https://origin/cards/4111111111111111": card 4111 1111 1111 1111 declined`,
+			want:       `Post "https://origin/cards/[REDACTED]": card [REDACTED] declined`,
+			wantMasked: []string{"pattern:card_number"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, masked := DefaultRules().MaskMessage(tt.s)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantMasked, masked)
+		})
+	}
+}
+
+func TestMaskedLocation(t *testing.T) {
+	tests := []struct {
+		location   string
+		want       string
+		wantMasked bool
+	}{
+		{location: "header.authorization", want: "Authorization", wantMasked: true},
+		{location: "header.X-Request-Id", want: "", wantMasked: false},
+		{location: "cookie.session", want: "Cookie", wantMasked: true},
+		{location: "query.Access_Token", want: "Access_Token", wantMasked: true},
+		{location: "query.limit", want: "", wantMasked: false},
+		{location: "path.id", want: "", wantMasked: false},
+		{location: "body/user/password", want: "$.user.password", wantMasked: true},
+		{location: "body/items/0/secret", want: "$.items.secret", wantMasked: true},
+		{location: "body/name", want: "", wantMasked: false},
+		{location: "body", want: "", wantMasked: false},
+		{location: "status", want: "", wantMasked: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			got, masked := DefaultRules().MaskedLocation(tt.location)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantMasked, masked)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/uforg/ufogateway/internal/util/strutil"
+)
+
+// Mask replaces every redacted value.
+const Mask = "[REDACTED]"
+
+// Rules selects the sensitive parts of logged requests and responses.
+type Rules struct {
+	Headers    []string  // are canonical header names whose values are masked
+	JSONPaths  []string  // are JSON paths whose values are masked, see MaskJSON
+	FormFields []string  // are form and query fields whose values are masked
+	Patterns   []Pattern // are masked wherever they match in text
+}
+
+// Pattern is a regular expression for sensitive text such as card numbers.
+type Pattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+	// Valid, when set, filters out matches that only look sensitive
+	Valid func(match string) bool
+}
+
+var defaultRules = Rules{
+	Headers: []string{
+		"Authorization",
+		"Proxy-Authorization",
+		"Cookie",
+		"Set-Cookie",
+		"X-Api-Key",
+		"X-Auth-Token",
+	},
+	JSONPaths: []string{
+		"password",
+		"secret",
+		"client_secret",
+		"access_token",
+		"refresh_token",
+		"api_key",
+	},
+	FormFields: []string{
+		"password",
+		"secret",
+		"client_secret",
+		"access_token",
+		"refresh_token",
+		"api_key",
+	},
+	Patterns: []Pattern{
+		{
+			Name:   "card_number",
+			Regexp: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+			Valid:  isCardNumber,
+		},
+	},
+}
+
+// DefaultRules returns the rules applied to every project unless it skips
+// them.
+func DefaultRules() Rules {
+	return defaultRules
+}
+
+// ParseRules builds rules from the user provided lists of a project. Header
+// names, JSON paths and form fields are lists as read by strutil.SplitList,
+// patterns are regular expressions one per line. Patterns that don't compile
+// are left out and reported in the returned error.
+func ParseRules(headers, jsonPaths, formFields, patterns string) (Rules, error) {
+	rules := Rules{
+		JSONPaths:  strutil.SplitList(jsonPaths),
+		FormFields: strutil.SplitList(formFields),
+	}
+
+	for _, header := range strutil.SplitList(headers) {
+		rules.Headers = append(rules.Headers, http.CanonicalHeaderKey(header))
+	}
+
+	errs := []error{}
+	for _, pattern := range strings.Split(patterns, "\n") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err))
+			continue
+		}
+		rules.Patterns = append(rules.Patterns, Pattern{Name: pattern, Regexp: re})
+	}
+
+	return rules, errors.Join(errs...)
+}
+
+// Merge returns the rules of both r and other.
+func (r Rules) Merge(other Rules) Rules {
+	return Rules{
+		Headers:    append(append([]string{}, r.Headers...), other.Headers...),
+		JSONPaths:  append(append([]string{}, r.JSONPaths...), other.JSONPaths...),
+		FormFields: append(append([]string{}, r.FormFields...), other.FormFields...),
+		Patterns:   append(append([]Pattern{}, r.Patterns...), other.Patterns...),
+	}
+}
+
+// isCardNumber reports whether the digits of s pass the Luhn checksum, so
+// only plausible card numbers are masked and not every long number.
+func isCardNumber(s string) bool {
+	sum, digits := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+
+		digit := int(s[i] - '0')
+		if digits%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
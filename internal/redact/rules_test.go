@@ -0,0 +1,63 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(
+		"x-secret, authorization",
+		"$.user.pin\ncard.*",
+		"pin",
+		"  \nsk_live_[a-z0-9]+\n  tok_[0-9]{4}, ok  \n",
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"X-Secret", "Authorization"}, rules.Headers)
+	assert.Equal(t, []string{"$.user.pin", "card.*"}, rules.JSONPaths)
+	assert.Equal(t, []string{"pin"}, rules.FormFields)
+	require.Len(t, rules.Patterns, 2)
+	assert.Equal(t, "sk_live_[a-z0-9]+", rules.Patterns[0].Name)
+	assert.Equal(t, "tok_[0-9]{4}, ok", rules.Patterns[1].Name)
+}
+
+func TestParseRulesInvalidPattern(t *testing.T) {
+	rules, err := ParseRules("", "", "", "valid\n(invalid")
+	assert.ErrorContains(t, err, `invalid redaction pattern "(invalid"`)
+	require.Len(t, rules.Patterns, 1)
+	assert.Equal(t, "valid", rules.Patterns[0].Name)
+}
+
+func TestRulesMerge(t *testing.T) {
+	project := Rules{Headers: []string{"X-Secret"}, FormFields: []string{"pin"}}
+	merged := DefaultRules().Merge(project)
+
+	assert.Contains(t, merged.Headers, "Authorization")
+	assert.Contains(t, merged.Headers, "X-Secret")
+	assert.Contains(t, merged.FormFields, "pin")
+	assert.Len(t, merged.Patterns, len(DefaultRules().Patterns))
+	assert.NotContains(t, DefaultRules().Headers, "X-Secret")
+}
+
+func TestIsCardNumber(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{s: "4111111111111111", want: true},
+		{s: "4111 1111 1111 1111", want: true},
+		{s: "5500-0000-0000-0004", want: true},
+		{s: "4111111111111112", want: false},
+		{s: "1737817200000", want: false},
+		{s: "123456789012", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			assert.Equal(t, tt.want, isCardNumber(tt.s))
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package redact
+
+// MaskText masks the matches of the patterns in s and returns the names of
+// the patterns that matched, prefixed with "pattern:".
+func (r Rules) MaskText(s string) (string, []string) {
+	masked := []string{}
+	for _, pattern := range r.Patterns {
+		matched := false
+		s = pattern.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			if pattern.Valid != nil && !pattern.Valid(match) {
+				return match
+			}
+			matched = true
+			return Mask
+		})
+		if matched {
+			masked = append(masked, "pattern:"+pattern.Name)
+		}
+	}
+	return s, masked
+}
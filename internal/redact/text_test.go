@@ -0,0 +1,47 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskText(t *testing.T) {
+	rules, err := ParseRules("", "", "", "sk_live_[a-z0-9]+")
+	assert.NoError(t, err)
+	rules = DefaultRules().Merge(rules)
+
+	tests := []struct {
+		name       string
+		s          string
+		want       string
+		wantMasked []string
+	}{
+		{
+			name:       "nothing to mask",
+			s:          "order 1737817200000 placed",
+			want:       "order 1737817200000 placed",
+			wantMasked: []string{},
+		},
+		{
+			name:       "card number",
+			s:          "paid with 4111 1111 1111 1111.",
+			want:       "paid with [REDACTED].",
+			wantMasked: []string{"pattern:card_number"},
+		},
+		{
+			name:       "custom pattern",
+			s:          "key=sk_live_abc123 and 4111111111111112",
+			want:       "key=[REDACTED] and 4111111111111112",
+			wantMasked: []string{"pattern:sk_live_[a-z0-9]+"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, masked := rules.MaskText(tt.s)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantMasked, masked)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package redact
+
+import (
+	"net/url"
+	"slices"
+)
+
+// MaskURL masks the rule form fields in the query of an URL and the patterns
+// anywhere in it, returning what was masked.
+func (r Rules) MaskURL(rawURL string) (string, []string) {
+	masked := []string{}
+
+	if u, err := url.Parse(rawURL); err == nil && u.RawQuery != "" {
+		var fields []string
+		u.RawQuery, fields = r.MaskForm(u.RawQuery)
+		if len(fields) > 0 {
+			rawURL = u.String()
+			masked = append(masked, fields...)
+		}
+	}
+
+	rawURL, patterns := r.MaskText(rawURL)
+	masked = append(masked, patterns...)
+
+	return rawURL, slices.Compact(masked)
+}
@@ -0,0 +1,43 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		want       string
+		wantMasked []string
+	}{
+		{
+			name:       "nothing to mask",
+			url:        "http://example.com/users/1?page=2",
+			want:       "http://example.com/users/1?page=2",
+			wantMasked: []string{},
+		},
+		{
+			name:       "query field",
+			url:        "http://example.com/login?user=bob&access_token=abc",
+			want:       "http://example.com/login?user=bob&access_token=%5BREDACTED%5D",
+			wantMasked: []string{"access_token"},
+		},
+		{
+			name:       "pattern in path",
+			url:        "/cards/4111111111111111/charges",
+			want:       "/cards/[REDACTED]/charges",
+			wantMasked: []string{"pattern:card_number"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, masked := DefaultRules().MaskURL(tt.url)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantMasked, masked)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCommand returns the "replay" command that sends stored requests again
+// through the gateway and prints the result as JSON.
+func NewCommand(replayer *Replayer) *cobra.Command {
+	opts := Options{}
+	headers := []string{}
+
+	command := &cobra.Command{
+		Use:   "replay",
+		Short: "Replays stored requests through their route or a target URL",
+		Long: `Replays stored requests through their route or a target URL.
+
+Headers stored redacted, like Authorization or Cookie by default, are not
+sent since their values are lost. They are listed in the skipped_headers of
+each replay, use --header to send them with a new value.
+
+Requests with redacted values in their URL or body, like a password field,
+are not replayed and their masked parts are listed in the redacted of each
+replay. Use --send-redacted to replay them with the mask in their place.`,
+		Example: `  ufogateway replay --id abc123def456ghi
+  ufogateway replay --filter "route = 'abc123def456ghi' && res_status >= 500" --limit 50
+  ufogateway replay --id abc123def456ghi --target http://localhost:3000 --header "Authorization: Bearer test"`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Headers = make(map[string]string, len(headers))
+			for _, h := range headers {
+				name, value, err := ParseHeaderOverride(h)
+				if err != nil {
+					return err
+				}
+				opts.Headers[name] = value
+			}
+
+			result, err := replayer.Replay(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(result); err != nil {
+				return err
+			}
+
+			if failed := countFailed(result); failed > 0 {
+				return fmt.Errorf("%d of %d requests failed to replay", failed, len(result.Replays))
+			}
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&opts.RequestID, "id", "", "the id of the stored request to replay")
+	command.Flags().StringVar(&opts.Filter, "filter", "", "a filter over the requests collection selecting the requests to replay")
+	command.Flags().IntVar(&opts.Limit, "limit", DefaultLimit, "the maximum number of requests to replay when using --filter")
+	command.Flags().StringVar(&opts.TargetURL, "target", "", "an origin URL that replaces the route origin")
+	command.Flags().BoolVar(&opts.SendRedacted, "send-redacted", false, "replay requests with redacted URL or body values, sending the mask in their place")
+	command.Flags().StringArrayVar(&headers, "header", nil, `a "Name: value" header override, "Name:" removes the header (repeatable)`)
+	command.MarkFlagsMutuallyExclusive("id", "filter")
+	command.MarkFlagsOneRequired("id", "filter")
+
+	return command
+}
+
+// countFailed returns the number of replays in the result that failed.
+func countFailed(result Result) int {
+	failed := 0
+	for _, item := range result.Replays {
+		if item.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
@@ -0,0 +1,91 @@
+package replay
+
+import (
+	"cmp"
+
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/diff"
+)
+
+// BatchDiff summarises how the responses of a replay batch compare to the
+// responses of the original requests.
+type BatchDiff struct {
+	BatchID    string          `json:"batch_id"`
+	Total      int             `json:"total"`
+	Matches    int             `json:"matches"`
+	Mismatches int             `json:"mismatches"`
+	Errors     int             `json:"errors"`
+	Items      []BatchDiffItem `json:"items"`
+}
+
+// BatchDiffItem is the comparison of a single replay with its original
+// request. Diff is only set for mismatches.
+type BatchDiffItem struct {
+	OriginalID string       `json:"original_id"`
+	RequestID  string       `json:"request_id"`
+	Match      bool         `json:"match"`
+	Diff       *diff.Result `json:"diff,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// DiffBatch compares every replay of the batch with its original request.
+//
+// Only the response parts the route was configured to store can be
+// compared, a missing original or an unreadable body is reported as an
+// error of that item.
+func (rp *Replayer) DiffBatch(batchID string, opts diff.Options) (BatchDiff, error) {
+	replays, err := rp.db.FindStoredRequestsByReplayBatch(batchID)
+	if err != nil {
+		return BatchDiff{}, err
+	}
+
+	result := BatchDiff{
+		BatchID: batchID,
+		Total:   len(replays),
+		Items:   make([]BatchDiffItem, 0, len(replays)),
+	}
+
+	for _, replayed := range replays {
+		item := BatchDiffItem{
+			OriginalID: replayed.ReplayOf,
+			RequestID:  replayed.ID,
+		}
+
+		original, err := rp.db.GetStoredRequestByID(replayed.ReplayOf)
+		if err != nil {
+			item.Error = err.Error()
+			result.Errors++
+			result.Items = append(result.Items, item)
+			continue
+		}
+
+		if bodyErr := cmp.Or(original.ResBodyError, replayed.ResBodyError); bodyErr != "" {
+			item.Error = "the stored response body can't be read: " + bodyErr
+			result.Errors++
+			result.Items = append(result.Items, item)
+			continue
+		}
+
+		d := diff.Compare(StoredResponse(original), StoredResponse(replayed), opts)
+		item.Match = d.Match
+		if d.Match {
+			result.Matches++
+		} else {
+			item.Diff = &d
+			result.Mismatches++
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	return result, nil
+}
+
+// StoredResponse returns the stored response of a request in the form used
+// by the diff package.
+func StoredResponse(storedRequest db.StoredRequest) diff.Response {
+	return diff.Response{
+		Status:  storedRequest.ResStatus,
+		Headers: storedRequest.ResHeaders,
+		Body:    string(storedRequest.ResBodyBytes()),
+	}
+}
@@ -0,0 +1,18 @@
+package replay
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseHeaderOverride parses a "Name: value" header override as given on the
+// command line. "Name:" with no value removes the header from the replay.
+func ParseHeaderOverride(s string) (string, string, error) {
+	name, value, found := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		return "", "", fmt.Errorf("invalid header override %q, expected \"Name: value\"", s)
+	}
+
+	return name, strings.TrimSpace(value), nil
+}
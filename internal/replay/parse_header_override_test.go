@@ -0,0 +1,65 @@
+package replay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHeaderOverride(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantName  string
+		wantValue string
+		wantErr   bool
+	}{
+		{
+			name:      "name and value",
+			input:     "Authorization: Bearer abc",
+			wantName:  "Authorization",
+			wantValue: "Bearer abc",
+		},
+		{
+			name:      "value containing colons",
+			input:     "X-Time: 12:30:00",
+			wantName:  "X-Time",
+			wantValue: "12:30:00",
+		},
+		{
+			name:      "empty value",
+			input:     "Cookie:",
+			wantName:  "Cookie",
+			wantValue: "",
+		},
+		{
+			name:      "surrounding spaces",
+			input:     "  X-Env :  staging ",
+			wantName:  "X-Env",
+			wantValue: "staging",
+		},
+		{
+			name:    "missing colon",
+			input:   "X-Env staging",
+			wantErr: true,
+		},
+		{
+			name:    "missing name",
+			input:   ": value",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, value, err := ParseHeaderOverride(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantValue, value)
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/uforg/ufogateway/internal/bodycodec"
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/gateway"
+	"github.com/uforg/ufogateway/internal/logstorer"
+	"github.com/uforg/ufogateway/internal/redact"
+	"github.com/uforg/ufogateway/internal/util/randutil"
+)
+
+const (
+	// DefaultLimit is the number of requests replayed for a filter when no
+	// limit is given.
+	DefaultLimit = 100
+	// MaxLimit is the maximum number of requests replayed in a single batch.
+	MaxLimit = 1000
+)
+
+// Options selects the stored requests to replay and how to send them.
+//
+// Exactly one of RequestID or Filter must be set.
+type Options struct {
+	RequestID string            `json:"request_id"` // ID of a single stored request
+	Filter    string            `json:"filter"`     // PocketBase filter over the requests collection
+	Limit     int               `json:"limit"`      // Maximum number of requests matched by Filter
+	TargetURL string            `json:"target_url"` // Origin URL that replaces the route origin
+	Headers   map[string]string `json:"headers"`    // Header overrides, an empty value removes the header
+	// SendRedacted replays the requests whose stored URL or body had values
+	// masked, sending the mask in place of the values
+	SendRedacted bool `json:"send_redacted"`
+}
+
+// Result is the outcome of a replay batch.
+type Result struct {
+	BatchID string       `json:"batch_id"`
+	Replays []ReplayItem `json:"replays"`
+}
+
+// ReplayItem is the outcome of replaying a single stored request.
+type ReplayItem struct {
+	OriginalID     string        `json:"original_id"`
+	RequestID      string        `json:"request_id,omitempty"`
+	Status         int           `json:"status,omitempty"`
+	DurationUs     int64         `json:"duration_us,omitempty"`
+	SkippedHeaders []string      `json:"skipped_headers,omitempty"` // were stored redacted and not overridden
+	Redacted       redact.Marker `json:"redacted,omitempty"`        // are the masked parts of the stored URL and body
+	Error          string        `json:"error,omitempty"`
+}
+
+type Replayer struct {
+	app       *pocketbase.PocketBase
+	db        *db.DB
+	gateway   *gateway.Gateway
+	logStorer *logstorer.LogStorer
+}
+
+// NewReplayer returns a Replayer sending the requests through gateway, which
+// must store its logs with logStorer.
+func NewReplayer(
+	app *pocketbase.PocketBase,
+	db *db.DB,
+	gateway *gateway.Gateway,
+	logStorer *logstorer.LogStorer,
+) *Replayer {
+	return &Replayer{
+		app:       app,
+		db:        db,
+		gateway:   gateway,
+		logStorer: logStorer,
+	}
+}
+
+// Replay sends the stored requests selected by opts again through the
+// gateway, one after the other and oldest first.
+//
+// Every replay is stored as a new request linked to the original one and
+// sharing the same batch ID, the replays are written before Replay returns.
+// Requests whose stored URL or body had values masked are not replayed
+// unless opts.SendRedacted is set.
+// A failure replaying one request is reported in its ReplayItem and does not
+// stop the batch.
+func (rp *Replayer) Replay(ctx context.Context, opts Options) (Result, error) {
+	storedRequests, err := rp.findStoredRequests(opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		BatchID: randutil.GenerateIDForPocketBase(),
+		Replays: make([]ReplayItem, 0, len(storedRequests)),
+	}
+
+	defer rp.logStorer.Flush()
+
+	for _, storedRequest := range storedRequests {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		header, skippedHeaders := applyHeaderOverrides(storedRequest.ReqHeaders, opts.Headers)
+		item := ReplayItem{OriginalID: storedRequest.ID, SkippedHeaders: skippedHeaders}
+
+		if storedRequest.ReqBodyError != "" {
+			item.Error = "the stored request body can't be read: " + storedRequest.ReqBodyError
+			result.Replays = append(result.Replays, item)
+			continue
+		}
+
+		item.Redacted = redactedValues(storedRequest)
+		if len(item.Redacted) > 0 && !opts.SendRedacted {
+			item.Error = "the stored URL or body has redacted values, send_redacted replays it with the mask in their place"
+			result.Replays = append(result.Replays, item)
+			continue
+		}
+
+		replayResult, err := rp.gateway.Replay(ctx, gateway.ReplayRequest{
+			RouteID:           storedRequest.RouteID,
+			RequestID:         storedRequest.ID,
+			BatchID:           result.BatchID,
+			Method:            storedRequest.ReqMethod,
+			RequestGatewayURL: storedRequest.ReqGatewayURL,
+			TargetURL:         opts.TargetURL,
+			Header:            header,
+			Body:              replayBody(storedRequest, header),
+		})
+		if err != nil {
+			rp.app.Logger().Error(
+				"failed to replay request",
+				"fn", "Replay",
+				"request_id", storedRequest.ID,
+				"batch_id", result.BatchID,
+				"error", err,
+			)
+			item.Error = err.Error()
+		} else {
+			item.RequestID = replayResult.RequestID
+			item.Status = replayResult.StatusCode
+			item.DurationUs = replayResult.Duration.Microseconds()
+		}
+
+		result.Replays = append(result.Replays, item)
+	}
+
+	return result, nil
+}
+
+func (rp *Replayer) findStoredRequests(opts Options) ([]db.StoredRequest, error) {
+	if (opts.RequestID == "") == (opts.Filter == "") {
+		return nil, errors.New("either a request id or a filter is required")
+	}
+
+	if opts.RequestID != "" {
+		storedRequest, err := rp.db.GetStoredRequestByID(opts.RequestID)
+		if err != nil {
+			return nil, err
+		}
+		return []db.StoredRequest{storedRequest}, nil
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		return nil, errors.New("limit exceeds the maximum of 1000 requests")
+	}
+
+	return rp.db.FindStoredRequests(opts.Filter, limit)
+}
+
+// applyHeaderOverrides returns a copy of the stored headers with the
+// overrides applied. An override with an empty value removes the header.
+//
+// Headers stored redacted are not sent, since their values are lost, unless
+// they are overridden. Their names are returned sorted.
+func applyHeaderOverrides(
+	storedHeaders map[string][]string,
+	overrides map[string]string,
+) (http.Header, []string) {
+	header := http.Header(storedHeaders).Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	skipped := []string{}
+	for name, values := range header {
+		if !slices.ContainsFunc(values, isRedacted) {
+			continue
+		}
+		header.Del(name)
+		if !hasOverride(overrides, name) {
+			skipped = append(skipped, http.CanonicalHeaderKey(name))
+		}
+	}
+
+	for name, value := range overrides {
+		if value == "" {
+			header.Del(name)
+			continue
+		}
+		header.Set(name, value)
+	}
+
+	slices.Sort(skipped)
+	return header, skipped
+}
+
+// redactedValues returns what was masked in the stored URL and body of a
+// request, which can't be sent again as they were received.
+func redactedValues(storedRequest db.StoredRequest) redact.Marker {
+	redacted := redact.Marker{}
+	for _, field := range []string{"req_gateway_url", "req_body"} {
+		redacted.Add(field, storedRequest.Redactions[field])
+	}
+	return redacted
+}
+
+// isRedacted reports whether a stored value was masked, fully or in part, by
+// the redaction rules.
+func isRedacted(value string) bool {
+	return strings.Contains(value, redact.Mask)
+}
+
+// hasOverride reports whether the overrides include the header name, which
+// is matched without case.
+func hasOverride(overrides map[string]string, name string) bool {
+	for overrideName := range overrides {
+		if strings.EqualFold(overrideName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// replayBody returns the body to send when replaying a stored request.
+//
+// Bodies stored decoded are encoded again with the Content-Encoding of the
+// replayed headers, so overriding that header with an empty value sends them
+// decoded. If they can't be encoded the header is removed instead.
+func replayBody(storedRequest db.StoredRequest, header http.Header) []byte {
+	body := storedRequest.ReqBodyBytes()
+	if !storedRequest.ReqBodyMeta.Decoded {
+		return body
+	}
+
+	encoded, err := bodycodec.Encode(body, header.Get("Content-Encoding"))
+	if err != nil {
+		header.Del("Content-Encoding")
+		return body
+	}
+	return encoded
+}
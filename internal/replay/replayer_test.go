@@ -0,0 +1,179 @@
+package replay
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uforg/ufogateway/internal/bodycodec"
+	"github.com/uforg/ufogateway/internal/db"
+	"github.com/uforg/ufogateway/internal/redact"
+)
+
+func TestApplyHeaderOverrides(t *testing.T) {
+	tests := []struct {
+		name        string
+		stored      map[string][]string
+		overrides   map[string]string
+		want        http.Header
+		wantSkipped []string
+	}{
+		{
+			name:        "no overrides",
+			stored:      map[string][]string{"Accept": {"text/html", "application/json"}},
+			overrides:   nil,
+			want:        http.Header{"Accept": {"text/html", "application/json"}},
+			wantSkipped: []string{},
+		},
+		{
+			name:        "replace a header",
+			stored:      map[string][]string{"Authorization": {"Bearer old"}},
+			overrides:   map[string]string{"authorization": "Bearer new"},
+			want:        http.Header{"Authorization": {"Bearer new"}},
+			wantSkipped: []string{},
+		},
+		{
+			name:        "add a header",
+			stored:      map[string][]string{"Accept": {"*/*"}},
+			overrides:   map[string]string{"X-Replay": "1"},
+			want:        http.Header{"Accept": {"*/*"}, "X-Replay": {"1"}},
+			wantSkipped: []string{},
+		},
+		{
+			name:        "remove a header",
+			stored:      map[string][]string{"Accept": {"*/*"}, "Cookie": {"a=b"}},
+			overrides:   map[string]string{"Cookie": ""},
+			want:        http.Header{"Accept": {"*/*"}},
+			wantSkipped: []string{},
+		},
+		{
+			name:        "nil stored headers",
+			stored:      nil,
+			overrides:   map[string]string{"X-Replay": "1"},
+			want:        http.Header{"X-Replay": {"1"}},
+			wantSkipped: []string{},
+		},
+		{
+			name: "skip redacted headers",
+			stored: map[string][]string{
+				"Accept":        {"*/*"},
+				"Authorization": {"[REDACTED]"},
+				"X-Card":        {"card [REDACTED]"},
+			},
+			overrides:   nil,
+			want:        http.Header{"Accept": {"*/*"}},
+			wantSkipped: []string{"Authorization", "X-Card"},
+		},
+		{
+			name:        "override a redacted header",
+			stored:      map[string][]string{"Authorization": {"[REDACTED]"}, "Cookie": {"[REDACTED]"}},
+			overrides:   map[string]string{"authorization": "Bearer test", "Cookie": ""},
+			want:        http.Header{"Authorization": {"Bearer test"}},
+			wantSkipped: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped := applyHeaderOverrides(tt.stored, tt.overrides)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantSkipped, skipped)
+		})
+	}
+
+	t.Run("does not modify the stored headers", func(t *testing.T) {
+		stored := map[string][]string{"Cookie": {"a=b"}}
+		applyHeaderOverrides(stored, map[string]string{"Cookie": ""})
+		assert.Equal(t, map[string][]string{"Cookie": {"a=b"}}, stored)
+	})
+}
+
+func TestRedactedValues(t *testing.T) {
+	storedRequest := db.StoredRequest{
+		Redactions: redact.Marker{
+			"req_headers":     {"Authorization"},
+			"req_gateway_url": {"access_token"},
+			"req_body":        {"$.password", "pattern:card_number"},
+			"res_body":        {"$.token"},
+		},
+	}
+
+	assert.Equal(t, redact.Marker{
+		"req_gateway_url": {"access_token"},
+		"req_body":        {"$.password", "pattern:card_number"},
+	}, redactedValues(storedRequest))
+	assert.Empty(t, redactedValues(db.StoredRequest{Redactions: redact.Marker{"req_headers": {"Cookie"}}}))
+}
+
+func TestReplayBody(t *testing.T) {
+	gzipBody, err := bodycodec.Encode([]byte(`{"a":1}`), "gzip")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		stored     db.StoredRequest
+		header     http.Header
+		want       []byte
+		wantHeader http.Header
+	}{
+		{
+			name:       "plain body",
+			stored:     db.StoredRequest{ReqBody: `{"a":1}`},
+			header:     http.Header{},
+			want:       []byte(`{"a":1}`),
+			wantHeader: http.Header{},
+		},
+		{
+			name:       "base64 body",
+			stored:     db.StoredRequest{ReqBody: "//4=", ReqBodyMeta: bodycodec.Meta{Base64: true}},
+			header:     http.Header{},
+			want:       []byte{0xff, 0xfe},
+			wantHeader: http.Header{},
+		},
+		{
+			name: "decoded body is encoded again",
+			stored: db.StoredRequest{
+				ReqBody:     `{"a":1}`,
+				ReqBodyMeta: bodycodec.Meta{Encoding: "gzip", Decoded: true},
+			},
+			header:     http.Header{"Content-Encoding": {"gzip"}},
+			want:       gzipBody,
+			wantHeader: http.Header{"Content-Encoding": {"gzip"}},
+		},
+		{
+			name: "decoded body without content encoding",
+			stored: db.StoredRequest{
+				ReqBody:     `{"a":1}`,
+				ReqBodyMeta: bodycodec.Meta{Encoding: "gzip", Decoded: true},
+			},
+			header:     http.Header{},
+			want:       []byte(`{"a":1}`),
+			wantHeader: http.Header{},
+		},
+		{
+			name: "decoded body with an unsupported content encoding",
+			stored: db.StoredRequest{
+				ReqBody:     `{"a":1}`,
+				ReqBodyMeta: bodycodec.Meta{Encoding: "gzip", Decoded: true},
+			},
+			header:     http.Header{"Content-Encoding": {"compress"}},
+			want:       []byte(`{"a":1}`),
+			wantHeader: http.Header{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := replayBody(tt.stored, tt.header)
+			if tt.stored.ReqBodyMeta.Decoded && tt.header.Get("Content-Encoding") != "" {
+				decoded, err := bodycodec.Decode(got, tt.header.Get("Content-Encoding"), 0)
+				require.NoError(t, err)
+				assert.Equal(t, []byte(tt.stored.ReqBody), decoded)
+			} else {
+				assert.Equal(t, tt.want, got)
+			}
+			assert.Equal(t, tt.wantHeader, tt.header)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package routeprovider
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/uforg/ufogateway/internal/gateway"
+	"github.com/uforg/ufogateway/internal/util/strutil"
+)
+
+// ipListFields are the fields of the routes and projects that hold IP
+// access lists.
+var ipListFields = []string{"ip_allow_list", "ip_deny_list"}
+
+// BindHooks rejects routes and projects saved with invalid IP access list
+// entries.
+func (rp *RouteProvider) BindHooks() {
+	rp.app.OnRecordValidate("routes", "projects").BindFunc(func(e *core.RecordEvent) error {
+		if err := validateIPLists(e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+}
+
+// validateIPLists returns a validation error for every IP access list of the
+// record with entries that are neither a CIDR range nor an IP address.
+func validateIPLists(record *core.Record) error {
+	errs := validation.Errors{}
+	for _, field := range ipListFields {
+		invalid := gateway.InvalidIPAccessEntries(strutil.SplitList(record.GetString(field)))
+		if len(invalid) > 0 {
+			errs[field] = validation.NewError(
+				"validation_invalid_ip_list",
+				fmt.Sprintf("Invalid CIDR ranges or IP addresses: %s.", strings.Join(invalid, ", ")),
+			)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ipList parses an IP access list of a route or project. The invalid
+// entries, which can only come from records saved before they were
+// validated, are logged once per list value and left out of the list.
+func (rp *RouteProvider) ipList(collection string, id string, field string, value string) []netip.Prefix {
+	prefixes, invalid := gateway.ParseIPAccessList(strutil.SplitList(value))
+	if len(invalid) == 0 {
+		return prefixes
+	}
+
+	key := strings.Join([]string{collection, id, field, value}, "\x00")
+	rp.invalidIPListsMu.Lock()
+	logged := rp.invalidIPLists[key]
+	rp.invalidIPLists[key] = true
+	rp.invalidIPListsMu.Unlock()
+
+	if !logged {
+		rp.app.Logger().Warn(
+			"skipping invalid IP access list entries",
+			"fn", "ipList",
+			"collection", collection,
+			"id", id,
+			"field", field,
+			"entries", invalid,
+		)
+	}
+	return prefixes
+}
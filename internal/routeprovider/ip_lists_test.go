@@ -0,0 +1,48 @@
+package routeprovider
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateIPLists(t *testing.T) {
+	collection := core.NewBaseCollection("routes")
+	collection.Fields.Add(&core.TextField{Name: "ip_allow_list"}, &core.TextField{Name: "ip_deny_list"})
+
+	tests := []struct {
+		name       string
+		allowList  string
+		denyList   string
+		wantFields []string
+	}{
+		{name: "empty lists"},
+		{name: "valid lists", allowList: "10.0.0.0/8, 192.168.1.1", denyList: "2001:db8::/32"},
+		{name: "invalid allowlist", allowList: "10.0.0.0/8 vpn", wantFields: []string{"ip_allow_list"}},
+		{name: "invalid lists", allowList: "10.0.0.0/33", denyList: "1.2.3", wantFields: []string{"ip_allow_list", "ip_deny_list"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := core.NewRecord(collection)
+			record.Set("ip_allow_list", tt.allowList)
+			record.Set("ip_deny_list", tt.denyList)
+
+			err := validateIPLists(record)
+			if len(tt.wantFields) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var errs validation.Errors
+			require.ErrorAs(t, err, &errs)
+			for _, field := range tt.wantFields {
+				assert.Contains(t, errs, field)
+			}
+			assert.Len(t, errs, len(tt.wantFields))
+		})
+	}
+}
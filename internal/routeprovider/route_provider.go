@@ -1,14 +1,32 @@
 package routeprovider
 
 import (
+	"sync"
+	"time"
+
 	"github.com/pocketbase/pocketbase"
 	"github.com/uforg/ufogateway/internal/db"
 	"github.com/uforg/ufogateway/internal/gateway"
+	"github.com/uforg/ufogateway/internal/openapi"
+	"github.com/uforg/ufogateway/internal/util/strutil"
 )
 
 type RouteProvider struct {
 	app *pocketbase.PocketBase
 	db  *db.DB
+
+	validatorsMu sync.Mutex
+	validators   map[string]cachedValidator // by OpenAPI spec ID
+
+	invalidIPListsMu sync.Mutex
+	invalidIPLists   map[string]bool // IP access lists whose invalid entries were logged
+}
+
+// cachedValidator is the contract validator built from a version of an
+// OpenAPI spec, nil if the spec document is invalid.
+type cachedValidator struct {
+	updated   time.Time
+	validator *openapi.Validator
 }
 
 func NewRouteProvider(
@@ -16,8 +34,10 @@ func NewRouteProvider(
 	db *db.DB,
 ) *RouteProvider {
 	return &RouteProvider{
-		app: app,
-		db:  db,
+		app:            app,
+		db:             db,
+		validators:     map[string]cachedValidator{},
+		invalidIPLists: map[string]bool{},
 	}
 }
 
@@ -29,16 +49,101 @@ func (rp *RouteProvider) Routes() ([]gateway.Route, error) {
 
 	routes := []gateway.Route{}
 	for _, route := range dbRoutes {
+		project, err := rp.db.GetProjectByIDCached(route.Project)
+		if err != nil {
+			return nil, err
+		}
+
+		var contractValidator gateway.ContractValidator
+		if route.OpenAPISpec != "" && route.ContractMode != "" && route.ContractMode != gateway.ContractModeOff {
+			if validator := rp.contractValidator(route.OpenAPISpec); validator != nil {
+				contractValidator = validator
+			}
+		}
+
 		routes = append(routes, gateway.Route{
-			ID:                route.ID,
-			Endpoint:          route.Endpoint,
-			OriginURL:         route.OriginURL,
-			TLSClientCert:     route.TLSClientCert,
-			TLSClientKey:      route.TLSClientKey,
-			TLSCaCert:         route.TLSCaCert,
-			TLSSkipCertVerify: route.TLSSkipCertVerify,
+			ID:                 route.ID,
+			Name:               route.Name,
+			Type:               route.Type,
+			Endpoint:           route.Endpoint,
+			OriginURL:          route.OriginURL,
+			TLSClientCert:      route.TLSClientCert,
+			TLSClientKey:       route.TLSClientKey,
+			TLSCaCert:          route.TLSCaCert,
+			TLSSkipCertVerify:  route.TLSSkipCertVerify,
+			IPAllowList:        rp.ipList("routes", route.ID, "ip_allow_list", route.IPAllowList),
+			IPDenyList:         rp.ipList("routes", route.ID, "ip_deny_list", route.IPDenyList),
+			ProjectIPAllowList: rp.ipList("projects", project.ID, "ip_allow_list", project.IPAllowList),
+			ProjectIPDenyList:  rp.ipList("projects", project.ID, "ip_deny_list", project.IPDenyList),
+			IPBlockStatus:      route.IPBlockStatus,
+			IPBlockMessage:     route.IPBlockMessage,
+			CacheEnabled:       route.CacheEnabled,
+			CacheVaryHeaders:   strutil.SplitList(route.CacheVaryHeaders),
+			CacheTTL:           time.Duration(route.CacheTTLSeconds) * time.Second,
+			CoalesceRequests:   route.CoalesceRequests,
+			MockStatus:         route.MockStatus,
+			MockHeaders:        route.MockHeaders,
+			MockBody:           route.MockBody,
+			MockLatency:        time.Duration(route.MockLatencyMs) * time.Millisecond,
+			MockPathPattern:    route.MockPathPattern,
+			MirrorURL:          route.MirrorURL,
+			MirrorPercent:      route.MirrorPercent,
+			ContractMode:       route.ContractMode,
+			ContractValidator:  contractValidator,
+			ContractResponses:  route.ContractValidateRes,
+			MaxBodyBytes:       int64(route.ReqBodyMaxBytes),
+			MaxHeaderBytes:     route.ReqHeadersMaxBytes,
+			MaxHeaderCount:     route.ReqHeadersMaxCount,
+			CompressResponses:  route.CompressResponses,
+			CompressMinBytes:   route.CompressMinBytes,
+			CompressTypes:      strutil.SplitList(route.CompressContentTypes),
+			CompressEncodings:  strutil.SplitList(route.CompressEncodings),
+			ErrorTemplate:      project.ErrorTemplate,
+			ErrorContentType:   project.ErrorContentType,
+			ServerTiming:       route.ServerTiming,
 		})
 	}
 
 	return routes, nil
 }
+
+// contractValidator returns the validator for an OpenAPI spec, it is only
+// rebuilt when the spec changes. It returns nil if the spec can't be used,
+// which disables the contract validation of the routes using it.
+func (rp *RouteProvider) contractValidator(specID string) *openapi.Validator {
+	spec, err := rp.db.GetOpenAPISpecByIDCached(specID)
+	if err != nil {
+		rp.app.Logger().Error(
+			"failed to get openapi spec by id",
+			"id", specID,
+			"fn", "contractValidator",
+			"error", err,
+		)
+		return nil
+	}
+
+	rp.validatorsMu.Lock()
+	defer rp.validatorsMu.Unlock()
+
+	cached, found := rp.validators[specID]
+	if found && cached.updated.Equal(spec.Updated) {
+		return cached.validator
+	}
+
+	cached = cachedValidator{updated: spec.Updated}
+	doc, err := openapi.LoadDocument([]byte(spec.Document))
+	if err == nil {
+		cached.validator, err = openapi.NewValidator(doc)
+	}
+	if err != nil {
+		rp.app.Logger().Error(
+			"invalid openapi spec, contract validation is disabled for its routes",
+			"id", specID,
+			"fn", "contractValidator",
+			"error", err,
+		)
+	}
+
+	rp.validators[specID] = cached
+	return cached.validator
+}
@@ -0,0 +1,164 @@
+package routestats
+
+import (
+	"fmt"
+	"time"
+)
+
+// Granularities of the buckets.
+const (
+	GranularityMinute = "minute"
+	GranularityHour   = "hour"
+	GranularityDay    = "day"
+)
+
+// ParseGranularity returns the granularity named s, the minute one when s is
+// empty.
+func ParseGranularity(s string) (string, error) {
+	switch s {
+	case "":
+		return GranularityMinute, nil
+	case GranularityMinute, GranularityHour, GranularityDay:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown granularity %q, expected minute, hour or day", s)
+	}
+}
+
+// Duration returns the time spanned by a bucket of the granularity.
+func Duration(granularity string) time.Duration {
+	switch granularity {
+	case GranularityHour:
+		return time.Hour
+	case GranularityDay:
+		return 24 * time.Hour
+	default:
+		return time.Minute
+	}
+}
+
+// Truncate returns the start of the bucket of the granularity that holds t.
+// Buckets are aligned to UTC.
+func Truncate(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	if granularity == GranularityDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(Duration(granularity))
+}
+
+// Bucket is the traffic of a route during a bucket of time.
+type Bucket struct {
+	Requests   int64
+	Status1xx  int64
+	Status2xx  int64
+	Status3xx  int64
+	Status4xx  int64
+	Status5xx  int64
+	BytesIn    int64
+	BytesOut   int64
+	LatencySum time.Duration
+	LatencyMax time.Duration
+	Latency    Histogram
+}
+
+// NewBucket returns an empty bucket.
+func NewBucket() Bucket {
+	return Bucket{Latency: Histogram{}}
+}
+
+// Add counts a request answered with statusCode after duration, which
+// received bytesIn and sent bytesOut body bytes.
+func (b *Bucket) Add(statusCode int, duration time.Duration, bytesIn int64, bytesOut int64) {
+	if b.Latency == nil {
+		b.Latency = Histogram{}
+	}
+
+	b.Requests++
+	switch statusCode / 100 {
+	case 1:
+		b.Status1xx++
+	case 2:
+		b.Status2xx++
+	case 3:
+		b.Status3xx++
+	case 4:
+		b.Status4xx++
+	case 5:
+		b.Status5xx++
+	}
+	b.BytesIn += bytesIn
+	b.BytesOut += bytesOut
+	b.LatencySum += duration
+	b.LatencyMax = max(b.LatencyMax, duration)
+	b.Latency.Add(duration)
+}
+
+// Merge adds the traffic of other to b.
+func (b *Bucket) Merge(other Bucket) {
+	if b.Latency == nil {
+		b.Latency = Histogram{}
+	}
+
+	b.Requests += other.Requests
+	b.Status1xx += other.Status1xx
+	b.Status2xx += other.Status2xx
+	b.Status3xx += other.Status3xx
+	b.Status4xx += other.Status4xx
+	b.Status5xx += other.Status5xx
+	b.BytesIn += other.BytesIn
+	b.BytesOut += other.BytesOut
+	b.LatencySum += other.LatencySum
+	b.LatencyMax = max(b.LatencyMax, other.LatencyMax)
+	b.Latency.Merge(other.Latency)
+}
+
+// Percentile returns the estimated latency percentile p, from 0 to 100. It
+// never exceeds the maximum latency.
+func (b Bucket) Percentile(p float64) time.Duration {
+	return min(b.Latency.Quantile(p/100), b.LatencyMax)
+}
+
+// Summary is the JSON representation of a bucket with the latencies in
+// microseconds.
+type Summary struct {
+	Start        time.Time `json:"start"`
+	Requests     int64     `json:"requests"`
+	Status1xx    int64     `json:"status_1xx"`
+	Status2xx    int64     `json:"status_2xx"`
+	Status3xx    int64     `json:"status_3xx"`
+	Status4xx    int64     `json:"status_4xx"`
+	Status5xx    int64     `json:"status_5xx"`
+	BytesIn      int64     `json:"bytes_in"`
+	BytesOut     int64     `json:"bytes_out"`
+	LatencyAvgUs int64     `json:"latency_avg_us"`
+	LatencyP50Us int64     `json:"latency_p50_us"`
+	LatencyP90Us int64     `json:"latency_p90_us"`
+	LatencyP95Us int64     `json:"latency_p95_us"`
+	LatencyP99Us int64     `json:"latency_p99_us"`
+	LatencyMaxUs int64     `json:"latency_max_us"`
+}
+
+// Summary returns the summary of the bucket starting at start.
+func (b Bucket) Summary(start time.Time) Summary {
+	summary := Summary{
+		Start:        start,
+		Requests:     b.Requests,
+		Status1xx:    b.Status1xx,
+		Status2xx:    b.Status2xx,
+		Status3xx:    b.Status3xx,
+		Status4xx:    b.Status4xx,
+		Status5xx:    b.Status5xx,
+		BytesIn:      b.BytesIn,
+		BytesOut:     b.BytesOut,
+		LatencyP50Us: b.Percentile(50).Microseconds(),
+		LatencyP90Us: b.Percentile(90).Microseconds(),
+		LatencyP95Us: b.Percentile(95).Microseconds(),
+		LatencyP99Us: b.Percentile(99).Microseconds(),
+		LatencyMaxUs: b.LatencyMax.Microseconds(),
+	}
+	if b.Requests > 0 {
+		summary.LatencyAvgUs = b.LatencySum.Microseconds() / b.Requests
+	}
+	return summary
+}
@@ -0,0 +1,92 @@
+package routestats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGranularity(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "", want: GranularityMinute},
+		{input: "minute", want: GranularityMinute},
+		{input: "hour", want: GranularityHour},
+		{input: "day", want: GranularityDay},
+		{input: "week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseGranularity(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	moment := time.Date(2025, 2, 3, 14, 35, 42, 123, time.FixedZone("UTC+2", 2*60*60))
+
+	tests := []struct {
+		granularity string
+		want        time.Time
+	}{
+		{granularity: GranularityMinute, want: time.Date(2025, 2, 3, 12, 35, 0, 0, time.UTC)},
+		{granularity: GranularityHour, want: time.Date(2025, 2, 3, 12, 0, 0, 0, time.UTC)},
+		{granularity: GranularityDay, want: time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.granularity, func(t *testing.T) {
+			assert.Equal(t, tt.want, Truncate(moment, tt.granularity))
+		})
+	}
+}
+
+func TestBucket(t *testing.T) {
+	a := NewBucket()
+	a.Add(200, 10*time.Millisecond, 100, 1000)
+	a.Add(201, 30*time.Millisecond, 0, 10)
+	a.Add(404, 20*time.Millisecond, 5, 50)
+
+	b := Bucket{}
+	b.Add(503, 2*time.Second, 0, 0)
+	b.Add(0, time.Millisecond, 0, 0)
+
+	a.Merge(b)
+
+	assert.Equal(t, int64(5), a.Requests)
+	assert.Equal(t, int64(0), a.Status1xx)
+	assert.Equal(t, int64(2), a.Status2xx)
+	assert.Equal(t, int64(0), a.Status3xx)
+	assert.Equal(t, int64(1), a.Status4xx)
+	assert.Equal(t, int64(1), a.Status5xx)
+	assert.Equal(t, int64(105), a.BytesIn)
+	assert.Equal(t, int64(1060), a.BytesOut)
+	assert.Equal(t, 2*time.Second, a.LatencyMax)
+	assert.Equal(t, int64(5), a.Latency.Count())
+
+	start := time.Date(2025, 2, 3, 12, 35, 0, 0, time.UTC)
+	summary := a.Summary(start)
+	assert.Equal(t, start, summary.Start)
+	assert.Equal(t, int64(2061000/5), summary.LatencyAvgUs)
+	assert.InEpsilon(t, 20000, summary.LatencyP50Us, 0.05)
+	assert.Equal(t, int64(2000000), summary.LatencyP99Us, "percentiles never exceed the maximum")
+	assert.Equal(t, int64(2000000), summary.LatencyMaxUs)
+
+	t.Run("empty bucket", func(t *testing.T) {
+		summary := NewBucket().Summary(start)
+		assert.Equal(t, int64(0), summary.LatencyAvgUs)
+		assert.Equal(t, int64(0), summary.LatencyP50Us)
+	})
+}
@@ -0,0 +1,87 @@
+package routestats
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// histogramMin is the upper bound of the first histogram bin, every
+	// latency up to it falls in bin 0.
+	histogramMin = 50 * time.Microsecond
+	// histogramRatio is the ratio between the bounds of a bin, so estimated
+	// percentiles are within 5% of the real value.
+	histogramRatio = 1.1
+)
+
+// Histogram counts latencies in bins with exponentially growing bounds. Only
+// the bins with a count are kept, so a histogram is small when encoded as
+// JSON and two histograms are merged by adding their counts.
+type Histogram map[int]int64
+
+// histogramBin returns the bin of a latency.
+func histogramBin(d time.Duration) int {
+	if d <= histogramMin {
+		return 0
+	}
+	return int(math.Ceil(math.Log(float64(d)/float64(histogramMin)) / math.Log(histogramRatio)))
+}
+
+// histogramBinValue returns the value reported for the latencies of a bin,
+// the geometric mean of its bounds.
+func histogramBinValue(bin int) time.Duration {
+	if bin <= 0 {
+		return histogramMin
+	}
+	upper := float64(histogramMin) * math.Pow(histogramRatio, float64(bin))
+	return time.Duration(upper / math.Sqrt(histogramRatio))
+}
+
+// Add counts one latency.
+func (h Histogram) Add(d time.Duration) {
+	h[histogramBin(d)]++
+}
+
+// Merge adds the counts of other to h.
+func (h Histogram) Merge(other Histogram) {
+	for bin, count := range other {
+		h[bin] += count
+	}
+}
+
+// Count returns the number of latencies counted.
+func (h Histogram) Count() int64 {
+	var count int64
+	for _, c := range h {
+		count += c
+	}
+	return count
+}
+
+// Quantile returns the estimated latency below which the q fraction of the
+// counted latencies fall, q is from 0 to 1. It is 0 for an empty histogram.
+func (h Histogram) Quantile(q float64) time.Duration {
+	count := h.Count()
+	if count == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(q * float64(count)))
+	rank = max(rank, 1)
+
+	bins := make([]int, 0, len(h))
+	for bin := range h {
+		bins = append(bins, bin)
+	}
+	slices.Sort(bins)
+
+	var seen int64
+	for _, bin := range bins {
+		seen += h[bin]
+		if seen >= rank {
+			return histogramBinValue(bin)
+		}
+	}
+	return histogramBinValue(bins[len(bins)-1])
+}
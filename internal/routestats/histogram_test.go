@@ -0,0 +1,73 @@
+package routestats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramBin(t *testing.T) {
+	latencies := []time.Duration{
+		0,
+		histogramMin,
+		histogramMin + 1,
+		time.Millisecond,
+		37 * time.Millisecond,
+		time.Second,
+		2 * time.Minute,
+	}
+
+	for _, latency := range latencies {
+		bin := histogramBin(latency)
+		value := histogramBinValue(bin)
+
+		if latency <= histogramMin {
+			assert.Equal(t, 0, bin, latency)
+			continue
+		}
+		assert.InEpsilon(t, float64(latency), float64(value), 0.05, latency)
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	t.Run("empty histogram", func(t *testing.T) {
+		assert.Equal(t, time.Duration(0), Histogram{}.Quantile(0.5))
+	})
+
+	t.Run("estimates the quantiles within 5%", func(t *testing.T) {
+		h := Histogram{}
+		for i := 1; i <= 1000; i++ {
+			h.Add(time.Duration(i) * time.Millisecond)
+		}
+
+		tests := []struct {
+			q    float64
+			want time.Duration
+		}{
+			{q: 0, want: time.Millisecond},
+			{q: 0.5, want: 500 * time.Millisecond},
+			{q: 0.9, want: 900 * time.Millisecond},
+			{q: 0.99, want: 990 * time.Millisecond},
+			{q: 1, want: 1000 * time.Millisecond},
+		}
+		for _, tt := range tests {
+			assert.InEpsilon(t, float64(tt.want), float64(h.Quantile(tt.q)), 0.05, tt.q)
+		}
+	})
+
+	t.Run("merged histograms give the quantiles of all latencies", func(t *testing.T) {
+		fast, slow := Histogram{}, Histogram{}
+		for range 90 {
+			fast.Add(10 * time.Millisecond)
+		}
+		for range 10 {
+			slow.Add(time.Second)
+		}
+
+		fast.Merge(slow)
+		assert.Equal(t, int64(100), fast.Count())
+		assert.InEpsilon(t, float64(10*time.Millisecond), float64(fast.Quantile(0.9)), 0.05)
+		assert.InEpsilon(t, float64(time.Second), float64(fast.Quantile(0.95)), 0.05)
+	})
+}
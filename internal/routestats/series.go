@@ -0,0 +1,51 @@
+package routestats
+
+import "time"
+
+// Point is a bucket of a route starting at Start.
+type Point struct {
+	Start  time.Time
+	Bucket Bucket
+}
+
+// SeriesLength returns the number of buckets of the granularity from the
+// bucket holding from to the one holding to, excluding to.
+func SeriesLength(granularity string, from time.Time, to time.Time) int {
+	from = Truncate(from, granularity)
+	if !to.After(from) {
+		return 0
+	}
+	return int((to.Sub(from) + Duration(granularity) - 1) / Duration(granularity))
+}
+
+// Series returns one summary per bucket of the granularity from the bucket
+// holding from to the one holding to, excluding to. Points with the same
+// start, of different routes, are merged and buckets without points are
+// empty.
+func Series(granularity string, from time.Time, to time.Time, points []Point) []Summary {
+	merged := map[time.Time]*Bucket{}
+	for _, point := range points {
+		start := Truncate(point.Start, granularity)
+		bucket, ok := merged[start]
+		if !ok {
+			newBucket := NewBucket()
+			bucket = &newBucket
+			merged[start] = bucket
+		}
+		bucket.Merge(point.Bucket)
+	}
+
+	length := SeriesLength(granularity, from, to)
+	series := make([]Summary, 0, length)
+	start := Truncate(from, granularity)
+	for range length {
+		bucket, ok := merged[start]
+		if !ok {
+			empty := NewBucket()
+			bucket = &empty
+		}
+		series = append(series, bucket.Summary(start))
+		start = start.Add(Duration(granularity))
+	}
+	return series
+}
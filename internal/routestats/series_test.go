@@ -0,0 +1,63 @@
+package routestats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesLength(t *testing.T) {
+	from := time.Date(2025, 2, 3, 12, 0, 30, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		granularity string
+		to          time.Time
+		want        int
+	}{
+		{name: "empty range", granularity: GranularityMinute, to: from.Add(-time.Minute), want: 0},
+		{name: "partial bucket", granularity: GranularityMinute, to: from.Add(time.Second), want: 1},
+		{name: "an hour of minutes", granularity: GranularityMinute, to: from.Add(time.Hour), want: 61},
+		{name: "a day of hours", granularity: GranularityHour, to: from.Add(24 * time.Hour), want: 25},
+		{name: "a week of days", granularity: GranularityDay, to: from.Add(7 * 24 * time.Hour), want: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SeriesLength(tt.granularity, from, tt.to))
+		})
+	}
+}
+
+func TestSeries(t *testing.T) {
+	from := time.Date(2025, 2, 3, 12, 0, 0, 0, time.UTC)
+	to := from.Add(3 * time.Minute)
+
+	routeA := NewBucket()
+	routeA.Add(200, 10*time.Millisecond, 1, 2)
+	routeB := NewBucket()
+	routeB.Add(500, 20*time.Millisecond, 3, 4)
+
+	series := Series(GranularityMinute, from, to, []Point{
+		{Start: from, Bucket: routeA},
+		{Start: from, Bucket: routeB},
+		{Start: from.Add(2 * time.Minute), Bucket: routeA},
+	})
+
+	require.Len(t, series, 3)
+
+	assert.Equal(t, from, series[0].Start)
+	assert.Equal(t, int64(2), series[0].Requests)
+	assert.Equal(t, int64(1), series[0].Status2xx)
+	assert.Equal(t, int64(1), series[0].Status5xx)
+	assert.Equal(t, int64(4), series[0].BytesIn)
+	assert.Equal(t, int64(6), series[0].BytesOut)
+	assert.Equal(t, int64(20000), series[0].LatencyMaxUs)
+
+	assert.Equal(t, from.Add(time.Minute), series[1].Start)
+	assert.Equal(t, int64(0), series[1].Requests)
+
+	assert.Equal(t, int64(1), series[2].Requests)
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Protocols of the OTLP exporter.
+const (
+	ProtocolGRPC = "grpc" // OTLP over gRPC, usually on port 4317
+	ProtocolHTTP = "http" // OTLP over HTTP with protobuf payloads, usually on port 4318
+)
+
+const serviceName = "ufogateway"
+
+// Config configures the export of the gateway spans to an OTLP collector.
+type Config struct {
+	Endpoint    string  // URL of the collector, e.g. http://localhost:4317, tracing is disabled when empty
+	Protocol    string  // Protocol of the collector, defaults to ProtocolGRPC
+	SampleRatio float64 // Ratio of the new traces that are sampled, from 0 to 1
+}
+
+// Enabled reports whether the config enables tracing.
+func (c Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// NewTracerProvider returns a tracer provider that exports the spans to the
+// collector of the config in batches. Requests that continue a trace follow
+// the sampling decision of their parent.
+func NewTracerProvider(ctx context.Context, config Config) (*sdktrace.TracerProvider, error) {
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid sample ratio %v, expected a value from 0 to 1", config.SampleRatio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Protocol {
+	case "", ProtocolGRPC:
+		exporter, err = otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(config.Endpoint))
+	case ProtocolHTTP:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(config.Endpoint))
+	default:
+		return nil, fmt.Errorf("unknown tracing protocol %q, expected grpc or http", config.Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", serviceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	), nil
+}
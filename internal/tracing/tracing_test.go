@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "grpc", config: Config{Endpoint: "http://localhost:4317", SampleRatio: 1}},
+		{name: "default protocol", config: Config{Endpoint: "http://localhost:4317", SampleRatio: 0.5}},
+		{name: "http", config: Config{Endpoint: "http://localhost:4318", Protocol: ProtocolHTTP, SampleRatio: 1}},
+		{name: "unknown protocol", config: Config{Endpoint: "http://localhost:4317", Protocol: "udp", SampleRatio: 1}, wantErr: true},
+		{name: "negative sample ratio", config: Config{Endpoint: "http://localhost:4317", SampleRatio: -0.1}, wantErr: true},
+		{name: "sample ratio above one", config: Config{Endpoint: "http://localhost:4317", SampleRatio: 2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracerProvider, err := NewTracerProvider(context.Background(), tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, tracerProvider.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestConfigEnabled(t *testing.T) {
+	assert.False(t, Config{}.Enabled())
+	assert.True(t, Config{Endpoint: "http://localhost:4317"}.Enabled())
+}
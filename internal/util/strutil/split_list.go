@@ -0,0 +1,13 @@
+package strutil
+
+import "strings"
+
+// SplitList splits a user provided list into its items.
+//
+// Items can be separated by commas, spaces or new lines, surrounding
+// whitespace is trimmed and empty items are discarded.
+func SplitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
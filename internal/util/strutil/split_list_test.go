@@ -0,0 +1,53 @@
+package strutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "empty string",
+			input:    "",
+			expected: []string{},
+		},
+		{
+			name:     "only separators",
+			input:    " ,\n\t,",
+			expected: []string{},
+		},
+		{
+			name:     "single item",
+			input:    "10.0.0.0/8",
+			expected: []string{"10.0.0.0/8"},
+		},
+		{
+			name:     "comma separated",
+			input:    "a,b,c",
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "new line separated",
+			input:    "a\nb\r\nc\n",
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "mixed separators with whitespace",
+			input:    "  a , b\n\n c\td ",
+			expected: []string{"a", "b", "c", "d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SplitList(tt.input)
+			assert.ElementsMatch(t, tt.expected, result)
+		})
+	}
+}
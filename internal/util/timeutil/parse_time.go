@@ -0,0 +1,34 @@
+package timeutil
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// timeLayouts are the layouts accepted for the bounds of a time range.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	types.DefaultDateLayout,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseTime parses a user provided bound of a time range, like those of the
+// HAR export or the route stats. It accepts RFC 3339, the PocketBase date
+// format and plain dates, an empty string returns the zero time. Times
+// without a zone are taken as UTC.
+func ParseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD", s)
+}
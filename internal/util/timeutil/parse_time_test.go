@@ -0,0 +1,60 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			input: "",
+			want:  time.Time{},
+		},
+		{
+			name:  "rfc 3339",
+			input: "2025-01-02T03:04:05+01:00",
+			want:  time.Date(2025, 1, 2, 2, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "pocketbase date",
+			input: "2025-01-02 03:04:05.123Z",
+			want:  time.Date(2025, 1, 2, 3, 4, 5, 123_000_000, time.UTC),
+		},
+		{
+			name:  "date and time",
+			input: "2025-01-02 03:04:05",
+			want:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "date",
+			input: "2025-01-02",
+			want:  time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid",
+			input:   "yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTime(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "want %s, got %s", tt.want, got)
+		})
+	}
+}